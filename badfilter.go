@@ -0,0 +1,85 @@
+package urlfilter
+
+import (
+	"strings"
+
+	"github.com/AdguardTeam/urlfilter/filterlist"
+	"github.com/AdguardTeam/urlfilter/internal/lookup"
+	"github.com/AdguardTeam/urlfilter/rules"
+)
+
+// badfilterResolver applies the $badfilter rules of a storage to the network
+// rules while an engine is being built, so that the disabled rules are never
+// added to the lookup tables.  It is also the [lookup.RuleStorage] of these
+// tables, which returns the partially disabled rules instead of the ones from
+// the storage.
+type badfilterResolver struct {
+	// storage is the storage of the rules.
+	storage *filterlist.RuleStorage
+
+	// replaced are the partially disabled rules by their storage indexes.
+	replaced map[int64]*rules.NetworkRule
+
+	// index contains the $badfilter rules of storage.
+	index *rules.BadfilterIndex
+
+	// disabled are the rules disabled so far.
+	disabled []*rules.BadfilterDisabled
+}
+
+// newBadfilterResolver returns a new *badfilterResolver with the $badfilter
+// rules of s.  Since these rules are rare, only the lines that may contain them
+// are parsed.
+func newBadfilterResolver(s *filterlist.RuleStorage, workers int) (r *badfilterResolver) {
+	idx := rules.NewBadfilterIndex()
+	s.Scan(&filterlist.ScanConfig{
+		Handler: func(f rules.Rule, _ int64) {
+			idx.Add(f.(*rules.NetworkRule))
+		},
+		LineFilter: func(line string) (ok bool) {
+			return strings.Contains(line, "badfilter")
+		},
+		Filter: func(f rules.Rule) (ok bool) {
+			nr, ok := f.(*rules.NetworkRule)
+
+			return ok && nr.IsOptionEnabled(rules.OptionBadfilter)
+		},
+		Workers: workers,
+	})
+
+	return &badfilterResolver{
+		storage:  s,
+		replaced: map[int64]*rules.NetworkRule{},
+		index:    idx,
+	}
+}
+
+// type check
+var _ lookup.RuleStorage = (*badfilterResolver)(nil)
+
+// RetrieveNetworkRule implements the [lookup.RuleStorage] interface for
+// *badfilterResolver.
+func (r *badfilterResolver) RetrieveNetworkRule(storageIdx int64) (nr *rules.NetworkRule) {
+	nr, ok := r.replaced[storageIdx]
+	if ok {
+		return nr
+	}
+
+	return r.storage.RetrieveNetworkRule(storageIdx)
+}
+
+// resolve returns the rule which should be added to the lookup tables instead
+// of f, or nil if f must not be added.
+func (r *badfilterResolver) resolve(f *rules.NetworkRule, storageIdx int64) (res *rules.NetworkRule) {
+	res, disabled := r.index.Apply(f)
+	if disabled == nil {
+		return res
+	}
+
+	r.disabled = append(r.disabled, disabled)
+	if res != nil {
+		r.replaced[storageIdx] = res
+	}
+
+	return res
+}
@@ -2,6 +2,7 @@ package urlfilter
 
 import (
 	"github.com/AdguardTeam/urlfilter/filterlist"
+	"github.com/AdguardTeam/urlfilter/internal/ufnet"
 	"github.com/AdguardTeam/urlfilter/rules"
 )
 
@@ -35,12 +36,8 @@ func NewCosmeticEngine(s *filterlist.RuleStorage) *CosmeticEngine {
 
 // addRule adds a new cosmetic rule to one of the lookup tables
 func (e *CosmeticEngine) addRule(rule *rules.CosmeticRule) {
-	switch rule.Type {
-	case rules.CosmeticElementHiding:
-		e.lookupTables[rules.CosmeticElementHiding].addRule(rule)
-	default:
-		// TODO: Implement
-		// ignore
+	if c, ok := e.lookupTables[rule.Type]; ok {
+		c.addRule(rule)
 	}
 }
 
@@ -74,6 +71,16 @@ type ScriptsResult struct {
 	Specific []string
 }
 
+// append adds the content of the JS rule r to the generic or the specific
+// scripts.
+func (s *ScriptsResult) append(r *rules.CosmeticRule) {
+	if r.IsGeneric() {
+		s.Generic = append(s.Generic, r.Content)
+	} else {
+		s.Specific = append(s.Specific, r.Content)
+	}
+}
+
 // CosmeticResult represents all scripts and styles that needs to be injected into the page
 type CosmeticResult struct {
 	ElementHiding StylesResult
@@ -88,7 +95,21 @@ type CosmeticResult struct {
 // includeGenericCSS defines if we should inject generic CSS and element hiding rules (see $generichide)
 // TODO: Additionally, we should provide a method that writes result to an io.Writer
 func (e *CosmeticEngine) Match(hostname string, includeCSS, includeJS, includeGenericCSS bool) CosmeticResult {
-	r := CosmeticResult{
+	return e.match(hostname, includeCSS, includeJS, includeGenericCSS, true)
+}
+
+// match is like [CosmeticEngine.Match] but also allows excluding the specific
+// CSS and element hiding rules, see $specifichide.
+func (e *CosmeticEngine) match(
+	hostname string,
+	includeCSS bool,
+	includeJS bool,
+	includeGenericCSS bool,
+	includeSpecificCSS bool,
+) (r CosmeticResult) {
+	hostname = ufnet.NormalizeDomain(hostname)
+
+	r = CosmeticResult{
 		ElementHiding: StylesResult{},
 		CSS:           StylesResult{},
 		JS:            ScriptsResult{},
@@ -96,23 +117,22 @@ func (e *CosmeticEngine) Match(hostname string, includeCSS, includeJS, includeGe
 
 	if includeCSS {
 		c := e.lookupTables[rules.CosmeticElementHiding]
-		if includeGenericCSS {
-			for _, rule := range c.genericRules {
-				if !c.isWhitelisted(hostname, rule) && rule.Match(hostname) {
-					r.ElementHiding.append(rule)
-				}
-			}
+		for _, rule := range c.match(hostname, includeGenericCSS, includeSpecificCSS) {
+			r.ElementHiding.append(rule)
 		}
 
-		rules := c.findByHostname(hostname)
-		if len(rules) > 0 {
-			for _, rule := range rules {
-				r.ElementHiding.append(rule)
-			}
+		c = e.lookupTables[rules.CosmeticCSS]
+		for _, rule := range c.match(hostname, includeGenericCSS, includeSpecificCSS) {
+			r.CSS.append(rule)
 		}
 	}
 
-	// TODO: Implement CosmeticCSS and CosmeticJS
+	if includeJS {
+		c := e.lookupTables[rules.CosmeticJS]
+		for _, rule := range c.match(hostname, true, true) {
+			r.JS.append(rule)
+		}
+	}
 
 	return r
 }
@@ -154,6 +174,25 @@ func (c *cosmeticLookupTable) addRule(f *rules.CosmeticRule) {
 	}
 }
 
+// match returns the rules matching hostname, which aren't whitelisted.  generic
+// and specific define whether the generic and the domain-specific rules are
+// included.
+func (c *cosmeticLookupTable) match(hostname string, generic, specific bool) (res []*rules.CosmeticRule) {
+	if generic {
+		for _, rule := range c.genericRules {
+			if !c.isWhitelisted(hostname, rule) && rule.Match(hostname) {
+				res = append(res, rule)
+			}
+		}
+	}
+
+	if specific {
+		res = append(res, c.findByHostname(hostname)...)
+	}
+
+	return res
+}
+
 // findByHostname looks for matching domain-specific rules
 // Returns nil if nothing found
 func (c *cosmeticLookupTable) findByHostname(hostname string) []*rules.CosmeticRule {
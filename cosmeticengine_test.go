@@ -6,6 +6,7 @@ import (
 	"github.com/AdguardTeam/golibs/testutil"
 	"github.com/AdguardTeam/urlfilter"
 	"github.com/AdguardTeam/urlfilter/filterlist"
+	"github.com/AdguardTeam/urlfilter/rules"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -58,6 +59,35 @@ func TestCosmeticEngine_Match_elementHidingNoGeneric(t *testing.T) {
 	}, result.ElementHiding)
 }
 
+func TestEngine_GetCosmeticResult_specifichide(t *testing.T) {
+	t.Parallel()
+
+	rulesText := `##banner_generic
+example.org##banner_specific
+example.org#$#.specific { display: none }
+example.org#%#window.ads = false;
+@@||example.org^$specifichide`
+
+	engine := urlfilter.NewEngine(newRuleStorage(t, rulesText))
+
+	option := engine.MatchRequest(rules.NewRequest("https://example.org/", "", rules.TypeDocument)).GetCosmeticOption()
+	assert.Equal(t, rules.CosmeticOptionAll|rules.CosmeticOptionNoSpecificCSS, option)
+
+	result := engine.GetCosmeticResult("example.org", option)
+	assert.Equal(t, urlfilter.StylesResult{
+		Generic:        []string{"banner_generic"},
+		Specific:       nil,
+		GenericExtCSS:  nil,
+		SpecificExtCSS: nil,
+	}, result.ElementHiding)
+	assert.Equal(t, urlfilter.StylesResult{}, result.CSS)
+	assert.Equal(t, []string{"window.ads = false;"}, result.JS.Specific)
+
+	result = engine.GetCosmeticResult("example.org", rules.CosmeticOptionAll)
+	assert.Equal(t, []string{"banner_specific"}, result.ElementHiding.Specific)
+	assert.Equal(t, []string{".specific { display: none }"}, result.CSS.Specific)
+}
+
 func TestCosmeticEngine_Match_elementHidingNoCSS(t *testing.T) {
 	t.Parallel()
 
@@ -74,6 +104,47 @@ func TestCosmeticEngine_Match_elementHidingNoCSS(t *testing.T) {
 	}, result.ElementHiding)
 }
 
+func TestCosmeticEngine_Match_cssAndJS(t *testing.T) {
+	t.Parallel()
+
+	rulesText := `example.org##.ad:style(color: red)
+example.org##.banner:remove()
+##+js(set-constant, foo, true)
+example.org#$#.popup { display: none }
+example.org#%#window.ads = false;
+##+js(abort-on-property-read, bar)
+example.org#@%#//scriptlet('ubo-abort-on-property-read', 'bar')`
+
+	engine := newCosmeticEngine(t, rulesText)
+
+	result := engine.Match("example.org", true, true, true)
+
+	assert.Equal(t, urlfilter.StylesResult{
+		Generic:        nil,
+		Specific:       []string{".ad { color: red }", ".popup { display: none }"},
+		GenericExtCSS:  nil,
+		SpecificExtCSS: []string{".banner { remove: true; }"},
+	}, result.CSS)
+
+	assert.Equal(t, urlfilter.ScriptsResult{
+		Generic:  []string{"//scriptlet('ubo-set-constant', 'foo', 'true')"},
+		Specific: []string{"window.ads = false;"},
+	}, result.JS)
+
+	result = engine.Match("example.org", true, false, true)
+	assert.Equal(t, urlfilter.ScriptsResult{}, result.JS)
+
+	result = engine.Match("example.com", true, true, true)
+	assert.Equal(t, urlfilter.StylesResult{}, result.CSS)
+	assert.Equal(t, urlfilter.ScriptsResult{
+		Generic: []string{
+			"//scriptlet('ubo-set-constant', 'foo', 'true')",
+			"//scriptlet('ubo-abort-on-property-read', 'bar')",
+		},
+		Specific: nil,
+	}, result.JS)
+}
+
 func FuzzCosmeticEngine_Match(f *testing.F) {
 	for _, seed := range []string{
 		"",
@@ -95,8 +166,9 @@ func FuzzCosmeticEngine_Match(f *testing.F) {
 	})
 }
 
-// newTestCosmeticEngine is a helper function to build a cosmetic engine for
-// testing.  It adds rule storage close method to tb's cleanup.
+// newTestCosmeticEngine is a helper function to build a cosmetic engine with
+// the common element hiding rules for testing.  It adds rule storage close
+// method to tb's cleanup.
 func newTestCosmeticEngine(tb testing.TB) (eng *urlfilter.CosmeticEngine) {
 	tb.Helper()
 
@@ -105,17 +177,34 @@ func newTestCosmeticEngine(tb testing.TB) (eng *urlfilter.CosmeticEngine) {
 example.org##banner_specific
 example.org#@#banner_generic_disabled`
 
+	return newCosmeticEngine(tb, rulesText)
+}
+
+// newCosmeticEngine is a helper function to build a cosmetic engine with
+// rulesText for testing.  It adds rule storage close method to tb's cleanup.
+func newCosmeticEngine(tb testing.TB, rulesText string) (eng *urlfilter.CosmeticEngine) {
+	tb.Helper()
+
+	return urlfilter.NewCosmeticEngine(newRuleStorage(tb, rulesText))
+}
+
+// newRuleStorage is a helper function to build a rule storage with rulesText
+// for testing.  It adds rule storage close method to tb's cleanup.
+func newRuleStorage(tb testing.TB, rulesText string) (s *filterlist.RuleStorage) {
+	tb.Helper()
+
 	lists := []filterlist.Interface{
 		filterlist.NewString(&filterlist.StringConfig{
 			RulesText: rulesText,
 			ID:        1,
+			Trusted:   true,
 		}),
 	}
 
-	ruleStorage, err := filterlist.NewRuleStorage(lists)
+	s, err := filterlist.NewRuleStorage(lists)
 	require.NoError(tb, err)
 
-	testutil.CleanupAndRequireSuccess(tb, ruleStorage.Close)
+	testutil.CleanupAndRequireSuccess(tb, s.Close)
 
-	return urlfilter.NewCosmeticEngine(ruleStorage)
+	return s
 }
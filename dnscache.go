@@ -0,0 +1,182 @@
+package urlfilter
+
+import (
+	"container/list"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+)
+
+// DNSResultCacheStats are the statistics of the result cache of a [DNSEngine].
+type DNSResultCacheStats struct {
+	// Hits is the number of requests the results of which have been taken from
+	// the cache.
+	Hits uint64
+
+	// Misses is the number of requests the results of which have been
+	// computed.
+	Misses uint64
+
+	// Len is the number of results currently in the cache.
+	Len int
+}
+
+// HitRatio returns the ratio of the hits to all requests, or zero if there
+// were no requests.
+func (s *DNSResultCacheStats) HitRatio() (r float64) {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(total)
+}
+
+// dnsCacheKey is the key of a result in the cache.  It contains all fields of
+// [DNSRequest] which affect the result.
+type dnsCacheKey struct {
+	// clientIP is [DNSRequest.ClientIP].
+	clientIP netip.Addr
+
+	// clientName is [DNSRequest.ClientName].
+	clientName string
+
+	// hostname is the normalized [DNSRequest.Hostname].
+	hostname string
+
+	// clientTags are [DNSRequest.SortedClientTags] joined with a character
+	// not allowed in tags.
+	clientTags string
+
+	// dnsType is [DNSRequest.DNSType].
+	dnsType rules.RRType
+}
+
+// newDNSCacheKey returns the cache key for req with the normalized hostname.
+func newDNSCacheKey(req *DNSRequest, hostname string) (k dnsCacheKey) {
+	return dnsCacheKey{
+		clientIP:   req.ClientIP,
+		clientName: req.ClientName,
+		hostname:   hostname,
+		clientTags: strings.Join(req.SortedClientTags, "\x00"),
+		dnsType:    req.DNSType,
+	}
+}
+
+// dnsCacheEntry is an entry of the cache.
+type dnsCacheEntry struct {
+	// res is the cached result.  Its slices are never modified.
+	res *DNSResult
+
+	// key is the key of the entry.
+	key dnsCacheKey
+
+	// matched is the cached value returned by [DNSEngine.MatchRequestInto].
+	matched bool
+}
+
+// dnsResultCache is a bounded cache of the matching results which evicts the
+// least recently used results.
+type dnsResultCache struct {
+	// mu protects all fields below.
+	mu *sync.Mutex
+
+	// entries are the elements of order by their keys.
+	entries map[dnsCacheKey]*list.Element
+
+	// order contains *dnsCacheEntry values, starting from the most recently
+	// used one.
+	order *list.List
+
+	// hits is the number of the cache hits.
+	hits uint64
+
+	// misses is the number of the cache misses.
+	misses uint64
+
+	// size is the maximum number of entries.
+	size int
+}
+
+// newDNSResultCache returns a new cache with the given maximum number of
+// entries.  size must be positive.
+func newDNSResultCache(size int) (c *dnsResultCache) {
+	return &dnsResultCache{
+		mu:      &sync.Mutex{},
+		entries: make(map[dnsCacheKey]*list.Element, size),
+		order:   list.New(),
+		size:    size,
+	}
+}
+
+// get appends the cached result for k to res.  ok is false if there is no such
+// result.
+func (c *dnsResultCache) get(k dnsCacheKey, res *DNSResult) (matched, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[k]
+	if !ok {
+		c.misses++
+
+		return false, false
+	}
+
+	c.hits++
+	c.order.MoveToFront(elem)
+
+	e := elem.Value.(*dnsCacheEntry)
+	res.NetworkRule = e.res.NetworkRule
+	res.HostRulesV4 = append(res.HostRulesV4, e.res.HostRulesV4...)
+	res.HostRulesV6 = append(res.HostRulesV6, e.res.HostRulesV6...)
+	res.NetworkRules = append(res.NetworkRules, e.res.NetworkRules...)
+
+	return e.matched, true
+}
+
+// set puts a copy of res into the cache, evicting the least recently used
+// result if necessary.
+func (c *dnsResultCache) set(k dnsCacheKey, res *DNSResult, matched bool) {
+	e := &dnsCacheEntry{
+		res: &DNSResult{
+			NetworkRule:  res.NetworkRule,
+			HostRulesV4:  slices.Clone(res.HostRulesV4),
+			HostRulesV6:  slices.Clone(res.HostRulesV6),
+			NetworkRules: slices.Clone(res.NetworkRules),
+		},
+		key:     k,
+		matched: matched,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[k]; ok {
+		elem.Value = e
+		c.order.MoveToFront(elem)
+
+		return
+	}
+
+	c.entries[k] = c.order.PushFront(e)
+	if c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.entries, last.Value.(*dnsCacheEntry).key)
+	}
+}
+
+// stats returns the current statistics of the cache.
+func (c *dnsResultCache) stats() (s *DNSResultCacheStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &DNSResultCacheStats{
+		Hits:   c.hits,
+		Misses: c.misses,
+		Len:    c.order.Len(),
+	}
+}
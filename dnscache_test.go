@@ -0,0 +1,111 @@
+package urlfilter
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSEngine_MatchRequestInto_resultCache(t *testing.T) {
+	t.Parallel()
+
+	const rulesText = `||blocked.example^
+||tagged.example^$ctag=device_pc
+127.0.0.1 host.example
+`
+
+	ruleStorage := newTestRuleStorage(t, 1, rulesText)
+	dnsEngine := NewDNSEngineWithConfig(&DNSEngineConfig{
+		Storage:         ruleStorage,
+		ResultCacheSize: 2,
+	})
+
+	uncached := NewDNSEngine(ruleStorage)
+	require.Nil(t, uncached.ResultCacheStats())
+
+	match := func(req *DNSRequest) (res *DNSResult, ok bool) {
+		t.Helper()
+
+		res = &DNSResult{}
+		ok = dnsEngine.MatchRequestInto(req, res)
+
+		want, wantOK := uncached.MatchRequest(req)
+		require.Equal(t, wantOK, ok)
+		require.Equal(t, want, res)
+
+		return res, ok
+	}
+
+	res, ok := match(&DNSRequest{Hostname: "blocked.example"})
+	require.True(t, ok)
+
+	// Make sure that the cached result isn't affected by the changes in the
+	// returned one.
+	res.NetworkRules[0] = nil
+	res, ok = match(&DNSRequest{Hostname: "blocked.example"})
+	require.True(t, ok)
+
+	assert.NotNil(t, res.NetworkRules[0])
+	assert.Equal(t, &DNSResultCacheStats{Hits: 1, Misses: 1, Len: 1}, dnsEngine.ResultCacheStats())
+
+	_, ok = match(&DNSRequest{Hostname: "tagged.example"})
+	require.False(t, ok)
+
+	_, ok = match(&DNSRequest{
+		Hostname:         "tagged.example",
+		SortedClientTags: []string{"device_pc"},
+	})
+	require.True(t, ok)
+
+	// The first result must have been evicted.
+	_, ok = match(&DNSRequest{Hostname: "blocked.example"})
+	require.True(t, ok)
+
+	_, ok = match(&DNSRequest{Hostname: "host.example"})
+	require.True(t, ok)
+
+	// The hostname is cached in the normalized form.
+	_, ok = match(&DNSRequest{Hostname: "HOST.example"})
+	require.True(t, ok)
+
+	stats := dnsEngine.ResultCacheStats()
+	assert.Equal(t, &DNSResultCacheStats{Hits: 2, Misses: 5, Len: 2}, stats)
+	assert.InDelta(t, 2.0/7, stats.HitRatio(), 0.001)
+}
+
+func BenchmarkDNSEngine_MatchRequestInto_resultCache(b *testing.B) {
+	testHostnames := loadHostnames(b)
+
+	ruleStorage := newRuleStorage(b)
+	testutil.CleanupAndRequireSuccess(b, ruleStorage.Close)
+
+	dnsEngine := NewDNSEngineWithConfig(&DNSEngineConfig{
+		Storage:         ruleStorage,
+		ResultCacheSize: len(testHostnames),
+	})
+
+	var match bool
+	req := &DNSRequest{}
+	res := &DNSResult{}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, reqHostname := range testHostnames {
+			req.Hostname = reqHostname
+			res.Reset()
+
+			match = dnsEngine.MatchRequestInto(req, res)
+		}
+	}
+
+	assert.True(b, match)
+
+	// Most recent results:
+	//	goos: linux
+	//	goarch: amd64
+	//	pkg: github.com/AdguardTeam/urlfilter
+	//	cpu: Intel(R) Xeon(R) Processor
+	//	BenchmarkDNSEngine_MatchRequestInto_resultCache    	     256	   4591609 ns/op	   32644 B/op	     276 allocs/op
+}
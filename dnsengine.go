@@ -1,10 +1,14 @@
 package urlfilter
 
 import (
+	"cmp"
 	"net/netip"
+	"strings"
 
 	"github.com/AdguardTeam/golibs/syncutil"
 	"github.com/AdguardTeam/urlfilter/filterlist"
+	"github.com/AdguardTeam/urlfilter/internal/bloom"
+	"github.com/AdguardTeam/urlfilter/internal/ufnet"
 	"github.com/AdguardTeam/urlfilter/rules"
 )
 
@@ -16,6 +20,10 @@ type DNSEngine struct {
 	// ruleIndex is a map for hosts mapped to the list of rule indexes.
 	ruleIndex map[string][]int64
 
+	// hostsFilter contains the hostnames of the host rules and of the
+	// host-anchored network rules.  It is nil if the pre-check is disabled.
+	hostsFilter *bloom.Filter
+
 	// networkEngine is a network rules engine constructed from the network
 	// rules.
 	networkEngine *NetworkEngine
@@ -29,6 +37,18 @@ type DNSEngine struct {
 	// rulesPool contains slices of rules for reuse.
 	rulesPool *syncutil.Pool[[]*rules.HostRule]
 
+	// badfilterDisabled are the rules disabled by the $badfilter rules while
+	// scanning the storage.
+	badfilterDisabled []*rules.BadfilterDisabled
+
+	// resultCache contains the recent results.  It is nil if the cache is
+	// disabled.
+	resultCache *dnsResultCache
+
+	// unanchoredCount is the number of the network rules, which aren't
+	// anchored to a hostname, so hostsFilter can't rule them out.
+	unanchoredCount int
+
 	// RulesCount is the count of rules loaded to the engine.
 	RulesCount int
 }
@@ -98,51 +118,168 @@ func (r *DNSRequest) Reset() {
 	r.Answer = false
 }
 
+// DefaultBloomFilterFPRate is the default false positive rate of the
+// probabilistic pre-check of [DNSEngine].  With this rate, the pre-check takes
+// about 11.9 bits per indexed hostname, and about one in a hundred of the
+// hostnames with up to three labels not matching any rules still goes through
+// the full lookup.
+const DefaultBloomFilterFPRate = 0.01
+
+// bloomFilterLabelsEst is the estimated number of the labels of a hostname.
+// The hostname and each of its parent domains are checked against the filter
+// separately, so the filter is sized for the rate of a single check being that
+// many times lower than the configured one.
+const bloomFilterLabelsEst = 3
+
+// DNSEngineConfig is the configuration structure for a *DNSEngine.
+type DNSEngineConfig struct {
+	// Storage is the storage of the rules.  It must not be nil.
+	Storage *filterlist.RuleStorage
+
+	// ResultCacheSize is the maximum number of the recent results to cache.
+	// The results are cached by the hostname, the DNS type, and the client
+	// information of the request.  Since the cache belongs to the engine, it
+	// is discarded together with it when the rules change.  If it is zero, the
+	// cache is disabled.  It must not be negative.
+	ResultCacheSize int
+
+	// BloomFilterFPRate is the false positive rate of the probabilistic
+	// pre-check, which short-circuits the requests for hostnames that
+	// definitely match neither the host rules nor the "||hostname^" network
+	// rules.  The rate is for the hostnames with up to three labels, and the
+	// longer ones have a proportionally higher rate.  It must be within [0, 1).
+	// If it is zero, [DefaultBloomFilterFPRate] is used.
+	BloomFilterFPRate float64
+
+	// DisableBloomFilter disables the probabilistic pre-check.  The pre-check
+	// only skips the network rules if all of them are anchored to a hostname,
+	// that is, if there are no regex rules, rules with wildcards, and so on.
+	// Otherwise, it only skips the lookup of the host rules, so it is useful to
+	// disable it for the lists that consist mostly of such rules.
+	DisableBloomFilter bool
+
+	// Workers is the number of goroutines parsing the rules of the storage.
+	// If it is zero, runtime.GOMAXPROCS(0) is used.  If it is one, the rules
+	// are parsed on the calling goroutine.  It must not be negative.
+	Workers int
+}
+
 // NewDNSEngine parses the specified filter lists and returns a *DNSEngine built
-// from them.  s must not be nil.
+// from them.  s must not be nil.  The probabilistic pre-check is enabled with
+// [DefaultBloomFilterFPRate].
 func NewDNSEngine(s *filterlist.RuleStorage) (d *DNSEngine) {
-	// Count rules in the rule storage to pre-allocate lookup tables.
-	var hostRulesCount, networkRulesCount int
-	scan := s.NewRuleStorageScanner()
-	for scan.Scan() {
-		f, _ := scan.Rule()
-		switch f := f.(type) {
-		case *rules.HostRule:
-			hostRulesCount += len(f.Hostnames)
-		case *rules.NetworkRule:
-			networkRulesCount++
-		}
-	}
+	return NewDNSEngineWithConfig(&DNSEngineConfig{
+		Storage: s,
+	})
+}
+
+// NewDNSEngineWithConfig returns a *DNSEngine built from the rules in the
+// storage.  The storage is scanned once, and the rules are added to the
+// engine in the same order regardless of the number of workers.  conf must not
+// be nil.
+func NewDNSEngineWithConfig(conf *DNSEngineConfig) (d *DNSEngine) {
+	s := conf.Storage
 
 	d = &DNSEngine{
 		rulesStorage: s,
-		ruleIndex:    make(map[string][]int64, hostRulesCount),
+		ruleIndex:    map[string][]int64{},
 		RulesCount:   0,
 		reqPool:      syncutil.NewPool(func() (v *rules.Request) { return &rules.Request{} }),
 		rulesPool:    syncutil.NewSlicePool[*rules.HostRule](1),
 	}
 
-	networkEngine := NewNetworkEngineSkipStorageScan(s)
+	if conf.ResultCacheSize > 0 {
+		d.resultCache = newDNSResultCache(conf.ResultCacheSize)
+	}
 
-	scanner := s.NewRuleStorageScanner()
-	for scanner.Scan() {
-		f, idx := scanner.Rule()
-		switch f := f.(type) {
-		case *rules.HostRule:
-			d.addRule(f, idx)
-		case *rules.NetworkRule:
-			if f.IsHostLevelNetworkRule() {
-				networkEngine.AddRule(f, idx)
+	workers := scanWorkers(conf.Workers)
+	bf := newBadfilterResolver(s, workers)
+	d.networkEngine = newNetworkEngine(s, bf)
+
+	var anchoredHosts []string
+	s.Scan(&filterlist.ScanConfig{
+		Handler: func(r rules.Rule, storageIdx int64) {
+			switch f := r.(type) {
+			case *rules.HostRule:
+				d.addRule(f, storageIdx)
+			case *rules.NetworkRule:
+				if f = bf.resolve(f, storageIdx); f != nil {
+					anchoredHosts = d.addNetworkRule(anchoredHosts, f, storageIdx)
+				}
 			}
-		}
+		},
+		Filter:  isDNSEngineRule,
+		Workers: workers,
+	})
+
+	d.badfilterDisabled = bf.disabled
+
+	if !conf.DisableBloomFilter {
+		fpRate := cmp.Or(conf.BloomFilterFPRate, DefaultBloomFilterFPRate)
+		d.hostsFilter = d.newHostsFilter(anchoredHosts, fpRate)
 	}
 
-	d.RulesCount += networkEngine.RulesCount
-	d.networkEngine = networkEngine
+	d.RulesCount += d.networkEngine.RulesCount
 
 	return d
 }
 
+// BadfilterDisabled returns the rules disabled, completely or partially, by the
+// $badfilter rules while building the engine.  The result must not be
+// modified.
+func (d *DNSEngine) BadfilterDisabled() (disabled []*rules.BadfilterDisabled) {
+	return d.badfilterDisabled
+}
+
+// isDNSEngineRule returns true if r is a host rule or a host-level network
+// rule.
+func isDNSEngineRule(r rules.Rule) (ok bool) {
+	switch r := r.(type) {
+	case *rules.HostRule:
+		return true
+	case *rules.NetworkRule:
+		return r.IsHostLevelNetworkRule()
+	default:
+		return false
+	}
+}
+
+// newHostsFilter returns a filter containing the hostnames of the host rules
+// in the index as well as anchoredHosts.  fpRate is the false positive rate of
+// [DNSEngine.mayMatchHostname] for the hostnames with up to
+// [bloomFilterLabelsEst] labels.
+func (d *DNSEngine) newHostsFilter(anchoredHosts []string, fpRate float64) (f *bloom.Filter) {
+	f = bloom.New(len(d.ruleIndex)+len(anchoredHosts), fpRate/bloomFilterLabelsEst)
+	for hostname := range d.ruleIndex {
+		f.Add(hostname)
+	}
+
+	for _, hostname := range anchoredHosts {
+		f.Add(hostname)
+	}
+
+	return f
+}
+
+// addNetworkRule adds the host-level network rule to the network engine.  If the
+// rule is anchored to a hostname, the hostname is appended to anchoredHosts.
+func (d *DNSEngine) addNetworkRule(
+	anchoredHosts []string,
+	f *rules.NetworkRule,
+	storageIdx int64,
+) (res []string) {
+	d.networkEngine.AddRule(f, storageIdx)
+
+	hostname, _, ok := f.HostnamePattern()
+	if !ok {
+		d.unanchoredCount++
+
+		return anchoredHosts
+	}
+
+	return append(anchoredHosts, hostname)
+}
+
 // Match finds a matching rule for the specified hostname.  It returns true and
 // the list of rules found or false and nil.  A list of rules is returned when
 // there are multiple host rules matching the same domain, for example:
@@ -154,8 +291,9 @@ func (d *DNSEngine) Match(hostname string) (res *DNSResult, matched bool) {
 }
 
 // getRequestFromPool returns an instance of request from the engine's pool.
-// Fills it's properties to match the given DNS request.
-func (d *DNSEngine) getRequestFromPool(dReq *DNSRequest) (req *rules.Request) {
+// Fills it's properties to match the given DNS request.  hostname is the
+// normalized hostname of dReq.
+func (d *DNSEngine) getRequestFromPool(dReq *DNSRequest, hostname string) (req *rules.Request) {
 	req = d.reqPool.Get()
 
 	req.SourceDomain = ""
@@ -167,7 +305,7 @@ func (d *DNSEngine) getRequestFromPool(dReq *DNSRequest) (req *rules.Request) {
 	req.ClientName = dReq.ClientName
 	req.DNSType = dReq.DNSType
 
-	rules.FillRequestForHostname(req, dReq.Hostname)
+	rules.FillRequestForNormalizedHostname(req, hostname)
 
 	return req
 }
@@ -188,7 +326,51 @@ func (d *DNSEngine) MatchRequestInto(req *DNSRequest, res *DNSResult) (matched b
 		return false
 	}
 
-	r := d.getRequestFromPool(req)
+	hostname := ufnet.NormalizeDomain(req.Hostname)
+	if d.resultCache == nil {
+		return d.matchRequestInto(req, hostname, res)
+	}
+
+	k := newDNSCacheKey(req, hostname)
+	matched, ok := d.resultCache.get(k, res)
+	if ok {
+		return matched
+	}
+
+	matched = d.matchRequestInto(req, hostname, res)
+	d.resultCache.set(k, res, matched)
+
+	return matched
+}
+
+// ResultCacheStats returns the statistics of the result cache.  It returns nil
+// if the cache is disabled.
+func (d *DNSEngine) ResultCacheStats() (s *DNSResultCacheStats) {
+	if d.resultCache == nil {
+		return nil
+	}
+
+	return d.resultCache.stats()
+}
+
+// matchRequestInto is the uncached implementation of
+// [DNSEngine.MatchRequestInto].  hostname is the normalized hostname of req.
+// The network rules are in the order of the lookup tables of the network
+// engine.  If the probabilistic pre-check rules out the hostname, the host
+// rules aren't looked up, and if all network rules are anchored to hostnames,
+// the network rules aren't looked up either.
+func (d *DNSEngine) matchRequestInto(
+	req *DNSRequest,
+	hostname string,
+	res *DNSResult,
+) (matched bool) {
+	mayMatch := d.mayMatchHostname(hostname)
+	if !mayMatch && d.unanchoredCount == 0 {
+		// Neither the host rules nor the network rules can match.
+		return false
+	}
+
+	r := d.getRequestFromPool(req, hostname)
 	defer d.reqPool.Put(r)
 
 	res.NetworkRules = d.networkEngine.AppendAllMatching(res.NetworkRules, r)
@@ -197,12 +379,20 @@ func (d *DNSEngine) MatchRequestInto(req *DNSRequest, res *DNSResult) (matched b
 		res.NetworkRule = resultRule
 
 		return true
+	} else if !mayMatch {
+		return false
 	}
 
+	return d.matchHostRules(hostname, res)
+}
+
+// matchHostRules puts the host rules matching hostname into res.  matched is
+// true if there are any.
+func (d *DNSEngine) matchHostRules(hostname string, res *DNSResult) (matched bool) {
 	hostRulesPtr := d.rulesPool.Get()
 	defer d.rulesPool.Put(hostRulesPtr)
 
-	*hostRulesPtr = d.appendFromIndex((*hostRulesPtr)[:0], req.Hostname)
+	*hostRulesPtr = d.appendFromIndex((*hostRulesPtr)[:0], hostname)
 	if len(*hostRulesPtr) == 0 {
 		return false
 	}
@@ -218,6 +408,29 @@ func (d *DNSEngine) MatchRequestInto(req *DNSRequest, res *DNSResult) (matched b
 	return true
 }
 
+// mayMatchHostname returns false if hostname definitely matches neither the
+// host rules nor the host-anchored network rules.  hostname must be normalized.
+// Since each parent domain of hostname is checked separately, the false
+// positive rate grows with the number of its labels.
+func (d *DNSEngine) mayMatchHostname(hostname string) (ok bool) {
+	if d.hostsFilter == nil {
+		return true
+	}
+
+	for {
+		if d.hostsFilter.MayContain(hostname) {
+			return true
+		}
+
+		i := strings.IndexByte(hostname, '.')
+		if i < 0 {
+			return false
+		}
+
+		hostname = hostname[i+1:]
+	}
+}
+
 // MatchRequest is like [MatchRequestInto] but returns a new result.  req must
 // not be nil.
 func (d *DNSEngine) MatchRequest(dReq *DNSRequest) (res *DNSResult, matched bool) {
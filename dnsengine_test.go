@@ -1,6 +1,7 @@
 package urlfilter
 
 import (
+	"fmt"
 	"net/netip"
 	"os"
 	"runtime"
@@ -346,6 +347,7 @@ func TestBadfilterRules(t *testing.T) {
 	ruleStorage := newTestRuleStorage(t, 1, rulesText)
 	dnsEngine := NewDNSEngine(ruleStorage)
 	assert.NotNil(t, dnsEngine)
+	assert.Len(t, dnsEngine.BadfilterDisabled(), 1)
 
 	r, ok := dnsEngine.Match("example.org")
 	assert.False(t, ok)
@@ -509,6 +511,293 @@ func TestSlash(t *testing.T) {
 	assert.True(t, r.NetworkRule == nil && r.HostRulesV4 == nil && r.HostRulesV6 == nil)
 }
 
+func TestDNSEngine_Match_idn(t *testing.T) {
+	t.Parallel()
+
+	const rulesText = `0.0.0.0 пример.рф
+||xn--d1acufc.xn--p1ai^
+@@||разрешено.домен.рф^
+`
+
+	ruleStorage := newTestRuleStorage(t, 1, rulesText)
+	dnsEngine := NewDNSEngine(ruleStorage)
+
+	testCases := []struct {
+		name     string
+		hostname string
+		wantOK   bool
+	}{{
+		name:     "host_rule_ascii",
+		hostname: "xn--e1afmkfd.xn--p1ai",
+		wantOK:   true,
+	}, {
+		name:     "host_rule_unicode",
+		hostname: "Пример.рф",
+		wantOK:   true,
+	}, {
+		name:     "network_rule_unicode",
+		hostname: "sub.домен.рф",
+		wantOK:   true,
+	}, {
+		name:     "exception_ascii",
+		hostname: "xn--80akag6agic7e.xn--d1acufc.xn--p1ai",
+		wantOK:   true,
+	}, {
+		name:     "no_match",
+		hostname: "другой.рф",
+		wantOK:   false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, ok := dnsEngine.Match(tc.hostname)
+			assert.Equal(t, tc.wantOK, ok)
+		})
+	}
+
+	res, ok := dnsEngine.Match("разрешено.домен.рф")
+	require.True(t, ok)
+	require.NotNil(t, res.NetworkRule)
+
+	assert.True(t, res.NetworkRule.Whitelist)
+}
+
+func TestDNSEngine_Match_ip(t *testing.T) {
+	t.Parallel()
+
+	const rulesText = `||[2001:db8::1]^
+192.0.2.0/24
+@@192.0.2.128/25
+||[2001:db8:1::]/48^
+`
+
+	ruleStorage := newTestRuleStorage(t, 1, rulesText)
+	dnsEngine := NewDNSEngine(ruleStorage)
+
+	testCases := []struct {
+		name          string
+		hostname      string
+		wantOK        bool
+		wantWhitelist bool
+	}{{
+		name:          "ipv6",
+		hostname:      "2001:db8::1",
+		wantOK:        true,
+		wantWhitelist: false,
+	}, {
+		name:          "ipv6_other",
+		hostname:      "2001:db8::2",
+		wantOK:        false,
+		wantWhitelist: false,
+	}, {
+		name:          "cidr_ipv4",
+		hostname:      "192.0.2.1",
+		wantOK:        true,
+		wantWhitelist: false,
+	}, {
+		name:          "cidr_ipv4_exception",
+		hostname:      "192.0.2.200",
+		wantOK:        true,
+		wantWhitelist: true,
+	}, {
+		name:          "cidr_ipv4_other",
+		hostname:      "198.51.100.1",
+		wantOK:        false,
+		wantWhitelist: false,
+	}, {
+		name:          "cidr_ipv6",
+		hostname:      "2001:DB8:1::5",
+		wantOK:        true,
+		wantWhitelist: false,
+	}, {
+		name:          "domain",
+		hostname:      "example.org",
+		wantOK:        false,
+		wantWhitelist: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, ok := dnsEngine.Match(tc.hostname)
+			require.Equal(t, tc.wantOK, ok)
+
+			if ok {
+				require.NotNil(t, res.NetworkRule)
+
+				assert.Equal(t, tc.wantWhitelist, res.NetworkRule.Whitelist)
+			}
+		})
+	}
+}
+
+func TestDNSEngine_MatchRequest_rpz(t *testing.T) {
+	const zoneData = `$TTL 300
+blocked.example CNAME .
+allowed.blocked.example CNAME rpz-passthru.
+*.rewritten.example A 1.2.3.4
+`
+
+	list, err := filterlist.NewRPZ(&filterlist.RPZConfig{
+		ZoneData: []byte(zoneData),
+		Origin:   "rpz.test",
+		ID:       1,
+	})
+	require.NoError(t, err)
+
+	ruleStorage, err := filterlist.NewRuleStorage([]filterlist.Interface{list})
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, ruleStorage.Close)
+
+	dnsEngine := NewDNSEngine(ruleStorage)
+
+	// Only the rule for the wildcard owner name isn't anchored to a hostname.
+	assert.Equal(t, 1, dnsEngine.unanchoredCount)
+
+	res, _ := dnsEngine.Match("blocked.example")
+	dnsr := res.DNSRewrites()
+	require.Len(t, dnsr, 1)
+
+	assert.Equal(t, dns.RcodeNameError, dnsr[0].DNSRewrite.RCode)
+
+	res, _ = dnsEngine.Match("sub.blocked.example")
+	assert.Empty(t, res.DNSRewrites())
+
+	res, ok := dnsEngine.Match("allowed.blocked.example")
+	assertMatchRuleText(t, "@@|allowed.blocked.example^", res, ok)
+
+	res, _ = dnsEngine.Match("rewritten.example")
+	assert.Empty(t, res.DNSRewrites())
+
+	res, _ = dnsEngine.Match("sub.rewritten.example")
+	dnsr = res.DNSRewrites()
+	require.Len(t, dnsr, 1)
+
+	assert.Equal(t, netip.MustParseAddr("1.2.3.4"), dnsr[0].DNSRewrite.Value)
+}
+
+func TestNewDNSEngineWithConfig(t *testing.T) {
+	t.Parallel()
+
+	const rulesText = `||blocked.example^
+@@||allowed.blocked.example^
+||rewritten.example^$dnsrewrite=1.2.3.4
+/^ads[0-9]+\./
+127.0.0.1 host.example
+::1 host.example
+`
+
+	ruleStorage := newTestRuleStorage(t, 1, rulesText)
+
+	withFilter := NewDNSEngineWithConfig(&DNSEngineConfig{
+		Storage:           ruleStorage,
+		BloomFilterFPRate: 0.001,
+	})
+	noFilter := NewDNSEngineWithConfig(&DNSEngineConfig{
+		Storage:            ruleStorage,
+		DisableBloomFilter: true,
+	})
+
+	assert.Equal(t, 6, withFilter.RulesCount)
+	assert.Equal(t, noFilter.RulesCount, withFilter.RulesCount)
+
+	testCases := []struct {
+		name     string
+		hostname string
+		wantOK   bool
+	}{{
+		name:     "blocked",
+		hostname: "blocked.example",
+		wantOK:   true,
+	}, {
+		name:     "blocked_subdomain",
+		hostname: "sub.blocked.example",
+		wantOK:   true,
+	}, {
+		name:     "allowed",
+		hostname: "allowed.blocked.example",
+		wantOK:   true,
+	}, {
+		name:     "blocked_regex",
+		hostname: "ads1.blocked.example",
+		wantOK:   true,
+	}, {
+		name:     "rewritten",
+		hostname: "rewritten.example",
+		wantOK:   false,
+	}, {
+		name:     "regex",
+		hostname: "ads1.other.example",
+		wantOK:   true,
+	}, {
+		name:     "host",
+		hostname: "host.example",
+		wantOK:   true,
+	}, {
+		name:     "host_subdomain",
+		hostname: "sub.host.example",
+		wantOK:   false,
+	}, {
+		name:     "no_match",
+		hostname: "not-blocked.example",
+		wantOK:   false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want, wantOK := noFilter.Match(tc.hostname)
+			require.Equal(t, tc.wantOK, wantOK)
+
+			got, ok := withFilter.Match(tc.hostname)
+			require.Equal(t, wantOK, ok)
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestNewDNSEngineWithConfig_workers(t *testing.T) {
+	t.Parallel()
+
+	ruleStorage := newRuleStorage(t)
+	testutil.CleanupAndRequireSuccess(t, ruleStorage.Close)
+
+	sequential := NewDNSEngineWithConfig(&DNSEngineConfig{
+		Storage: ruleStorage,
+		Workers: 1,
+	})
+	parallel := NewDNSEngineWithConfig(&DNSEngineConfig{
+		Storage: ruleStorage,
+		Workers: 4,
+	})
+
+	require.Equal(t, sequential.RulesCount, parallel.RulesCount)
+
+	for _, hostname := range []string{
+		"clickiocdn.com",
+		"sub.xoor.ru",
+		"blog.logrocket.com",
+		"tags.news.com.au",
+		"tracking.lenzmx.com",
+		"not-blocked.example",
+	} {
+		t.Run(hostname, func(t *testing.T) {
+			t.Parallel()
+
+			want, wantOK := sequential.Match(hostname)
+			got, ok := parallel.Match(hostname)
+			require.Equal(t, wantOK, ok)
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
 func assertMatchRuleText(t *testing.T, rulesText string, rules *DNSResult, ok bool) {
 	assert.True(t, ok)
 	if ok {
@@ -566,6 +855,36 @@ func BenchmarkDNSEngine_heapAlloc(b *testing.B) {
 	//	BenchmarkDNSEngine_heapAlloc-16    	      48	 229129641 ns/op	  24802672 heap_after_loading_bytes	  25683512 heap_after_matching_bytes	  11263960 initial_heap_bytes	54175552 B/op	  874416 allocs/op
 }
 
+func BenchmarkNewDNSEngineWithConfig(b *testing.B) {
+	ruleStorage := newRuleStorage(b)
+	testutil.CleanupAndRequireSuccess(b, ruleStorage.Close)
+
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
+			conf := &DNSEngineConfig{
+				Storage: ruleStorage,
+				Workers: workers,
+			}
+
+			var dnsEngine *DNSEngine
+			b.ReportAllocs()
+			for b.Loop() {
+				dnsEngine = NewDNSEngineWithConfig(conf)
+			}
+
+			require.NotZero(b, dnsEngine.RulesCount)
+		})
+	}
+
+	// Most recent results:
+	//	goos: linux
+	//	goarch: amd64
+	//	pkg: github.com/AdguardTeam/urlfilter
+	//	cpu: Intel(R) Xeon(R) Processor
+	//	BenchmarkNewDNSEngineWithConfig/workers_1         	      10	 106043532 ns/op	37816908 B/op	  691449 allocs/op
+	//	BenchmarkNewDNSEngineWithConfig/workers_4         	       9	 119259305 ns/op	45477336 B/op	  692318 allocs/op
+}
+
 // heapAlloc is a helper that returns the current heap-allocated bytes as
 // counted by the runtime.
 func heapAlloc(tb testing.TB) (heap float64) {
@@ -638,6 +957,41 @@ func BenchmarkDNSEngine_MatchRequestInto(b *testing.B) {
 	//	BenchmarkDNSEngine_MatchRequestInto-16    	     240	  49861837 ns/op	  856973 B/op	   28219 allocs/op
 }
 
+func BenchmarkDNSEngine_MatchRequestInto_noBloomFilter(b *testing.B) {
+	testHostnames := loadHostnames(b)
+
+	ruleStorage := newRuleStorage(b)
+	testutil.CleanupAndRequireSuccess(b, ruleStorage.Close)
+
+	dnsEngine := NewDNSEngineWithConfig(&DNSEngineConfig{
+		Storage:            ruleStorage,
+		DisableBloomFilter: true,
+	})
+
+	var match bool
+	req := &DNSRequest{}
+	res := &DNSResult{}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, reqHostname := range testHostnames {
+			req.Hostname = reqHostname
+			res.Reset()
+
+			match = dnsEngine.MatchRequestInto(req, res)
+		}
+	}
+
+	assert.True(b, match)
+
+	// Most recent results:
+	//	goos: linux
+	//	goarch: amd64
+	//	pkg: github.com/AdguardTeam/urlfilter
+	//	cpu: Intel(R) Xeon(R) Processor
+	//	BenchmarkDNSEngine_MatchRequestInto_noBloomFilter    	      20	  50360544 ns/op	 1193021 B/op	   30929 allocs/op
+}
+
 func FuzzDNSEngine_Match(f *testing.F) {
 	for _, seed := range []string{
 		"",
@@ -0,0 +1,662 @@
+// Package dnsexport converts host-level filtering rules into the configuration
+// formats of DNS resolvers, which cannot use the filtering engine directly.
+package dnsexport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/urlfilter/filterlist"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/miekg/dns"
+)
+
+// Format is the target format of the export.
+type Format uint8
+
+// Format values.
+const (
+	// FormatHosts is the /etc/hosts format.  Blocking rules are exported as
+	// 0.0.0.0 addresses.  Note that hosts files only match the exact hostname,
+	// so the rules don't block the subdomains anymore.
+	FormatHosts Format = iota + 1
+
+	// FormatDnsmasq is the dnsmasq configuration file format.  CNAME rewrites
+	// are exported as cname records, which only match the exact hostname.
+	FormatDnsmasq
+
+	// FormatUnbound is the format of the Unbound local-zone and local-data
+	// statements.  The output is supposed to be included into the server
+	// clause.
+	FormatUnbound
+
+	// FormatRPZ is the Response Policy Zone file format.  The output is a
+	// complete zone file with relative owner names.
+	FormatRPZ
+)
+
+// String implements the [fmt.Stringer] interface for Format.
+func (f Format) String() (s string) {
+	switch f {
+	case FormatHosts:
+		return "hosts"
+	case FormatDnsmasq:
+		return "dnsmasq"
+	case FormatUnbound:
+		return "unbound"
+	case FormatRPZ:
+		return "rpz"
+	default:
+		return fmt.Sprintf("Format(%d)", f)
+	}
+}
+
+const (
+	// ErrBadfilter is reported for the $badfilter rules, since they require
+	// the rules they disable to be removed from the source lists.
+	ErrBadfilter errors.Error = "badfilter rules are not supported"
+
+	// ErrConflict is reported for rules which contradict a previously exported
+	// rule in a way the target format can't express.
+	ErrConflict errors.Error = "rule conflicts with a previously exported rule"
+
+	// ErrFormat is reported for rules which have no equivalent in the target
+	// format.
+	ErrFormat errors.Error = "rule has no equivalent in the target format"
+
+	// ErrNotHostLevel is reported for network rules which have neither the
+	// "||hostname^" nor the "|hostname^" pattern or have modifiers not used in
+	// DNS filtering.
+	ErrNotHostLevel errors.Error = "not a host-level rule"
+
+	// ErrRestricted is reported for network rules with the $client, $ctag,
+	// $denyallow, $dnstype, $domain, or $to modifiers.
+	ErrRestricted errors.Error = "rule has client or request restrictions"
+)
+
+// Config is the configuration structure for [Export].
+type Config struct {
+	// Storage is the storage of the rules to export.  It must not be nil.
+	Storage *filterlist.RuleStorage
+
+	// Format is the target format.  It must be one of the Format values.
+	Format Format
+}
+
+// UnsupportedRule is a rule which couldn't be exported.
+type UnsupportedRule struct {
+	// Rule is the rule itself.
+	Rule rules.Rule
+
+	// Err is the reason why the rule couldn't be exported.
+	Err error
+}
+
+// Report is the result of an export.
+type Report struct {
+	// Unsupported are the rules which couldn't be exported, in the order of
+	// the storage.
+	Unsupported []*UnsupportedRule
+
+	// Exported is the number of rules which produced output.  The rules, the
+	// output of which duplicates that of the previously exported rules, are
+	// not counted.
+	Exported int
+}
+
+// Export writes the host rules and host-level network rules from conf.Storage
+// to w in conf.Format.  Cosmetic rules are skipped silently, and the other rules
+// which cannot be expressed in the target format are listed in rep.  The
+// blocking and allowlist rules for the same hostname are resolved the way the
+// DNS engine does it, so the rules overridden by a higher-priority one are
+// skipped silently as well.  err is only returned for invalid configurations
+// and write errors.
+func Export(w io.Writer, conf *Config) (rep *Report, err error) {
+	f, err := newFormatter(conf.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newPriorities(conf.Storage)
+
+	bw := bufio.NewWriter(w)
+	_, err = bw.WriteString(f.header())
+	if err != nil {
+		return nil, fmt.Errorf("writing header: %w", err)
+	}
+
+	rep = &Report{}
+	sc := conf.Storage.NewRuleStorageScanner()
+	for sc.Scan() {
+		r, _ := sc.Rule()
+
+		var text string
+		text, err = formatRule(f, p, r)
+		if errors.Is(err, errSkip) {
+			continue
+		} else if err != nil {
+			rep.Unsupported = append(rep.Unsupported, &UnsupportedRule{
+				Rule: r,
+				Err:  err,
+			})
+
+			continue
+		}
+
+		if text == "" {
+			// The output duplicates that of the previous rules.
+			continue
+		}
+
+		_, err = bw.WriteString(text)
+		if err != nil {
+			return nil, fmt.Errorf("writing rule %q: %w", r.Text(), err)
+		}
+
+		rep.Exported++
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return nil, fmt.Errorf("flushing: %w", err)
+	}
+
+	return rep, nil
+}
+
+// errSkip is returned by [formatRule] for rules which must be skipped
+// silently.
+const errSkip errors.Error = "skip rule"
+
+// formatRule returns the text of r in the format of f.  The entries overridden
+// according to p are skipped.
+func formatRule(f formatter, p *priorities, r rules.Rule) (text string, err error) {
+	entries, err := ruleEntries(r)
+	if err != nil {
+		return "", err
+	}
+
+	b := &strings.Builder{}
+	for _, e := range entries {
+		if p.isOverridden(e) {
+			continue
+		}
+
+		var s string
+		s, err = f.format(e)
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteString(s)
+	}
+
+	return b.String(), nil
+}
+
+// entryKind is the kind of the DNS response described by an entry.
+type entryKind uint8
+
+// entryKind values.
+const (
+	kindBlock entryKind = iota + 1
+	kindAllow
+	kindAddr
+	kindCNAME
+	kindNoData
+	kindRefused
+)
+
+// entry is a single hostname with the response for it, which is the common
+// denominator of the rules and the target formats.
+type entry struct {
+	// addr is the address of the response for kindAddr.
+	addr netip.Addr
+
+	// host is the lowercased hostname.
+	host string
+
+	// cname is the canonical name of the response for kindCNAME.
+	cname string
+
+	// priority is the priority of the basic blocking or allowlist rule the
+	// entry is created from, see [basicPriority].  It is zero for the other
+	// entries.
+	priority int
+
+	// kind is the kind of the response.
+	kind entryKind
+
+	// subdomains is true if the entry also applies to the subdomains of host.
+	subdomains bool
+}
+
+// ruleEntries converts r into entries.  It returns errSkip for the rules not
+// related to DNS filtering.
+func ruleEntries(r rules.Rule) (entries []*entry, err error) {
+	switch r := r.(type) {
+	case *rules.HostRule:
+		entries = make([]*entry, 0, len(r.Hostnames))
+		for _, h := range r.Hostnames {
+			entries = append(entries, &entry{
+				addr: r.IP,
+				host: strings.ToLower(h),
+				kind: kindAddr,
+			})
+		}
+
+		return entries, nil
+	case *rules.NetworkRule:
+		var e *entry
+		e, err = networkRuleEntry(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*entry{e}, nil
+	default:
+		return nil, errSkip
+	}
+}
+
+// networkRuleEntry converts r into an entry.
+func networkRuleEntry(r *rules.NetworkRule) (e *entry, err error) {
+	if r.IsOptionEnabled(rules.OptionBadfilter) {
+		return nil, ErrBadfilter
+	} else if r.IsRestricted() {
+		return nil, ErrRestricted
+	}
+
+	host, subdomains, ok := r.HostnamePattern()
+	if !ok || !r.IsHostLevelNetworkRule() {
+		return nil, ErrNotHostLevel
+	}
+
+	e = &entry{
+		host:       host,
+		subdomains: subdomains,
+	}
+
+	switch {
+	case r.DNSRewrite != nil && r.Whitelist:
+		return nil, ErrFormat
+	case r.DNSRewrite != nil:
+		err = setRewrite(e, r.DNSRewrite)
+		if err != nil {
+			return nil, err
+		}
+	case r.Whitelist:
+		e.kind, e.priority = kindAllow, basicPriority(r)
+	default:
+		e.kind, e.priority = kindBlock, basicPriority(r)
+	}
+
+	return e, nil
+}
+
+// basicPriority returns the priority of the basic blocking or allowlist rule r
+// in the order of [rules.NetworkRule.IsHigherPriority]: important allowlist
+// rules, important blocking rules, allowlist rules, and blocking rules.
+func basicPriority(r *rules.NetworkRule) (p int) {
+	p = 1
+	if r.IsOptionEnabled(rules.OptionImportant) {
+		p += 2
+	}
+
+	if r.Whitelist {
+		p++
+	}
+
+	return p
+}
+
+// priorities are the highest priorities of the basic blocking and allowlist
+// rules for each hostname.
+type priorities struct {
+	// exact maps the hostnames to the highest priorities of the entries which
+	// only apply to the hostname itself.
+	exact map[string]int
+
+	// subdomains maps the hostnames to the highest priorities of the entries
+	// which also apply to the subdomains.
+	subdomains map[string]int
+}
+
+// newPriorities returns the priorities of the basic blocking and allowlist
+// rules in s.
+func newPriorities(s *filterlist.RuleStorage) (p *priorities) {
+	p = &priorities{
+		exact:      map[string]int{},
+		subdomains: map[string]int{},
+	}
+
+	sc := s.NewRuleStorageScanner()
+	for sc.Scan() {
+		r, _ := sc.Rule()
+		nr, ok := r.(*rules.NetworkRule)
+		if !ok {
+			continue
+		}
+
+		e, err := networkRuleEntry(nr)
+		if err != nil || e.priority == 0 {
+			continue
+		}
+
+		m := p.exact
+		if e.subdomains {
+			m = p.subdomains
+		}
+
+		m[e.host] = max(m[e.host], e.priority)
+	}
+
+	return p
+}
+
+// isOverridden returns true if e is created from a basic blocking or allowlist
+// rule and a rule with a higher priority matches all hostnames e applies to, so
+// that the DNS engine never applies the rule of e.
+func (p *priorities) isOverridden(e *entry) (ok bool) {
+	if e.priority == 0 {
+		return false
+	}
+
+	best := p.subdomains[e.host]
+	if !e.subdomains {
+		best = max(best, p.exact[e.host])
+	}
+
+	for host := e.host; best <= e.priority; {
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+
+		host = host[i+1:]
+		best = max(best, p.subdomains[host])
+	}
+
+	return best > e.priority
+}
+
+// setRewrite sets the response of e from rw.
+func setRewrite(e *entry, rw *rules.DNSRewrite) (err error) {
+	switch {
+	case rw.NewCNAME != "":
+		e.kind, e.cname = kindCNAME, strings.ToLower(rw.NewCNAME)
+	case rw.RCode == dns.RcodeNameError:
+		e.kind = kindBlock
+	case rw.RCode == dns.RcodeRefused:
+		e.kind = kindRefused
+	case rw.RCode != dns.RcodeSuccess:
+		return ErrFormat
+	case rw.RRType == 0:
+		e.kind = kindNoData
+	case rw.RRType == dns.TypeA, rw.RRType == dns.TypeAAAA:
+		e.kind, e.addr = kindAddr, rw.Value.(netip.Addr)
+	default:
+		return ErrFormat
+	}
+
+	return nil
+}
+
+// addrType returns the name of the resource record type for addr.
+func addrType(addr netip.Addr) (rrType string) {
+	if addr.Is4() {
+		return "A"
+	}
+
+	return "AAAA"
+}
+
+// formatter converts entries into the text of a target format.
+type formatter interface {
+	// header returns the text to write before all entries.
+	header() (text string)
+
+	// format returns the text for e, each line of which ends with a newline.
+	// text is empty if it duplicates the text of the previous entries.  It
+	// returns an error if e cannot be expressed in the target format.
+	format(e *entry) (text string, err error)
+}
+
+// newFormatter returns a new formatter for f.
+func newFormatter(f Format) (fmtr formatter, err error) {
+	switch f {
+	case FormatHosts:
+		return hostsFormatter{}, nil
+	case FormatDnsmasq:
+		return dnsmasqFormatter{}, nil
+	case FormatUnbound:
+		return &unboundFormatter{
+			zones: map[string]string{},
+			data:  map[string]struct{}{},
+		}, nil
+	case FormatRPZ:
+		return &rpzFormatter{owners: map[string][]string{}}, nil
+	default:
+		return nil, fmt.Errorf("format: %w: %d", errors.ErrBadEnumValue, f)
+	}
+}
+
+// hostsFormatter is the formatter for [FormatHosts].
+type hostsFormatter struct{}
+
+// type check
+var _ formatter = hostsFormatter{}
+
+// header implements the [formatter] interface for hostsFormatter.
+func (hostsFormatter) header() (text string) {
+	return ""
+}
+
+// format implements the [formatter] interface for hostsFormatter.
+func (hostsFormatter) format(e *entry) (text string, err error) {
+	switch e.kind {
+	case kindBlock:
+		return "0.0.0.0 " + e.host + "\n", nil
+	case kindAddr:
+		return e.addr.String() + " " + e.host + "\n", nil
+	default:
+		return "", ErrFormat
+	}
+}
+
+// dnsmasqFormatter is the formatter for [FormatDnsmasq].
+type dnsmasqFormatter struct{}
+
+// type check
+var _ formatter = dnsmasqFormatter{}
+
+// header implements the [formatter] interface for dnsmasqFormatter.
+func (dnsmasqFormatter) header() (text string) {
+	return ""
+}
+
+// format implements the [formatter] interface for dnsmasqFormatter.  The
+// address and server options always apply to the subdomains as well.
+func (dnsmasqFormatter) format(e *entry) (text string, err error) {
+	if e.kind != kindAddr && e.kind != kindCNAME && !e.subdomains {
+		return "", ErrFormat
+	}
+
+	switch e.kind {
+	case kindBlock:
+		return "address=/" + e.host + "/\n", nil
+	case kindAllow:
+		return "server=/" + e.host + "/#\n", nil
+	case kindAddr:
+		if e.subdomains {
+			return "address=/" + e.host + "/" + e.addr.String() + "\n", nil
+		}
+
+		return "host-record=" + e.host + "," + e.addr.String() + "\n", nil
+	case kindCNAME:
+		return "cname=" + e.host + "," + e.cname + "\n", nil
+	default:
+		return "", ErrFormat
+	}
+}
+
+// unboundFormatter is the formatter for [FormatUnbound].
+type unboundFormatter struct {
+	// zones maps the names of the declared local zones to their types, since
+	// Unbound doesn't allow declaring a zone twice.
+	zones map[string]string
+
+	// data is the set of the written local-data statements.
+	data map[string]struct{}
+}
+
+// type check
+var _ formatter = (*unboundFormatter)(nil)
+
+// header implements the [formatter] interface for *unboundFormatter.
+func (*unboundFormatter) header() (text string) {
+	return ""
+}
+
+// unboundZoneTypes are the types of the Unbound local zones for the entry kinds
+// which are exported as local zones.
+var unboundZoneTypes = map[entryKind]string{
+	kindBlock:   "always_nxdomain",
+	kindAllow:   "transparent",
+	kindNoData:  "always_nodata",
+	kindRefused: "always_refuse",
+}
+
+// format implements the [formatter] interface for *unboundFormatter.  The local
+// zones always apply to the subdomains as well.
+func (f *unboundFormatter) format(e *entry) (text string, err error) {
+	if typ, ok := unboundZoneTypes[e.kind]; ok {
+		if !e.subdomains {
+			return "", ErrFormat
+		}
+
+		return f.zone(e.host, typ)
+	}
+
+	var data string
+	switch e.kind {
+	case kindAddr:
+		data = fmt.Sprintf("%s. %s %s", e.host, addrType(e.addr), e.addr)
+	case kindCNAME:
+		data = fmt.Sprintf("%s. CNAME %s.", e.host, e.cname)
+	default:
+		return "", ErrFormat
+	}
+
+	if e.subdomains {
+		text, err = f.zone(e.host, "redirect")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if _, ok := f.data[data]; ok {
+		return text, nil
+	}
+
+	f.data[data] = struct{}{}
+
+	return text + "local-data: \"" + data + "\"\n", nil
+}
+
+// zone returns the declaration of the local zone for host with the type typ.
+// text is empty if the zone has already been declared with the same type.
+func (f *unboundFormatter) zone(host, typ string) (text string, err error) {
+	if prev, ok := f.zones[host]; ok {
+		if prev != typ {
+			return "", ErrConflict
+		}
+
+		return "", nil
+	}
+
+	f.zones[host] = typ
+
+	return "local-zone: \"" + host + ".\" " + typ + "\n", nil
+}
+
+// rpzFormatter is the formatter for [FormatRPZ].
+type rpzFormatter struct {
+	// owners maps the written owner names to the data of their records, since
+	// a CNAME record can't coexist with any other record of the same owner.
+	owners map[string][]string
+}
+
+// type check
+var _ formatter = (*rpzFormatter)(nil)
+
+// header implements the [formatter] interface for *rpzFormatter.
+func (*rpzFormatter) header() (text string) {
+	return "$TTL 3600\n" +
+		"@ IN SOA localhost. root.localhost. 1 3600 600 86400 3600\n" +
+		"@ IN NS localhost.\n"
+}
+
+// format implements the [formatter] interface for *rpzFormatter.
+func (f *rpzFormatter) format(e *entry) (text string, err error) {
+	data, err := rpzData(e)
+	if err != nil {
+		return "", err
+	}
+
+	owners := []string{e.host}
+	if e.subdomains {
+		owners = append(owners, "*."+e.host)
+	}
+
+	// Check all owners first so that a conflicting entry writes nothing.
+	for _, o := range owners {
+		if rpzConflicts(f.owners[o], data) {
+			return "", ErrConflict
+		}
+	}
+
+	for _, o := range owners {
+		if slices.Contains(f.owners[o], data) {
+			continue
+		}
+
+		f.owners[o] = append(f.owners[o], data)
+		text += o + " " + data + "\n"
+	}
+
+	return text, nil
+}
+
+// rpzData returns the type and the data of the RPZ record for e.
+func rpzData(e *entry) (data string, err error) {
+	switch e.kind {
+	case kindBlock:
+		return "CNAME .", nil
+	case kindAllow:
+		return "CNAME rpz-passthru.", nil
+	case kindNoData:
+		return "CNAME *.", nil
+	case kindAddr:
+		return addrType(e.addr) + " " + e.addr.String(), nil
+	case kindCNAME:
+		return "CNAME " + e.cname + ".", nil
+	default:
+		return "", ErrFormat
+	}
+}
+
+// rpzConflicts returns true if the record with data can't be added to the
+// owner with the records with prev data.
+func rpzConflicts(prev []string, data string) (ok bool) {
+	if len(prev) == 0 || slices.Contains(prev, data) {
+		return false
+	}
+
+	return strings.HasPrefix(data, "CNAME ") || strings.HasPrefix(prev[0], "CNAME ")
+}
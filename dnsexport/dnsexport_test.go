@@ -0,0 +1,340 @@
+package dnsexport_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/dnsexport"
+	"github.com/AdguardTeam/urlfilter/filterlist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testListID is the common filter list ID for tests.
+const testListID = 1
+
+// testRules are the rules used in the export tests.
+const testRules = `||block.example^
+|exact.example^
+@@||allow.block.example^
+||ipv4.example^$dnsrewrite=1.2.3.4
+||ipv6.example^$dnsrewrite=NOERROR;AAAA;2001:db8::1
+||cname.example^$dnsrewrite=target.example
+||nodata.example^$dnsrewrite=NOERROR;;
+||refused.example^$dnsrewrite=REFUSED
+192.168.0.1 host.example
+example.org##.banner
+/regex/
+||script.example^$script,third-party
+||client.example^$client=127.0.0.1
+||bad.example^$badfilter
+||mx.example^$dnsrewrite=NOERROR;MX;10 mail.example`
+
+// newTestStorage returns a new rule storage with text for tests.
+func newTestStorage(tb testing.TB, text string) (s *filterlist.RuleStorage) {
+	tb.Helper()
+
+	list := filterlist.NewString(&filterlist.StringConfig{
+		RulesText: text,
+		ID:        testListID,
+	})
+
+	s, err := filterlist.NewRuleStorage([]filterlist.Interface{list})
+	require.NoError(tb, err)
+	testutil.CleanupAndRequireSuccess(tb, s.Close)
+
+	return s
+}
+
+// unsupportedTexts returns the texts of the unsupported rules in rep.
+func unsupportedTexts(rep *dnsexport.Report) (texts []string) {
+	for _, u := range rep.Unsupported {
+		texts = append(texts, u.Rule.Text())
+	}
+
+	return texts
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	// alwaysUnsupported are the rules that cannot be exported to any format.
+	alwaysUnsupported := []string{
+		"/regex/",
+		"||script.example^$script,third-party",
+		"||client.example^$client=127.0.0.1",
+		"||bad.example^$badfilter",
+		"||mx.example^$dnsrewrite=NOERROR;MX;10 mail.example",
+	}
+
+	testCases := []struct {
+		name            string
+		want            string
+		wantUnsupported []string
+		format          dnsexport.Format
+	}{{
+		name: "hosts",
+		want: `0.0.0.0 block.example
+0.0.0.0 exact.example
+1.2.3.4 ipv4.example
+2001:db8::1 ipv6.example
+192.168.0.1 host.example
+`,
+		wantUnsupported: []string{
+			"@@||allow.block.example^",
+			"||cname.example^$dnsrewrite=target.example",
+			"||nodata.example^$dnsrewrite=NOERROR;;",
+			"||refused.example^$dnsrewrite=REFUSED",
+		},
+		format: dnsexport.FormatHosts,
+	}, {
+		name: "dnsmasq",
+		want: `address=/block.example/
+server=/allow.block.example/#
+address=/ipv4.example/1.2.3.4
+address=/ipv6.example/2001:db8::1
+cname=cname.example,target.example
+host-record=host.example,192.168.0.1
+`,
+		wantUnsupported: []string{
+			"|exact.example^",
+			"||nodata.example^$dnsrewrite=NOERROR;;",
+			"||refused.example^$dnsrewrite=REFUSED",
+		},
+		format: dnsexport.FormatDnsmasq,
+	}, {
+		name: "unbound",
+		want: `local-zone: "block.example." always_nxdomain
+local-zone: "allow.block.example." transparent
+local-zone: "ipv4.example." redirect
+local-data: "ipv4.example. A 1.2.3.4"
+local-zone: "ipv6.example." redirect
+local-data: "ipv6.example. AAAA 2001:db8::1"
+local-zone: "cname.example." redirect
+local-data: "cname.example. CNAME target.example."
+local-zone: "nodata.example." always_nodata
+local-zone: "refused.example." always_refuse
+local-data: "host.example. A 192.168.0.1"
+`,
+		wantUnsupported: []string{
+			"|exact.example^",
+		},
+		format: dnsexport.FormatUnbound,
+	}, {
+		name: "rpz",
+		want: `$TTL 3600
+@ IN SOA localhost. root.localhost. 1 3600 600 86400 3600
+@ IN NS localhost.
+block.example CNAME .
+*.block.example CNAME .
+exact.example CNAME .
+allow.block.example CNAME rpz-passthru.
+*.allow.block.example CNAME rpz-passthru.
+ipv4.example A 1.2.3.4
+*.ipv4.example A 1.2.3.4
+ipv6.example AAAA 2001:db8::1
+*.ipv6.example AAAA 2001:db8::1
+cname.example CNAME target.example.
+*.cname.example CNAME target.example.
+nodata.example CNAME *.
+*.nodata.example CNAME *.
+host.example A 192.168.0.1
+`,
+		wantUnsupported: []string{
+			"||refused.example^$dnsrewrite=REFUSED",
+		},
+		format: dnsexport.FormatRPZ,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := &strings.Builder{}
+			rep, err := dnsexport.Export(b, &dnsexport.Config{
+				Storage: newTestStorage(t, testRules),
+				Format:  tc.format,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, b.String())
+
+			wantUnsupported := append(tc.wantUnsupported, alwaysUnsupported...)
+			assert.ElementsMatch(t, wantUnsupported, unsupportedTexts(rep))
+			assert.Equal(t, 14-len(wantUnsupported), rep.Exported)
+		})
+	}
+}
+
+func TestExport_conflict(t *testing.T) {
+	t.Parallel()
+
+	const text = "||example.org^\n" +
+		"@@||example.org^\n" +
+		"||example.org^$dnsrewrite=NXDOMAIN\n" +
+		"||example.org^$dnsrewrite=1.2.3.4"
+
+	testCases := []struct {
+		name   string
+		want   string
+		format dnsexport.Format
+	}{{
+		name:   "unbound",
+		want:   "local-zone: \"example.org.\" transparent\n",
+		format: dnsexport.FormatUnbound,
+	}, {
+		name: "rpz",
+		want: "$TTL 3600\n" +
+			"@ IN SOA localhost. root.localhost. 1 3600 600 86400 3600\n" +
+			"@ IN NS localhost.\n" +
+			"example.org CNAME rpz-passthru.\n" +
+			"*.example.org CNAME rpz-passthru.\n",
+		format: dnsexport.FormatRPZ,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := &strings.Builder{}
+			rep, err := dnsexport.Export(b, &dnsexport.Config{
+				Storage: newTestStorage(t, text),
+				Format:  tc.format,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, b.String())
+			assert.Equal(t, 1, rep.Exported)
+
+			require.Len(t, rep.Unsupported, 2)
+
+			wantTexts := []string{
+				"||example.org^$dnsrewrite=NXDOMAIN",
+				"||example.org^$dnsrewrite=1.2.3.4",
+			}
+			for i, want := range wantTexts {
+				assert.Equal(t, want, rep.Unsupported[i].Rule.Text())
+				assert.ErrorIs(t, rep.Unsupported[i].Err, dnsexport.ErrConflict)
+			}
+		})
+	}
+}
+
+func TestExport_priority(t *testing.T) {
+	t.Parallel()
+
+	const text = "||example.org^\n" +
+		"@@||example.org^\n" +
+		"||important.example^$important\n" +
+		"@@||important.example^\n" +
+		"||sub.allow.example^\n" +
+		"@@||allow.example^\n" +
+		"||parent.example^\n" +
+		"@@|sub.parent.example^\n" +
+		"@@||both.example^\n" +
+		"||both.example^$important\n" +
+		"@@||both.example^$important"
+
+	testCases := []struct {
+		name   string
+		want   string
+		format dnsexport.Format
+	}{{
+		name: "dnsmasq",
+		want: "server=/example.org/#\n" +
+			"address=/important.example/\n" +
+			"server=/allow.example/#\n" +
+			"address=/parent.example/\n" +
+			"server=/both.example/#\n",
+		format: dnsexport.FormatDnsmasq,
+	}, {
+		name: "unbound",
+		want: "local-zone: \"example.org.\" transparent\n" +
+			"local-zone: \"important.example.\" always_nxdomain\n" +
+			"local-zone: \"allow.example.\" transparent\n" +
+			"local-zone: \"parent.example.\" always_nxdomain\n" +
+			"local-zone: \"both.example.\" transparent\n",
+		format: dnsexport.FormatUnbound,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := &strings.Builder{}
+			rep, err := dnsexport.Export(b, &dnsexport.Config{
+				Storage: newTestStorage(t, text),
+				Format:  tc.format,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, b.String())
+			assert.Equal(t, 5, rep.Exported)
+
+			// The allowlist rule for the exact hostname can't be expressed in
+			// these formats.
+			require.Len(t, rep.Unsupported, 1)
+
+			assert.Equal(t, "@@|sub.parent.example^", rep.Unsupported[0].Rule.Text())
+			assert.ErrorIs(t, rep.Unsupported[0].Err, dnsexport.ErrFormat)
+		})
+	}
+}
+
+func TestExport_duplicates(t *testing.T) {
+	t.Parallel()
+
+	const text = "||example.org^$dnsrewrite=1.2.3.4\n" +
+		"||example.org^$dnsrewrite=1.2.3.4\n" +
+		"||example.org^$dnsrewrite=1.2.3.5"
+
+	testCases := []struct {
+		name   string
+		want   string
+		format dnsexport.Format
+	}{{
+		name: "unbound",
+		want: "local-zone: \"example.org.\" redirect\n" +
+			"local-data: \"example.org. A 1.2.3.4\"\n" +
+			"local-data: \"example.org. A 1.2.3.5\"\n",
+		format: dnsexport.FormatUnbound,
+	}, {
+		name: "rpz",
+		want: "$TTL 3600\n" +
+			"@ IN SOA localhost. root.localhost. 1 3600 600 86400 3600\n" +
+			"@ IN NS localhost.\n" +
+			"example.org A 1.2.3.4\n" +
+			"*.example.org A 1.2.3.4\n" +
+			"example.org A 1.2.3.5\n" +
+			"*.example.org A 1.2.3.5\n",
+		format: dnsexport.FormatRPZ,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := &strings.Builder{}
+			rep, err := dnsexport.Export(b, &dnsexport.Config{
+				Storage: newTestStorage(t, text),
+				Format:  tc.format,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, b.String())
+			assert.Equal(t, 2, rep.Exported)
+			assert.Empty(t, rep.Unsupported)
+		})
+	}
+}
+
+func TestExport_badFormat(t *testing.T) {
+	t.Parallel()
+
+	_, err := dnsexport.Export(&strings.Builder{}, &dnsexport.Config{
+		Storage: newTestStorage(t, ""),
+		Format:  0,
+	})
+	assert.Error(t, err)
+}
@@ -42,5 +42,6 @@ func (e *Engine) GetCosmeticResult(hostname string, option rules.CosmeticOption)
 	includeCSS := option&rules.CosmeticOptionCSS == rules.CosmeticOptionCSS
 	includeGenericCSS := option&rules.CosmeticOptionGenericCSS == rules.CosmeticOptionGenericCSS
 	includeJS := option&rules.CosmeticOptionJS == rules.CosmeticOptionJS
-	return e.cosmeticEngine.Match(hostname, includeCSS, includeJS, includeGenericCSS)
+	includeSpecificCSS := option&rules.CosmeticOptionNoSpecificCSS == 0
+	return e.cosmeticEngine.match(hostname, includeCSS, includeJS, includeGenericCSS, includeSpecificCSS)
 }
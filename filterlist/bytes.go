@@ -17,6 +17,11 @@ type BytesConfig struct {
 
 	// IgnoreCosmetic tells whether to ignore cosmetic rules or not.
 	IgnoreCosmetic bool
+
+	// Trusted tells whether the list is trusted to contain the JS rules with
+	// the arbitrary JavaScript code.  Such rules of the untrusted lists are
+	// ignored, while the scriptlets are allowed in any list.
+	Trusted bool
 }
 
 // Bytes is an [Interface] implementation which stores rules within a byte
@@ -25,6 +30,7 @@ type Bytes struct {
 	rulesText      []byte
 	id             int
 	ignoreCosmetic bool
+	trusted        bool
 }
 
 // NewBytes creates a new bytes-based rule list with the given configuration.
@@ -33,6 +39,7 @@ func NewBytes(conf *BytesConfig) (s *Bytes) {
 		rulesText:      conf.RulesText,
 		id:             conf.ID,
 		ignoreCosmetic: conf.IgnoreCosmetic,
+		trusted:        conf.Trusted,
 	}
 }
 
@@ -46,7 +53,10 @@ func (b *Bytes) GetID() (id int) {
 
 // NewScanner implements the [Interface] interface for *Bytes.
 func (b *Bytes) NewScanner() (sc *RuleScanner) {
-	return NewRuleScanner(bytes.NewReader(b.rulesText), b.id, b.ignoreCosmetic)
+	sc = NewRuleScanner(bytes.NewReader(b.rulesText), b.id, b.ignoreCosmetic)
+	sc.trusted = b.trusted
+
+	return sc
 }
 
 // RetrieveRule implements the [Interface] interface for *Bytes.
@@ -21,6 +21,11 @@ type FileConfig struct {
 
 	// IgnoreCosmetic tells whether to ignore cosmetic rules or not.
 	IgnoreCosmetic bool
+
+	// Trusted tells whether the list is trusted to contain the JS rules with
+	// the arbitrary JavaScript code.  Such rules of the untrusted lists are
+	// ignored, while the scriptlets are allowed in any list.
+	Trusted bool
 }
 
 // File is an [Interface] implementation which stores rules within a file.
@@ -41,6 +46,10 @@ type File struct {
 
 	// ignoreCosmetic tells whether to ignore cosmetic rules or not.
 	ignoreCosmetic bool
+
+	// trusted tells whether the list is trusted to contain the JS rules with
+	// the arbitrary JavaScript code.
+	trusted bool
 }
 
 // NewFile creates a new file-based rule list with the given configuration.
@@ -48,6 +57,7 @@ func NewFile(conf *FileConfig) (f *File, err error) {
 	f = &File{
 		id:             conf.ID,
 		ignoreCosmetic: conf.IgnoreCosmetic,
+		trusted:        conf.Trusted,
 		buffer:         make([]byte, readerBufferSize),
 	}
 
@@ -71,7 +81,10 @@ func (l *File) GetID() (id int) {
 func (l *File) NewScanner() (sc *RuleScanner) {
 	_, _ = l.file.Seek(0, io.SeekStart)
 
-	return NewRuleScanner(l.file, l.id, l.ignoreCosmetic)
+	sc = NewRuleScanner(l.file, l.id, l.ignoreCosmetic)
+	sc.trusted = l.trusted
+
+	return sc
 }
 
 // RetrieveRule finds and deserializes rule by its index.  If there's no rule by
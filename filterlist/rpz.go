@@ -0,0 +1,159 @@
+package filterlist
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/AdguardTeam/urlfilter/internal/ufnet"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/miekg/dns"
+)
+
+// RPZConfig represents configuration for a rule list built from a DNS Response
+// Policy Zone file.
+type RPZConfig struct {
+	// ZoneData is the contents of the zone file.
+	ZoneData []byte
+
+	// Origin is the name of the policy zone, for example "rpz.example".  The
+	// owner names of the records are made relative to it.  If empty, the root
+	// zone is used, so that the relative owner names in the file are used as
+	// is.
+	Origin string
+
+	// ID is the rule list identifier.
+	ID int
+}
+
+// RPZ is an [Interface] implementation which converts the QNAME triggers of a
+// DNS Response Policy Zone into network rules with the $dnsrewrite modifier:
+//
+//	example.com CNAME .                 |example.com^$dnsrewrite=NXDOMAIN
+//	example.com CNAME *.                |example.com^$dnsrewrite=NOERROR;;
+//	example.com CNAME rpz-passthru.     @@|example.com^
+//	example.com CNAME host.example.     |example.com^$dnsrewrite=NOERROR;CNAME;host.example
+//	example.com A 1.2.3.4               |example.com^$dnsrewrite=NOERROR;A;1.2.3.4
+//	*.example.com AAAA 2001:db8::1      ||*.example.com^$dnsrewrite=NOERROR;AAAA;2001:db8::1
+//
+// Other triggers, such as rpz-ip and rpz-nsdname, as well as the rpz-drop and
+// rpz-tcp-only actions, have no equivalent and are skipped.
+type RPZ struct {
+	// list is the list with the converted rules.
+	list *String
+}
+
+// NewRPZ parses the zone file from conf and returns a new rule list with the
+// converted rules.
+func NewRPZ(conf *RPZConfig) (l *RPZ, err error) {
+	origin := dns.Fqdn(strings.ToLower(conf.Origin))
+	zp := dns.NewZoneParser(bytes.NewReader(conf.ZoneData), origin, "")
+
+	b := &strings.Builder{}
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		text := rpzRuleText(rr, origin)
+		if text != "" {
+			b.WriteString(text)
+			b.WriteByte('\n')
+		}
+	}
+
+	err = zp.Err()
+	if err != nil {
+		return nil, fmt.Errorf("parsing zone: %w", err)
+	}
+
+	return &RPZ{
+		list: NewString(&StringConfig{
+			RulesText:      b.String(),
+			ID:             conf.ID,
+			IgnoreCosmetic: true,
+		}),
+	}, nil
+}
+
+// type check
+var _ Interface = (*RPZ)(nil)
+
+// GetID implements the [Interface] interface for *RPZ.
+func (l *RPZ) GetID() (id int) {
+	return l.list.GetID()
+}
+
+// NewScanner implements the [Interface] interface for *RPZ.
+func (l *RPZ) NewScanner() (sc *RuleScanner) {
+	return l.list.NewScanner()
+}
+
+// RetrieveRule implements the [Interface] interface for *RPZ.
+func (l *RPZ) RetrieveRule(ruleIdx int) (r rules.Rule, err error) {
+	return l.list.RetrieveRule(ruleIdx)
+}
+
+// Close implements the [Interface] interface for *RPZ.
+func (l *RPZ) Close() (err error) {
+	return l.list.Close()
+}
+
+// rpzRuleText returns the text of the network rule equivalent to rr from the
+// zone with the fully-qualified name origin.  text is empty if rr has no
+// equivalent.
+func rpzRuleText(rr dns.RR, origin string) (text string) {
+	pattern := rpzPattern(rr.Header().Name, origin)
+	if pattern == "" {
+		return ""
+	}
+
+	var rewrite string
+	switch rr := rr.(type) {
+	case *dns.CNAME:
+		switch target := strings.ToLower(rr.Target); target {
+		case ".":
+			rewrite = "NXDOMAIN"
+		case "*.":
+			rewrite = "NOERROR;;"
+		case "rpz-passthru.":
+			return "@@" + pattern
+		case "rpz-drop.", "rpz-tcp-only.":
+			return ""
+		default:
+			rewrite = "NOERROR;CNAME;" + strings.TrimSuffix(target, ".")
+		}
+	case *dns.A:
+		rewrite = "NOERROR;A;" + rr.A.String()
+	case *dns.AAAA:
+		rewrite = "NOERROR;AAAA;" + rr.AAAA.String()
+	default:
+		return ""
+	}
+
+	return pattern + "$dnsrewrite=" + rewrite
+}
+
+// rpzPattern returns the network rule pattern for the QNAME trigger name from
+// the zone with the fully-qualified name origin.  pattern is empty if name is
+// not a QNAME trigger.
+func rpzPattern(name, origin string) (pattern string) {
+	suffix := "."
+	if origin != "." {
+		suffix += origin
+	}
+
+	name, ok := strings.CutSuffix(strings.ToLower(name), suffix)
+	if !ok {
+		return ""
+	}
+
+	pattern = rules.MaskPipe + name + rules.MaskSeparator
+	if wildcard, found := strings.CutPrefix(name, "*."); found {
+		name = wildcard
+		pattern = rules.MaskStartURL + "*." + name + rules.MaskSeparator
+	}
+
+	// Skip the other triggers, which use special labels like "rpz-ip".
+	if !ufnet.IsDomainName(name) || strings.Contains("."+name, ".rpz-") {
+		return ""
+	}
+
+	return pattern
+}
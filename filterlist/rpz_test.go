@@ -0,0 +1,93 @@
+package filterlist_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/filterlist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRPZData is the common RPZ zone file for tests.
+const testRPZData = `$TTL 3600
+@ IN SOA localhost. root.localhost. 1 3600 600 86400 3600
+@ IN NS localhost.
+nxdomain.example CNAME .
+nodata.example CNAME *.
+allowed.example CNAME rpz-passthru.
+dropped.example CNAME rpz-drop.
+cname.example CNAME Target.Example.
+ipv4.example A 1.2.3.4
+*.ipv6.example AAAA 2001:db8::1
+32.1.0.0.10.rpz-ip CNAME .
+ns.example.rpz-nsdname CNAME .
+absolute.example.rpz.test. CNAME .
+other.test. CNAME .
+`
+
+func TestNewRPZ(t *testing.T) {
+	t.Parallel()
+
+	l, err := filterlist.NewRPZ(&filterlist.RPZConfig{
+		ZoneData: []byte(testRPZData),
+		Origin:   "rpz.test",
+		ID:       testListID,
+	})
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, l.Close)
+
+	assert.Equal(t, testListID, l.GetID())
+
+	var texts []string
+	sc := l.NewScanner()
+	for sc.Scan() {
+		r, idx := sc.Rule()
+		require.NotNil(t, r)
+
+		texts = append(texts, r.Text())
+
+		retrieved, rErr := l.RetrieveRule(idx)
+		require.NoError(t, rErr)
+
+		assert.Equal(t, r.Text(), retrieved.Text())
+	}
+
+	want := []string{
+		"|nxdomain.example^$dnsrewrite=NXDOMAIN",
+		"|nodata.example^$dnsrewrite=NOERROR;;",
+		"@@|allowed.example^",
+		"|cname.example^$dnsrewrite=NOERROR;CNAME;target.example",
+		"|ipv4.example^$dnsrewrite=NOERROR;A;1.2.3.4",
+		"||*.ipv6.example^$dnsrewrite=NOERROR;AAAA;2001:db8::1",
+		"|absolute.example^$dnsrewrite=NXDOMAIN",
+	}
+	assert.Equal(t, want, texts)
+}
+
+func TestNewRPZ_noOrigin(t *testing.T) {
+	t.Parallel()
+
+	l, err := filterlist.NewRPZ(&filterlist.RPZConfig{
+		ZoneData: []byte("example.org 300 CNAME .\n"),
+		ID:       testListID,
+	})
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, l.Close)
+
+	r, err := l.RetrieveRule(0)
+	require.NoError(t, err)
+
+	assert.Equal(t, "|example.org^$dnsrewrite=NXDOMAIN", r.Text())
+}
+
+func TestNewRPZ_error(t *testing.T) {
+	t.Parallel()
+
+	_, err := filterlist.NewRPZ(&filterlist.RPZConfig{
+		ZoneData: []byte("example.org CNAME\n"),
+		Origin:   "rpz.test",
+		ID:       testListID,
+	})
+	assert.Error(t, err)
+}
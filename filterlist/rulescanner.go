@@ -26,6 +26,10 @@ type RuleScanner struct {
 
 	// ignoreCosmetic tells whether to ignore cosmetic rules or not.
 	ignoreCosmetic bool
+
+	// trusted tells whether the JS rules with the arbitrary JavaScript code
+	// are allowed.
+	trusted bool
 }
 
 // NewRuleScanner returns a new RuleScanner to read from the given reader.
@@ -47,8 +51,8 @@ func (s *RuleScanner) Scan() (ok bool) {
 			return false
 		}
 
-		rule, err := rules.NewRule(line, s.listID)
-		if rule != nil && err == nil && !s.isIgnored(rule) {
+		rule := s.parseLine(line)
+		if rule != nil {
 			s.currentRule = rule
 			s.currentRuleIndex = index
 
@@ -63,6 +67,18 @@ func (s *RuleScanner) Rule() (r rules.Rule, idx int) {
 	return s.currentRule, s.currentRuleIndex
 }
 
+// parseLine returns the rule parsed from line.  r is nil if the line is not a
+// valid rule or if the rule is ignored by this scanner.  It is safe for
+// concurrent use.
+func (s *RuleScanner) parseLine(line string) (r rules.Rule) {
+	r, err := rules.NewRule(line, s.listID)
+	if r == nil || err != nil || s.isIgnored(r) {
+		return nil
+	}
+
+	return r
+}
+
 // readNextLine reads the next line and returns it and the index of the
 // beginning of the string.
 func (s *RuleScanner) readNextLine() (line string, idx int, err error) {
@@ -84,15 +100,16 @@ func (s *RuleScanner) readNextLine() (line string, idx int, err error) {
 	}
 }
 
-// isIgnored checks if the rule should be ignored by this scanner.
+// isIgnored checks if the rule should be ignored by this scanner.  The JS
+// rules with the arbitrary JavaScript code are only allowed in the trusted
+// lists.
 func (s *RuleScanner) isIgnored(f rules.Rule) (ignored bool) {
-	if !s.ignoreCosmetic {
+	c, ok := f.(*rules.CosmeticRule)
+	if !ok {
 		return false
-	}
-
-	if _, ok := f.(*rules.CosmeticRule); ok {
+	} else if s.ignoreCosmetic {
 		return true
 	}
 
-	return false
+	return !s.trusted && c.Type == rules.CosmeticJS && !c.Whitelist && !c.IsScriptlet()
 }
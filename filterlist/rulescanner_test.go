@@ -57,6 +57,61 @@ func TestRuleScanner_fileReader(t *testing.T) {
 	assert.False(t, scanner.Scan())
 }
 
+func TestRuleScanner_trusted(t *testing.T) {
+	t.Parallel()
+
+	const rulesText = "example.org##.banner\n" +
+		"example.org##+js(set-constant, foo, true)\n" +
+		"example.org#%#//scriptlet('set-constant', 'foo', 'true')\n" +
+		"example.org#@%#window.ads = false;\n" +
+		"example.org#%#window.ads = false;\n"
+
+	testCases := []struct {
+		name    string
+		want    []string
+		trusted bool
+	}{{
+		name: "untrusted",
+		want: []string{
+			"example.org##.banner",
+			"example.org##+js(set-constant, foo, true)",
+			"example.org#%#//scriptlet('set-constant', 'foo', 'true')",
+			"example.org#@%#window.ads = false;",
+		},
+		trusted: false,
+	}, {
+		name: "trusted",
+		want: []string{
+			"example.org##.banner",
+			"example.org##+js(set-constant, foo, true)",
+			"example.org#%#//scriptlet('set-constant', 'foo', 'true')",
+			"example.org#@%#window.ads = false;",
+			"example.org#%#window.ads = false;",
+		},
+		trusted: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := filterlist.NewString(&filterlist.StringConfig{
+				RulesText: rulesText,
+				ID:        testListID,
+				Trusted:   tc.trusted,
+			})
+
+			var got []string
+			for scanner := l.NewScanner(); scanner.Scan(); {
+				f, _ := scanner.Rule()
+				got = append(got, f.Text())
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
 func BenchmarkRuleScanner_Scan(b *testing.B) {
 	r := strings.NewReader(testRuleText)
 	s := filterlist.NewRuleScanner(r, testListID, false)
@@ -0,0 +1,172 @@
+package filterlist
+
+import (
+	"github.com/AdguardTeam/urlfilter/rules"
+)
+
+// scanChunkSize is the number of lines parsed by a worker at once.
+const scanChunkSize = 1024
+
+// ScanConfig is the configuration structure for [RuleStorage.Scan].
+type ScanConfig struct {
+	// Handler is called for each rule in the storage with its storage index,
+	// in the same order as [RuleStorageScanner] returns them.  It is called on
+	// the goroutine calling [RuleStorage.Scan].  It must not be nil.
+	Handler func(r rules.Rule, storageIdx int64)
+
+	// LineFilter, if not nil, is called for each line before parsing it.  If
+	// it returns false, the line is skipped without parsing, which makes it
+	// much cheaper to scan for a few specific rules.  It is called
+	// concurrently on the worker goroutines.
+	LineFilter func(line string) (ok bool)
+
+	// Filter, if not nil, is called for each rule before Handler.  If it
+	// returns false, the rule is skipped.  It is called concurrently on the
+	// worker goroutines, so it can be used to move more of the work there.
+	Filter func(r rules.Rule) (ok bool)
+
+	// Workers is the number of goroutines parsing the rules.  If it is less
+	// than two, the rules are parsed on the calling goroutine.
+	Workers int
+}
+
+// scanChunk is a chunk of lines from a rule list parsed by a single worker.
+type scanChunk struct {
+	// scanner is the scanner of the list the lines are from.
+	scanner *RuleScanner
+
+	// done is closed when the rules have been parsed.
+	done chan struct{}
+
+	// lines are the lines to parse.
+	lines []string
+
+	// indexes are the indexes of the lines within the list.
+	indexes []int
+
+	// rules are the rules parsed from lines.  The elements for the invalid,
+	// ignored, or filtered out lines are nil.
+	rules []rules.Rule
+}
+
+// Scan parses all rules in the storage and passes them to conf.Handler.  Unlike
+// [RuleStorageScanner], it can parse the rules on several goroutines, while
+// still handling them in the deterministic order.  conf must not be nil.
+func (s *RuleStorage) Scan(conf *ScanConfig) {
+	if conf.Workers < 2 {
+		s.scanSequential(conf)
+
+		return
+	}
+
+	jobs := make(chan *scanChunk, conf.Workers)
+	ordered := make(chan *scanChunk, 2*conf.Workers)
+
+	go s.readChunks(jobs, ordered)
+
+	for range conf.Workers {
+		go parseChunks(jobs, conf)
+	}
+
+	for c := range ordered {
+		<-c.done
+
+		for i, r := range c.rules {
+			if r != nil {
+				conf.Handler(r, ruleListIdxToStorageIdx(int32(c.scanner.listID), int32(c.indexes[i])))
+			}
+		}
+	}
+}
+
+// scanSequential implements [RuleStorage.Scan] using a single goroutine.
+func (s *RuleStorage) scanSequential(conf *ScanConfig) {
+	for _, list := range s.lists {
+		sc := list.NewScanner()
+		for {
+			line, idx, err := sc.readNextLine()
+			if err != nil {
+				break
+			}
+
+			r := conf.parse(sc, line)
+			if r != nil {
+				conf.Handler(r, ruleListIdxToStorageIdx(int32(sc.listID), int32(idx)))
+			}
+		}
+	}
+}
+
+// parse returns the rule parsed from line by sc, if it passes both filters of
+// conf, and nil otherwise.
+func (conf *ScanConfig) parse(sc *RuleScanner, line string) (r rules.Rule) {
+	if conf.LineFilter != nil && !conf.LineFilter(line) {
+		return nil
+	}
+
+	r = sc.parseLine(line)
+	if r == nil || (conf.Filter != nil && !conf.Filter(r)) {
+		return nil
+	}
+
+	return r
+}
+
+// readChunks reads the lines of all lists in the storage, splits them into
+// chunks, and sends each chunk to both jobs and ordered.  It closes both
+// channels when all lists are read.
+func (s *RuleStorage) readChunks(jobs, ordered chan<- *scanChunk) {
+	defer close(ordered)
+	defer close(jobs)
+
+	for _, list := range s.lists {
+		readListChunks(list.NewScanner(), jobs, ordered)
+	}
+}
+
+// readListChunks reads the lines of a single list using sc and sends the chunks
+// of them to both jobs and ordered.
+func readListChunks(sc *RuleScanner, jobs, ordered chan<- *scanChunk) {
+	c := newScanChunk(sc)
+	for {
+		line, idx, err := sc.readNextLine()
+		if err != nil {
+			break
+		}
+
+		c.lines = append(c.lines, line)
+		c.indexes = append(c.indexes, idx)
+		if len(c.lines) == scanChunkSize {
+			jobs <- c
+			ordered <- c
+			c = newScanChunk(sc)
+		}
+	}
+
+	if len(c.lines) > 0 {
+		jobs <- c
+		ordered <- c
+	}
+}
+
+// newScanChunk returns a new empty chunk for the list read by sc.
+func newScanChunk(sc *RuleScanner) (c *scanChunk) {
+	return &scanChunk{
+		scanner: sc,
+		done:    make(chan struct{}),
+		lines:   make([]string, 0, scanChunkSize),
+		indexes: make([]int, 0, scanChunkSize),
+	}
+}
+
+// parseChunks parses the chunks received from jobs until it's closed.
+func parseChunks(jobs <-chan *scanChunk, conf *ScanConfig) {
+	for c := range jobs {
+		c.rules = make([]rules.Rule, len(c.lines))
+		for i, line := range c.lines {
+			c.rules[i] = conf.parse(c.scanner, line)
+		}
+
+		close(c.done)
+	}
+}
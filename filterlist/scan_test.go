@@ -0,0 +1,135 @@
+package filterlist_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/filterlist"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scannedRule is a rule returned by a scanner along with its storage index.
+type scannedRule struct {
+	text       string
+	storageIdx int64
+}
+
+// newScanTestStorage returns a storage with the hosts file between two small
+// lists so that the rules are split into several chunks.
+func newScanTestStorage(tb testing.TB) (s *filterlist.RuleStorage) {
+	tb.Helper()
+
+	hosts, err := filterlist.NewFile(&filterlist.FileConfig{
+		Path: hostsPath,
+		ID:   testListIDOther + 1,
+	})
+	require.NoError(tb, err)
+
+	s, err = filterlist.NewRuleStorage([]filterlist.Interface{
+		filterlist.NewString(&filterlist.StringConfig{
+			RulesText: testRuleText,
+			ID:        testListID,
+		}),
+		hosts,
+		filterlist.NewString(&filterlist.StringConfig{
+			RulesText:      testRuleTextOther,
+			ID:             testListIDOther,
+			IgnoreCosmetic: true,
+		}),
+	})
+	require.NoError(tb, err)
+	testutil.CleanupAndRequireSuccess(tb, s.Close)
+
+	return s
+}
+
+func TestRuleStorage_Scan(t *testing.T) {
+	t.Parallel()
+
+	s := newScanTestStorage(t)
+
+	isNetworkRule := func(r rules.Rule) (ok bool) {
+		_, ok = r.(*rules.NetworkRule)
+
+		return ok
+	}
+
+	var want, wantNetwork []scannedRule
+	sc := s.NewRuleStorageScanner()
+	for sc.Scan() {
+		r, idx := sc.Rule()
+		sr := scannedRule{text: r.Text(), storageIdx: idx}
+		want = append(want, sr)
+		if isNetworkRule(r) {
+			wantNetwork = append(wantNetwork, sr)
+		}
+	}
+
+	// Make sure that the storage is large enough to be split into chunks.
+	require.Greater(t, len(want), hostsRulesCount)
+	require.NotEmpty(t, wantNetwork)
+
+	// Don't run the subtests in parallel, since the scanners of a file list
+	// share the file.
+	for _, workers := range []int{0, 1, 2, 8} {
+		t.Run(fmt.Sprintf("workers_%d", workers), func(t *testing.T) {
+			var got []scannedRule
+			s.Scan(&filterlist.ScanConfig{
+				Handler: func(r rules.Rule, storageIdx int64) {
+					got = append(got, scannedRule{text: r.Text(), storageIdx: storageIdx})
+				},
+				Workers: workers,
+			})
+
+			assert.Equal(t, want, got)
+		})
+
+		t.Run(fmt.Sprintf("workers_%d_filter", workers), func(t *testing.T) {
+			var got []scannedRule
+			s.Scan(&filterlist.ScanConfig{
+				Handler: func(r rules.Rule, storageIdx int64) {
+					got = append(got, scannedRule{text: r.Text(), storageIdx: storageIdx})
+				},
+				Filter:  isNetworkRule,
+				Workers: workers,
+			})
+
+			assert.Equal(t, wantNetwork, got)
+		})
+	}
+}
+
+func BenchmarkRuleStorage_Scan(b *testing.B) {
+	s := newScanTestStorage(b)
+
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
+			var n int
+			conf := &filterlist.ScanConfig{
+				Handler: func(_ rules.Rule, _ int64) {
+					n++
+				},
+				Workers: workers,
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				n = 0
+				s.Scan(conf)
+			}
+
+			require.Greater(b, n, hostsRulesCount)
+		})
+	}
+
+	// Most recent results:
+	//	goos: linux
+	//	goarch: amd64
+	//	pkg: github.com/AdguardTeam/urlfilter/filterlist
+	//	cpu: Intel(R) Xeon(R) Processor
+	//	BenchmarkRuleStorage_Scan/workers_1         	      45	  31800486 ns/op	 9286667 B/op	  228138 allocs/op
+	//	BenchmarkRuleStorage_Scan/workers_4         	      60	  21528609 ns/op	11915845 B/op	  228438 allocs/op
+}
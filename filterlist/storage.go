@@ -161,5 +161,8 @@ func (s *RuleStorage) Close() (err error) {
 
 // GetCacheSize returns the size of the in-memory rules cache.
 func (s *RuleStorage) GetCacheSize() (sz int) {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+
 	return len(s.cache)
 }
@@ -146,3 +146,21 @@ func BenchmarkStorage_RetrieveRule(b *testing.B) {
 	//	cpu: AMD Ryzen 7 PRO 4750U with Radeon Graphics
 	//	BenchmarkStorage_RetrieveRule-16       	44905778	        26.14 ns/op	       0 B/op	       0 allocs/op
 }
+
+func TestRuleStorage_RetrieveRule_cache(t *testing.T) {
+	t.Parallel()
+
+	list := filterlist.NewString(&filterlist.StringConfig{
+		RulesText: testRuleText,
+		ID:        testListID,
+	})
+
+	s, err := filterlist.NewRuleStorage([]filterlist.Interface{list})
+	require.NoError(t, err)
+
+	r := s.RetrieveNetworkRule(testStrgID1Rule1)
+	require.NotNil(t, r)
+
+	assert.Same(t, r, s.RetrieveNetworkRule(testStrgID1Rule1))
+	assert.Equal(t, 1, s.GetCacheSize())
+}
@@ -16,6 +16,11 @@ type StringConfig struct {
 
 	// IgnoreCosmetic tells whether to ignore cosmetic rules or not.
 	IgnoreCosmetic bool
+
+	// Trusted tells whether the list is trusted to contain the JS rules with
+	// the arbitrary JavaScript code.  Such rules of the untrusted lists are
+	// ignored, while the scriptlets are allowed in any list.
+	Trusted bool
 }
 
 // String is an [Interface] implementation which stores rules within a string.
@@ -23,6 +28,7 @@ type String struct {
 	rulesText      string
 	id             int
 	ignoreCosmetic bool
+	trusted        bool
 }
 
 // NewString creates a new string-based rule list with the given configuration.
@@ -31,6 +37,7 @@ func NewString(conf *StringConfig) (s *String) {
 		rulesText:      conf.RulesText,
 		id:             conf.ID,
 		ignoreCosmetic: conf.IgnoreCosmetic,
+		trusted:        conf.Trusted,
 	}
 }
 
@@ -44,7 +51,10 @@ func (s *String) GetID() (id int) {
 
 // NewScanner implements the [Interface] interface for *String.
 func (s *String) NewScanner() (sc *RuleScanner) {
-	return NewRuleScanner(strings.NewReader(s.rulesText), s.id, s.ignoreCosmetic)
+	sc = NewRuleScanner(strings.NewReader(s.rulesText), s.id, s.ignoreCosmetic)
+	sc.trusted = s.trusted
+
+	return sc
 }
 
 // RetrieveRule implements the [Interface] interface for *String.
@@ -0,0 +1,79 @@
+// Package bloom implements a Bloom filter, a probabilistic set that may report
+// false positives but never reports false negatives.
+package bloom
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+)
+
+// Filter is a Bloom filter of strings.  It must not be modified concurrently
+// with reading.
+type Filter struct {
+	// seed is the seed of the hash function.
+	seed maphash.Seed
+
+	// bits is the bit array of the filter.
+	bits []uint64
+
+	// size is the number of bits in the filter.  It is never zero.
+	size uint64
+
+	// hashes is the number of bits set for each element.
+	hashes uint64
+}
+
+// New returns a new filter sized for n elements with the false positive rate of
+// fpRate, which must be within (0, 1).  n is rounded up to one.
+func New(n int, fpRate float64) (f *Filter) {
+	elems := float64(max(n, 1))
+
+	// See https://en.wikipedia.org/wiki/Bloom_filter#Optimal_number_of_hash_functions.
+	size := math.Ceil(-elems * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	hashes := max(math.Round(size/elems*math.Ln2), 1)
+
+	// Use whole words to make very small filters more precise.
+	words := max((uint64(size)+63)/64, 1)
+
+	return &Filter{
+		seed:   maphash.MakeSeed(),
+		bits:   make([]uint64, words),
+		size:   words * 64,
+		hashes: uint64(hashes),
+	}
+}
+
+// Add adds s to the filter.
+func (f *Filter) Add(s string) {
+	h1, h2 := f.hash(s)
+	for i := range f.hashes {
+		bit := (h1 + i*h2) % f.size
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// MayContain returns false if s has definitely not been added to the filter.
+// Otherwise, s has probably been added.
+func (f *Filter) MayContain(s string) (ok bool) {
+	h1, h2 := f.hash(s)
+	for i := range f.hashes {
+		bit := (h1 + i*h2) % f.size
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hash returns the two hashes of s used to derive the bit positions using the
+// double hashing technique.
+func (f *Filter) hash(s string) (h1, h2 uint64) {
+	h1 = maphash.String(f.seed, s)
+
+	// Make sure that the second hash is odd so that it's never zero.
+	h2 = bits.RotateLeft64(h1, 32) | 1
+
+	return h1, h2
+}
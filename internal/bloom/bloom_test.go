@@ -0,0 +1,90 @@
+package bloom_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/internal/bloom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		n      int
+		fpRate float64
+	}{{
+		name:   "empty",
+		n:      0,
+		fpRate: 0.01,
+	}, {
+		name:   "one",
+		n:      1,
+		fpRate: 0.01,
+	}, {
+		name:   "small",
+		n:      1_000,
+		fpRate: 0.01,
+	}, {
+		name:   "large_precise",
+		n:      100_000,
+		fpRate: 0.001,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := bloom.New(tc.n, tc.fpRate)
+			for i := range tc.n {
+				f.Add(strconv.Itoa(i) + ".added.example")
+			}
+
+			for i := range tc.n {
+				require.True(t, f.MayContain(strconv.Itoa(i)+".added.example"))
+			}
+
+			const checks = 100_000
+
+			var fps int
+			for i := range checks {
+				if f.MayContain(strconv.Itoa(i) + ".other.example") {
+					fps++
+				}
+			}
+
+			// Allow some deviation from the expected rate.
+			assert.LessOrEqual(t, float64(fps)/checks, tc.fpRate*2)
+		})
+	}
+}
+
+func BenchmarkFilter_MayContain(b *testing.B) {
+	const n = 100_000
+
+	f := bloom.New(n, 0.01)
+	for i := range n {
+		f.Add(strconv.Itoa(i) + ".added.example")
+	}
+
+	const hostname = "www.not-added.example"
+
+	var ok bool
+
+	b.ReportAllocs()
+	for b.Loop() {
+		ok = f.MayContain(hostname)
+	}
+
+	assert.False(b, ok)
+
+	// Most recent results:
+	//	goos: linux
+	//	goarch: amd64
+	//	pkg: github.com/AdguardTeam/urlfilter/internal/bloom
+	//	cpu: Intel(R) Xeon(R) Processor
+	//	BenchmarkFilter_MayContain    	122096232	        10.13 ns/op	       0 B/op	       0 allocs/op
+}
@@ -0,0 +1,320 @@
+package lookup
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/AdguardTeam/golibs/syncutil"
+	"github.com/AdguardTeam/urlfilter/rules"
+)
+
+// acMinShortcutLength is the minimum length of a rule shortcut eligible for
+// [AhoCorasickTable].
+const acMinShortcutLength = 2
+
+// acRoot is the identifier of the root node of the automaton.
+const acRoot acNodeID = 0
+
+// acNodeID is the identifier of an automaton node, which is its index within
+// the nodes slice.
+type acNodeID = int32
+
+// acNode is a single node of the automaton.
+type acNode struct {
+	// fail is the node of the longest proper suffix of this node's string,
+	// which is also a prefix of some shortcut.
+	fail acNodeID
+
+	// dict is the closest node along the fail links which has rules.  It is
+	// [acRoot] if there is no such node.
+	dict acNodeID
+
+	// out is the index of the rule indexes of this node within outputs plus
+	// one.  It is zero if there are no rules ending at this node.
+	out int32
+}
+
+// acEdge is the key of a transition, which is the identifier of the source
+// node in the high bits and the byte in the lowest eight.
+type acEdge uint64
+
+// newACEdge returns the key of the transition from node by b.
+func newACEdge(node acNodeID, b byte) (e acEdge) {
+	return acEdge(node)<<8 | acEdge(b)
+}
+
+// AhoCorasickTable is a [Table] that finds all rule shortcuts within the
+// request URL in a single pass using the Aho-Corasick automaton.  It indexes
+// the whole shortcuts of any length starting from [acMinShortcutLength].
+//
+// The transitions are added on [AhoCorasickTable.Add], and the fail links are
+// built lazily on the first match after that.
+type AhoCorasickTable struct {
+	// ruleStorage is the storage of the network filtering rules.
+	ruleStorage RuleStorage
+
+	// visitedPool contains sets of the nodes already reported during a match.
+	visitedPool *syncutil.Pool[map[acNodeID]struct{}]
+
+	// mu protects all fields below.
+	mu *sync.RWMutex
+
+	// edges are the transitions between the non-root nodes.
+	edges map[acEdge]acNodeID
+
+	// nodes are all nodes of the automaton, including the root one.
+	nodes []acNode
+
+	// outputs are the storage indexes of the rules ending at the nodes.
+	outputs [][]int64
+
+	// root are the transitions from the root node.  Zero means that there is
+	// no transition.
+	root [256]acNodeID
+
+	// dirty is true if the fail links must be rebuilt.
+	dirty bool
+}
+
+// NewAhoCorasickTable creates a new instance of *AhoCorasickTable.
+func NewAhoCorasickTable(rs RuleStorage) (t *AhoCorasickTable) {
+	return &AhoCorasickTable{
+		ruleStorage: rs,
+		visitedPool: syncutil.NewPool(func() (v *map[acNodeID]struct{}) {
+			m := map[acNodeID]struct{}{}
+
+			return &m
+		}),
+		mu:    &sync.RWMutex{},
+		edges: map[acEdge]acNodeID{},
+		nodes: []acNode{{}},
+	}
+}
+
+// type check
+var _ Table = (*AhoCorasickTable)(nil)
+
+// Add implements the [Table] interface for *AhoCorasickTable.
+func (t *AhoCorasickTable) Add(f *rules.NetworkRule, storageIdx int64) (ok bool) {
+	if len(f.Shortcut) < acMinShortcutLength || isAnyURLShortcut(f) {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	node := acRoot
+	for i := range len(f.Shortcut) {
+		node = t.addTransition(node, f.Shortcut[i])
+	}
+
+	n := &t.nodes[node]
+	if n.out == 0 {
+		t.outputs = append(t.outputs, nil)
+		n.out = int32(len(t.outputs))
+	}
+
+	t.outputs[n.out-1] = append(t.outputs[n.out-1], storageIdx)
+	t.dirty = true
+
+	return true
+}
+
+// isAnyURLShortcut checks if the rule potentially matches too many URLs.  It is
+// better use another type of lookup table for these kinds of rules.
+//
+// TODO(a.garipov):  Inspect and optimize.
+func isAnyURLShortcut(r *rules.NetworkRule) bool {
+	switch scLen := len(r.Shortcut); {
+	case
+		scLen < len("ws://")+1 && strings.HasPrefix(r.Shortcut, "ws:"),
+		scLen < len("wss://")+1 && strings.HasPrefix(r.Shortcut, "wss:"),
+		scLen < len("|wss://")+1 && strings.HasPrefix(r.Shortcut, "|ws"),
+		scLen < len("https://")+1 && strings.HasPrefix(r.Shortcut, "http"),
+		scLen < len("|https://")+1 && strings.HasPrefix(r.Shortcut, "|http"):
+		return true
+	default:
+		return false
+	}
+}
+
+// addTransition returns the node reachable from node by b, adding it if
+// necessary.  t.mu must be locked.
+func (t *AhoCorasickTable) addTransition(node acNodeID, b byte) (next acNodeID) {
+	next, ok := t.transition(node, b)
+	if ok {
+		return next
+	}
+
+	next = acNodeID(len(t.nodes))
+	t.nodes = append(t.nodes, acNode{})
+	if node == acRoot {
+		t.root[b] = next
+	} else {
+		t.edges[newACEdge(node, b)] = next
+	}
+
+	return next
+}
+
+// transition returns the node reachable from node by b, if any.  t.mu must be
+// locked for reading.
+func (t *AhoCorasickTable) transition(node acNodeID, b byte) (next acNodeID, ok bool) {
+	if node == acRoot {
+		next = t.root[b]
+
+		return next, next != acRoot
+	}
+
+	next, ok = t.edges[newACEdge(node, b)]
+
+	return next, ok
+}
+
+// acChild is a transition used while building the fail links.
+type acChild struct {
+	node acNodeID
+	b    byte
+}
+
+// build computes the fail and dictionary links of all nodes in the
+// breadth-first order.  t.mu must be locked.
+func (t *AhoCorasickTable) build() {
+	children := make([][]acChild, len(t.nodes))
+	for e, child := range t.edges {
+		parent := acNodeID(e >> 8)
+		children[parent] = append(children[parent], acChild{node: child, b: byte(e)})
+	}
+
+	queue := make([]acNodeID, 0, len(t.nodes))
+	for _, child := range t.root {
+		if child != acRoot {
+			t.nodes[child].fail, t.nodes[child].dict = acRoot, acRoot
+			queue = append(queue, child)
+		}
+	}
+
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+
+		for _, c := range children[parent] {
+			t.link(c.node, t.step(t.nodes[parent].fail, c.b))
+			queue = append(queue, c.node)
+		}
+	}
+
+	t.dirty = false
+}
+
+// link sets the failure link of the node to fail and its dictionary link to
+// the nearest node with outputs along the failure links.
+func (t *AhoCorasickTable) link(node, fail acNodeID) {
+	n := &t.nodes[node]
+	n.fail = fail
+	if t.nodes[fail].out != 0 {
+		n.dict = fail
+	} else {
+		n.dict = t.nodes[fail].dict
+	}
+}
+
+// step returns the node the automaton moves to from node by b, following the
+// fail links if necessary.  t.mu must be locked for reading.
+func (t *AhoCorasickTable) step(node acNodeID, b byte) (res acNodeID) {
+	for {
+		if next, ok := t.transition(node, b); ok {
+			return next
+		} else if node == acRoot {
+			return acRoot
+		}
+
+		node = t.nodes[node].fail
+	}
+}
+
+// AppendMatching implements the [Table] interface for *AhoCorasickTable.
+func (t *AhoCorasickTable) AppendMatching(
+	matching []*rules.NetworkRule,
+	r *rules.Request,
+) (res []*rules.NetworkRule) {
+	t.rlockBuilt()
+	defer t.mu.RUnlock()
+
+	visitedPtr := t.visitedPool.Get()
+	defer func() {
+		clear(*visitedPtr)
+		t.visitedPool.Put(visitedPtr)
+	}()
+
+	res = matching
+	node := acRoot
+	url := r.URLLowerCase
+	for i := range len(url) {
+		node = t.step(node, url[i])
+		res = t.appendOutputs(res, node, *visitedPtr, r)
+	}
+
+	return res
+}
+
+// rlockBuilt locks t.mu for reading, building the fail links first if
+// necessary.
+func (t *AhoCorasickTable) rlockBuilt() {
+	t.mu.RLock()
+	if !t.dirty {
+		return
+	}
+
+	t.mu.RUnlock()
+	t.mu.Lock()
+	if t.dirty {
+		t.build()
+	}
+	t.mu.Unlock()
+
+	t.mu.RLock()
+}
+
+// appendOutputs appends the rules matching r from node and the nodes along its
+// dictionary links to res.  visited are the nodes already reported, it is
+// updated with the new ones.  t.mu must be locked for reading.
+func (t *AhoCorasickTable) appendOutputs(
+	res []*rules.NetworkRule,
+	node acNodeID,
+	visited map[acNodeID]struct{},
+	r *rules.Request,
+) (matching []*rules.NetworkRule) {
+	if t.nodes[node].out == 0 {
+		node = t.nodes[node].dict
+	}
+
+	for ; node != acRoot; node = t.nodes[node].dict {
+		if _, ok := visited[node]; ok {
+			// The rest of the dictionary chain has already been reported as
+			// well.
+			break
+		}
+
+		visited[node] = struct{}{}
+		res = t.appendMatchingRules(res, t.outputs[t.nodes[node].out-1], r)
+	}
+
+	return res
+}
+
+// appendMatchingRules appends the rules from indexes matching r to res.
+func (t *AhoCorasickTable) appendMatchingRules(
+	res []*rules.NetworkRule,
+	indexes []int64,
+	r *rules.Request,
+) (matching []*rules.NetworkRule) {
+	for _, idx := range indexes {
+		rule := t.ruleStorage.RetrieveNetworkRule(idx)
+		if rule != nil && rule.Match(r) {
+			res = append(res, rule)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,158 @@
+package lookup_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/internal/lookup"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAhoCorasickTable_Add(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		want assert.BoolAssertionFunc
+		name string
+		text string
+	}{{
+		want: assert.True,
+		name: "short_shortcut",
+		text: testRuleTextNoShortcutsTiny,
+	}, {
+		want: assert.False,
+		name: "no_shortcuts_url",
+		text: testRuleTextNoShortcutsURL,
+	}, {
+		want: assert.False,
+		name: "no_shortcuts",
+		text: "$domain=" + testDomain + "\n",
+	}, {
+		want: assert.True,
+		name: "success",
+		text: testRuleText,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newStorage(t, tc.text)
+			tbl := lookup.NewAhoCorasickTable(s)
+			assertRuleIsAdded(t, tbl, s, tc.want)
+		})
+	}
+}
+
+func TestAhoCorasickTable_AppendMatching(t *testing.T) {
+	t.Parallel()
+
+	s := newStorage(t, testRuleTextAll)
+	tbl := lookup.NewAhoCorasickTable(s)
+	loadTable(t, tbl, s)
+
+	testCases := []struct {
+		name         string
+		urlStr       string
+		wantRuleText string
+	}{{
+		name:         "no_match",
+		urlStr:       testURLStrNoMatch,
+		wantRuleText: "",
+	}, {
+		name:         "match",
+		urlStr:       testURLStrWithDomain,
+		wantRuleText: testRule,
+	}, {
+		name:         "short_shortcut",
+		urlStr:       "https://tiny/",
+		wantRuleText: testRuleNoShortcutsTiny,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := rules.NewRequest(tc.urlStr, tc.urlStr, rules.TypeOther)
+			assertMatch(t, tbl, r, tc.wantRuleText)
+		})
+	}
+}
+
+func TestAhoCorasickTable_AppendMatching_overlapping(t *testing.T) {
+	t.Parallel()
+
+	const (
+		ruleAds     = "/ads/"
+		ruleBanner  = "ads/banner"
+		ruleNoMatch = "/ads/video"
+		ruleSuffix  = "nner.js|"
+		ruleLate    = "x/ads"
+	)
+
+	s := newStorage(t, ruleAds+"\n"+ruleBanner+"\n"+ruleNoMatch+"\n"+ruleSuffix+"\n"+ruleLate+"\n")
+	tbl := lookup.NewAhoCorasickTable(s)
+
+	var late *rules.NetworkRule
+	var lateIdx int64
+	sc := s.NewRuleStorageScanner()
+	for sc.Scan() {
+		r, idx := sc.Rule()
+		nr := testutil.RequireTypeAssert[*rules.NetworkRule](t, r)
+		if nr.Text() == ruleLate {
+			late, lateIdx = nr, idx
+
+			continue
+		}
+
+		require.True(t, tbl.Add(nr, idx))
+	}
+
+	require.NotNil(t, late)
+
+	// The URL contains "/ads/" twice to check that rules are reported only
+	// once.
+	const urlStr = "https://example.org/ads/x/ads/banner.js"
+	r := rules.NewRequest(urlStr, "", rules.TypeScript)
+
+	assert.ElementsMatch(
+		t,
+		[]string{ruleAds, ruleBanner, ruleSuffix},
+		ruleTexts(tbl.AppendMatching(nil, r)),
+	)
+
+	// Check that the automaton is rebuilt after adding a rule.
+	require.True(t, tbl.Add(late, lateIdx))
+
+	assert.ElementsMatch(
+		t,
+		[]string{ruleAds, ruleBanner, ruleSuffix, ruleLate},
+		ruleTexts(tbl.AppendMatching(nil, r)),
+	)
+}
+
+func BenchmarkAhoCorasickTable_AppendMatching(b *testing.B) {
+	s := newStorage(b, testRuleTextAll)
+	tbl := lookup.NewAhoCorasickTable(s)
+	loadTable(b, tbl, s)
+
+	r := rules.NewRequest(testURLStrWithDomain, testURLStrWithDomain, rules.TypeOther)
+
+	gotRules := make([]*rules.NetworkRule, 0, 1)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		gotRules = tbl.AppendMatching(gotRules[:0], r)
+	}
+
+	require.Len(b, gotRules, 1)
+
+	// Most recent results:
+	//	goos: linux
+	//	goarch: amd64
+	//	pkg: github.com/AdguardTeam/urlfilter/internal/lookup
+	//	cpu: Intel(R) Xeon(R) Processor
+	//	BenchmarkAhoCorasickTable_AppendMatching    	  923601	      1291 ns/op	       0 B/op	       0 allocs/op
+}
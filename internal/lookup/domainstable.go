@@ -2,9 +2,9 @@ package lookup
 
 import (
 	"strings"
+	"unique"
 
 	"github.com/AdguardTeam/golibs/syncutil"
-	"github.com/AdguardTeam/urlfilter/filterlist"
 	"github.com/AdguardTeam/urlfilter/rules"
 )
 
@@ -13,7 +13,7 @@ import (
 // for this lookup table.
 type DomainsTable struct {
 	// Storage for the network filtering rules.
-	ruleStorage *filterlist.RuleStorage
+	ruleStorage RuleStorage
 
 	// subdomainsPool contains slices of strings to fill with subdomains.
 	subdomainsPool *syncutil.Pool[[]string]
@@ -26,7 +26,7 @@ type DomainsTable struct {
 const subdomainsEst = 4
 
 // NewDomainsTable creates a new instance of the DomainsTable.
-func NewDomainsTable(rs *filterlist.RuleStorage) (s *DomainsTable) {
+func NewDomainsTable(rs RuleStorage) (s *DomainsTable) {
 	return &DomainsTable{
 		ruleStorage:    rs,
 		subdomainsPool: syncutil.NewSlicePool[string](subdomainsEst),
@@ -45,9 +45,14 @@ func (d *DomainsTable) Add(f *rules.NetworkRule, storageIdx int64) (ok bool) {
 	}
 
 	for _, domain := range permittedDomains {
-		rulesIndexes := d.domainsIndex[domain]
-		rulesIndexes = append(rulesIndexes, storageIdx)
-		d.domainsIndex[domain] = rulesIndexes
+		rulesIndexes, has := d.domainsIndex[domain]
+		if !has {
+			// Intern the domain so that the index doesn't retain the rule
+			// text and the same domains from different rules share memory.
+			domain = unique.Make(domain).Value()
+		}
+
+		d.domainsIndex[domain] = append(rulesIndexes, storageIdx)
 	}
 
 	return true
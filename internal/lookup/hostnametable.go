@@ -0,0 +1,114 @@
+package lookup
+
+import (
+	"strings"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+)
+
+// HostnameTable is a [Table] that indexes the rules with the "||hostname^" and
+// "|hostname^" patterns in a trie of reversed hostname labels.  Only the rules
+// for which [rules.NetworkRule.HostnamePattern] returns true are eligible for
+// this lookup table.
+type HostnameTable struct {
+	// ruleStorage is the storage of the network filtering rules.
+	ruleStorage RuleStorage
+
+	// root is the root of the trie, which corresponds to the empty hostname.
+	root *labelNode
+}
+
+// labelNode is a node of the reversed hostname labels trie.
+type labelNode struct {
+	// children are the nodes of the subdomains by their leftmost labels.
+	children map[string]*labelNode
+
+	// rules are the storage indexes of the rules for the hostname of this node.
+	rules []int64
+}
+
+// NewHostnameTable creates a new instance of *HostnameTable.
+func NewHostnameTable(rs RuleStorage) (t *HostnameTable) {
+	return &HostnameTable{
+		ruleStorage: rs,
+		root:        &labelNode{},
+	}
+}
+
+// type check
+var _ Table = (*HostnameTable)(nil)
+
+// Add implements the [Table] interface for *HostnameTable.
+func (t *HostnameTable) Add(f *rules.NetworkRule, storageIdx int64) (ok bool) {
+	hostname, _, ok := f.HostnamePattern()
+	if !ok {
+		return false
+	}
+
+	n := t.root
+	for hostname != "" {
+		var label string
+		hostname, label = cutLastLabel(hostname)
+
+		child, has := n.children[label]
+		if !has {
+			if n.children == nil {
+				n.children = map[string]*labelNode{}
+			}
+
+			// Clone the label so that the trie doesn't retain the rule text.
+			child = &labelNode{}
+			n.children[strings.Clone(label)] = child
+		}
+
+		n = child
+	}
+
+	n.rules = append(n.rules, storageIdx)
+
+	return true
+}
+
+// AppendMatching implements the [Table] interface for *HostnameTable.
+func (t *HostnameTable) AppendMatching(
+	matching []*rules.NetworkRule,
+	r *rules.Request,
+) (res []*rules.NetworkRule) {
+	res = matching
+
+	// strings.ToLower doesn't allocate if the hostname is already lowercased,
+	// which is the most common case.
+	hostname := strings.ToLower(r.Hostname)
+
+	n := t.root
+	for hostname != "" {
+		var label string
+		hostname, label = cutLastLabel(hostname)
+
+		var ok bool
+		n, ok = n.children[label]
+		if !ok {
+			break
+		}
+
+		for _, idx := range n.rules {
+			rule := t.ruleStorage.RetrieveNetworkRule(idx)
+			if rule != nil && rule.Match(r) {
+				res = append(res, rule)
+			}
+		}
+	}
+
+	return res
+}
+
+// cutLastLabel returns the rightmost label of hostname and the rest of it
+// without the separating dot.
+func cutLastLabel(hostname string) (rest, label string) {
+	i := strings.LastIndexByte(hostname, '.')
+	if i < 0 {
+		return "", hostname
+	}
+
+	return hostname[:i], hostname[i+1:]
+}
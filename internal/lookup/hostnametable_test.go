@@ -0,0 +1,138 @@
+package lookup_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/internal/lookup"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostnameTable_Add(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		want assert.BoolAssertionFunc
+		name string
+		text string
+	}{{
+		want: assert.True,
+		name: "hostname",
+		text: testRuleText,
+	}, {
+		want: assert.True,
+		name: "single_label",
+		text: testRuleTextNoShortcutsTiny,
+	}, {
+		want: assert.False,
+		name: "url",
+		text: testRuleTextNoShortcutsURL,
+	}, {
+		want: assert.False,
+		name: "path",
+		text: "||" + testDomain + "/path\n",
+	}, {
+		want: assert.False,
+		name: "no_separator",
+		text: "||" + testDomain + "\n",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newStorage(t, tc.text)
+			tbl := lookup.NewHostnameTable(s)
+			assertRuleIsAdded(t, tbl, s, tc.want)
+		})
+	}
+}
+
+func TestHostnameTable_AppendMatching(t *testing.T) {
+	t.Parallel()
+
+	s := newStorage(t, testRuleTextAll)
+	tbl := lookup.NewHostnameTable(s)
+	loadTable(t, tbl, s)
+
+	testCases := []struct {
+		name          string
+		urlStr        string
+		srcURLStr     string
+		wantRuleTexts []string
+	}{{
+		name:          "no_match",
+		urlStr:        testURLStrNoMatch,
+		srcURLStr:     "",
+		wantRuleTexts: nil,
+	}, {
+		name:          "match",
+		urlStr:        testURLStrWithDomain,
+		srcURLStr:     "",
+		wantRuleTexts: []string{testRule},
+	}, {
+		name:          "match_uppercase",
+		urlStr:        "https://DOMAIN.example/",
+		srcURLStr:     "",
+		wantRuleTexts: []string{testRule},
+	}, {
+		name:          "match_parent",
+		urlStr:        testURLStrNoDomain,
+		srcURLStr:     "",
+		wantRuleTexts: []string{testRule, testRuleNoDomain},
+	}, {
+		name:          "single_label",
+		urlStr:        "https://tiny/",
+		srcURLStr:     "",
+		wantRuleTexts: []string{testRuleNoShortcutsTiny},
+	}, {
+		name:          "not_subdomain",
+		urlStr:        "https://notdomain.example/",
+		srcURLStr:     "",
+		wantRuleTexts: nil,
+	}, {
+		name:          "modifier_no_match",
+		urlStr:        testURLStrWithSubdomain,
+		srcURLStr:     testURLStrNoMatch,
+		wantRuleTexts: []string{testRule},
+	}, {
+		name:          "modifier_match",
+		urlStr:        testURLStrWithSubdomain,
+		srcURLStr:     testURLStrWithDomain,
+		wantRuleTexts: []string{testRule, testRuleWithDomain},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := rules.NewRequest(tc.urlStr, tc.srcURLStr, rules.TypeOther)
+			assert.ElementsMatch(t, tc.wantRuleTexts, ruleTexts(tbl.AppendMatching(nil, r)))
+		})
+	}
+}
+
+func BenchmarkHostnameTable_AppendMatching(b *testing.B) {
+	s := newStorage(b, testRuleTextAll)
+	tbl := lookup.NewHostnameTable(s)
+	loadTable(b, tbl, s)
+
+	r := rules.NewRequest(testURLStrWithDomain, testURLStrWithDomain, rules.TypeOther)
+
+	gotRules := make([]*rules.NetworkRule, 0, 1)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		gotRules = tbl.AppendMatching(gotRules[:0], r)
+	}
+
+	require.Len(b, gotRules, 1)
+
+	// Most recent results:
+	//	goos: linux
+	//	goarch: amd64
+	//	pkg: github.com/AdguardTeam/urlfilter/internal/lookup
+	//	cpu: Intel(R) Xeon(R) Processor
+	//	BenchmarkHostnameTable_AppendMatching    	 1000000	      1039 ns/op	       0 B/op	       0 allocs/op
+}
@@ -14,3 +14,11 @@ type Table interface {
 	// appends them to matching.
 	AppendMatching(matching []*rules.NetworkRule, r *rules.Request) (res []*rules.NetworkRule)
 }
+
+// RuleStorage is the interface for the storages of the network rules added to
+// the lookup tables.
+type RuleStorage interface {
+	// RetrieveNetworkRule returns the network rule by its storage index or nil
+	// if there is no such rule.
+	RetrieveNetworkRule(storageIdx int64) (nr *rules.NetworkRule)
+}
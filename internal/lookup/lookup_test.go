@@ -137,3 +137,12 @@ func loadTable(tb testing.TB, tbl lookup.Table, s *filterlist.RuleStorage) {
 		}
 	}
 }
+
+// ruleTexts is a helper that returns the texts of rs.
+func ruleTexts(rs []*rules.NetworkRule) (texts []string) {
+	for _, r := range rs {
+		texts = append(texts, r.Text())
+	}
+
+	return texts
+}
@@ -0,0 +1,191 @@
+package lookup
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+)
+
+// regexBatchSize is the maximum number of rules combined into a single regular
+// expression.  Larger batches make the combined expressions slower to compile
+// and less selective.
+const regexBatchSize = 128
+
+// RegexTable is a [Table] that combines the regular expressions of the regex
+// rules into batches.  A single match of the combined expression of a batch
+// against the request selects the candidate rules of that batch, which are
+// then confirmed with [rules.NetworkRule.Match].  Only the regex rules are
+// eligible for this lookup table.  Note that the regex rules with shortcuts
+// should be indexed by [AhoCorasickTable] instead, if it's used.
+//
+// The combined expressions are compiled lazily on the first match after the
+// rules were added.
+type RegexTable struct {
+	// ruleStorage is the storage of the network filtering rules.
+	ruleStorage RuleStorage
+
+	// mu protects all fields below.
+	mu *sync.RWMutex
+
+	// batches are the groups of rules with combined regular expressions.
+	batches []*regexBatch
+
+	// dirty is true if some of the batches must be recompiled.
+	dirty bool
+}
+
+// regexBatch is a group of regex rules with a combined regular expression.
+type regexBatch struct {
+	// re is the combined regular expression of the rules.  It is nil if the
+	// batch hasn't been compiled yet or if the compilation has failed, in
+	// which case each rule is matched separately.
+	re *regexp.Regexp
+
+	// indexes are the storage indexes of the rules of this batch.
+	indexes []int64
+
+	// texts are the regular expressions of the rules.
+	texts []string
+
+	// dirty is true if the batch must be recompiled.
+	dirty bool
+}
+
+// NewRegexTable creates a new instance of *RegexTable.
+func NewRegexTable(rs RuleStorage) (t *RegexTable) {
+	return &RegexTable{
+		ruleStorage: rs,
+		mu:          &sync.RWMutex{},
+	}
+}
+
+// type check
+var _ Table = (*RegexTable)(nil)
+
+// Add implements the [Table] interface for *RegexTable.
+func (t *RegexTable) Add(f *rules.NetworkRule, storageIdx int64) (ok bool) {
+	if !f.IsRegexRule() {
+		return false
+	}
+
+	text, ok := f.RegexpText()
+	if !ok {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.batches) == 0 || len(t.batches[len(t.batches)-1].indexes) == regexBatchSize {
+		t.batches = append(t.batches, &regexBatch{})
+	}
+
+	b := t.batches[len(t.batches)-1]
+	b.indexes = append(b.indexes, storageIdx)
+	b.texts = append(b.texts, text)
+	b.dirty = true
+	t.dirty = true
+
+	return true
+}
+
+// AppendMatching implements the [Table] interface for *RegexTable.
+func (t *RegexTable) AppendMatching(
+	matching []*rules.NetworkRule,
+	r *rules.Request,
+) (res []*rules.NetworkRule) {
+	t.rlockBuilt()
+	defer t.mu.RUnlock()
+
+	res = matching
+	for _, b := range t.batches {
+		if b.re != nil && !b.matchAny(r) {
+			continue
+		}
+
+		for _, idx := range b.indexes {
+			rule := t.ruleStorage.RetrieveNetworkRule(idx)
+			if rule != nil && rule.Match(r) {
+				res = append(res, rule)
+			}
+		}
+	}
+
+	return res
+}
+
+// rlockBuilt locks t.mu for reading, compiling the batches first if necessary.
+func (t *RegexTable) rlockBuilt() {
+	t.mu.RLock()
+	if !t.dirty {
+		return
+	}
+
+	t.mu.RUnlock()
+	t.mu.Lock()
+	if t.dirty {
+		for _, b := range t.batches {
+			b.compile()
+		}
+
+		t.dirty = false
+	}
+	t.mu.Unlock()
+
+	t.mu.RLock()
+}
+
+// compile compiles the combined regular expression of b, if necessary.  If the
+// compilation fails, b.re is set to nil.
+func (b *regexBatch) compile() {
+	if !b.dirty {
+		return
+	}
+
+	b.dirty = false
+
+	re, err := regexp.Compile(combineRegexps(b.texts))
+	if err != nil {
+		// Some of the expressions may be invalid, in which case their rules
+		// never match and may be excluded from the combined expression.
+		var valid []string
+		for _, text := range b.texts {
+			if _, err = regexp.Compile(text); err == nil {
+				valid = append(valid, text)
+			}
+		}
+
+		// Don't return the error, since the rules are matched separately in
+		// that case.
+		re, _ = regexp.Compile(combineRegexps(valid))
+	}
+
+	b.re = re
+}
+
+// combineRegexps returns the alternation of the regular expressions texts.
+func combineRegexps(texts []string) (combined string) {
+	var sb strings.Builder
+	for i, text := range texts {
+		if i > 0 {
+			_ = sb.WriteByte('|')
+		}
+
+		// Wrap each expression into a group so that its flags only apply to
+		// it.
+		_, _ = sb.WriteString("(?:")
+		_, _ = sb.WriteString(text)
+		_ = sb.WriteByte(')')
+	}
+
+	return sb.String()
+}
+
+// matchAny returns true if any rule of b may match r.  b.re must not be nil.
+func (b *regexBatch) matchAny(r *rules.Request) (ok bool) {
+	// The regex rules are matched against the hostname in the hostname
+	// requests, see rules.NetworkRule.shouldMatchHostname.
+	return b.re.MatchString(r.URL) || (r.IsHostnameRequest && b.re.MatchString(r.Hostname))
+}
@@ -0,0 +1,143 @@
+package lookup_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/internal/lookup"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Common regex rules for tests.
+const (
+	testRuleRegex          = `/banner[0-9]+/`
+	testRuleRegexHostname  = `/^ads[0-9]\./`
+	testRuleRegexInvalid   = `/(?!x)ads/`
+	testRuleRegexMatchCase = `/Track(er)?\.js/$match-case`
+)
+
+func TestRegexTable_Add(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		want assert.BoolAssertionFunc
+		name string
+		text string
+	}{{
+		want: assert.True,
+		name: "regex",
+		text: testRuleRegex + "\n",
+	}, {
+		want: assert.True,
+		name: "invalid",
+		text: testRuleRegexInvalid + "\n",
+	}, {
+		want: assert.False,
+		name: "not_regex",
+		text: testRuleText,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newStorage(t, tc.text)
+			tbl := lookup.NewRegexTable(s)
+			assertRuleIsAdded(t, tbl, s, tc.want)
+		})
+	}
+}
+
+func TestRegexTable_AppendMatching(t *testing.T) {
+	t.Parallel()
+
+	// Add enough rules to fill several batches.
+	var sb strings.Builder
+	for i := range 300 {
+		_, _ = fmt.Fprintf(&sb, "/item-%d-[0-9]+/\n", i)
+	}
+
+	_, _ = sb.WriteString(strings.Join([]string{
+		testRuleRegex,
+		testRuleRegexHostname,
+		testRuleRegexInvalid,
+		testRuleRegexMatchCase,
+	}, "\n"))
+
+	s := newStorage(t, sb.String())
+	tbl := lookup.NewRegexTable(s)
+	loadTable(t, tbl, s)
+
+	testCases := []struct {
+		req          *rules.Request
+		name         string
+		wantRuleText string
+	}{{
+		req:          rules.NewRequest(testURLStrNoMatch, "", rules.TypeOther),
+		name:         "no_match",
+		wantRuleText: "",
+	}, {
+		req:          rules.NewRequest("https://example.org/BANNER12.png", "", rules.TypeImage),
+		name:         "match",
+		wantRuleText: testRuleRegex,
+	}, {
+		req:          rules.NewRequest("https://example.org/item-299-1", "", rules.TypeOther),
+		name:         "match_last_batch",
+		wantRuleText: "/item-299-[0-9]+/",
+	}, {
+		req:          rules.NewRequest("https://example.org/Tracker.js", "", rules.TypeScript),
+		name:         "match_case",
+		wantRuleText: testRuleRegexMatchCase,
+	}, {
+		req:          rules.NewRequest("https://example.org/tracker.js", "", rules.TypeScript),
+		name:         "match_case_no_match",
+		wantRuleText: "",
+	}, {
+		req:          rules.NewRequestForHostname("ads1.example"),
+		name:         "hostname",
+		wantRuleText: testRuleRegexHostname,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assertMatch(t, tbl, tc.req, tc.wantRuleText)
+		})
+	}
+}
+
+func BenchmarkRegexTable_AppendMatching(b *testing.B) {
+	var sb strings.Builder
+	for i := range 1000 {
+		_, _ = fmt.Fprintf(&sb, "/item-%d-[0-9]+/\n", i)
+	}
+
+	_, _ = sb.WriteString(testRuleRegex + "\n")
+
+	s := newStorage(b, sb.String())
+	tbl := lookup.NewRegexTable(s)
+	loadTable(b, tbl, s)
+
+	const urlStr = "https://example.org/banner1.png"
+	r := rules.NewRequest(urlStr, "", rules.TypeImage)
+
+	gotRules := make([]*rules.NetworkRule, 0, 1)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		gotRules = tbl.AppendMatching(gotRules[:0], r)
+	}
+
+	require.Len(b, gotRules, 1)
+
+	// Most recent results:
+	//	goos: linux
+	//	goarch: amd64
+	//	pkg: github.com/AdguardTeam/urlfilter/internal/lookup
+	//	cpu: Intel(R) Xeon(R) Processor
+	//	BenchmarkRegexTable_AppendMatching    	  102864	     11360 ns/op	      16 B/op	       0 allocs/op
+}
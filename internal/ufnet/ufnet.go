@@ -1,37 +1,50 @@
 // Package ufnet contains utilities for domain and hostname parsing/validation.
 package ufnet
 
-import "strings"
+import (
+	"strings"
+	"unicode/utf8"
 
-// ExtractHostname quickly retrieves hostname from the given URL.
+	"golang.org/x/net/idna"
+)
+
+// ExtractHostname quickly retrieves hostname from the given URL.  It handles
+// the userinfo, the port, and the IPv6 literals, which are returned without
+// the brackets, the same way [url.URL.Hostname] does.
 //
 // NOTE: ExtractHostname is an optimized, best-effort function to retrieve a
 // hostname from a URL-like string.  The result is not guaranteed to be correct
-// for some edge cases, which include non-hierarchical URLs and IPv6 hostnames.
+// for some edge cases, which include non-hierarchical URLs.
 //
 // TODO(a.garipov): Consider moving to golibs.
-//
-// TODO(s.chzhen):  Consider using url.URL.
 func ExtractHostname(url string) (hostname string) {
+	start, end := HostnameBounds(url)
+
+	return url[start:end]
+}
+
+// HostnameBounds returns the bounds of the hostname returned by
+// [ExtractHostname] within url.  start and end are equal if there is no
+// hostname.
+func HostnameBounds(url string) (start, end int) {
 	firstIdx := strings.Index(url, "//")
-	if firstIdx == -1 {
-		// This is a non-hierarchical structured URL (e.g. stun: or turn:)
-		// https://tools.ietf.org/html/rfc4395#section-2.2
-		// https://datatracker.ietf.org/doc/html/rfc7064#appendix-B
-		firstIdx = strings.Index(url, ":")
-		if firstIdx == -1 {
-			return ""
-		}
+	if firstIdx != -1 {
+		firstIdx += len("//")
+		start, end = authorityHostnameBounds(url[firstIdx:])
 
-		firstIdx = firstIdx - 1
-	} else {
-		firstIdx = firstIdx + 2
+		return firstIdx + start, firstIdx + end
 	}
 
-	if firstIdx < 0 {
-		return ""
+	// This is a non-hierarchical structured URL (e.g. stun: or turn:)
+	// https://tools.ietf.org/html/rfc4395#section-2.2
+	// https://datatracker.ietf.org/doc/html/rfc7064#appendix-B
+	firstIdx = strings.Index(url, ":")
+	if firstIdx < 1 {
+		return 0, 0
 	}
 
+	firstIdx = firstIdx - 1
+
 	nextIdx := strings.IndexAny(url[firstIdx:], "/:?")
 	if nextIdx == -1 {
 		nextIdx = len(url)
@@ -40,10 +53,77 @@ func ExtractHostname(url string) (hostname string) {
 	}
 
 	if nextIdx <= firstIdx {
-		return ""
+		return 0, 0
+	}
+
+	return firstIdx, nextIdx
+}
+
+// authorityHostnameBounds returns the bounds of the hostname within the part
+// of a URL following the "//".
+func authorityHostnameBounds(rest string) (start, end int) {
+	// The authority is terminated by the path, the query, or the fragment.
+	end = len(rest)
+	if i := strings.IndexAny(rest, "/?#"); i != -1 {
+		end = i
+	}
+
+	// Skip the userinfo, which may contain colons.
+	if at := strings.LastIndexByte(rest[:end], '@'); at != -1 {
+		start = at + 1
+	}
+
+	host := rest[start:end]
+	if host != "" && host[0] == '[' {
+		i := strings.IndexByte(host, ']')
+		if i == -1 {
+			return 0, 0
+		}
+
+		return start + 1, start + i
+	}
+
+	if colon := strings.IndexByte(host, ':'); colon != -1 {
+		end = start + colon
+	}
+
+	return start, end
+}
+
+// NormalizeDomain returns the lowercased ASCII form of the domain name, which
+// may be an internationalized one, for example "xn--e1afmkfd.xn--p1ai" for
+// "пример.рф".  If name cannot be converted, it is returned lowercased, so
+// that [IsDomainName] rejects it.  It doesn't allocate if name is already a
+// lowercased ASCII one, which is the most common case.
+func NormalizeDomain(name string) (normalized string) {
+	hasUpper := false
+	for i := range len(name) {
+		c := name[i]
+		if c >= utf8.RuneSelf {
+			return toASCII(name)
+		}
+
+		hasUpper = hasUpper || ('A' <= c && c <= 'Z')
+	}
+
+	if hasUpper {
+		return strings.ToLower(name)
+	}
+
+	return name
+}
+
+// toASCII converts the internationalized domain name to its lowercased ASCII
+// form.  If name cannot be converted, it is returned lowercased.
+func toASCII(name string) (ascii string) {
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		// Don't return the error, since the callers validate the result
+		// anyway.
+		return strings.ToLower(name)
 	}
 
-	return url[firstIdx:nextIdx]
+	return ascii
 }
 
 // IsDomainName - check if input string is a valid domain name
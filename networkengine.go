@@ -1,6 +1,8 @@
 package urlfilter
 
 import (
+	"runtime"
+
 	"github.com/AdguardTeam/golibs/syncutil"
 	"github.com/AdguardTeam/urlfilter/filterlist"
 	"github.com/AdguardTeam/urlfilter/internal/lookup"
@@ -21,6 +23,10 @@ type NetworkEngine struct {
 	// added to the faster table first.
 	lookupTables []lookup.Table
 
+	// badfilterDisabled are the rules disabled by the $badfilter rules while
+	// scanning the storage.
+	badfilterDisabled []*rules.BadfilterDisabled
+
 	// RulesCount is the count of rules added to the engine.
 	//
 	// TODO(a.garipov):  Unexport and export a getter method.
@@ -29,31 +35,87 @@ type NetworkEngine struct {
 
 // NewNetworkEngine builds an instance of the network engine.  It scans the
 // specified rule storage and adds all network rules found there to the internal
-// lookup tables.
+// lookup tables.  The rules are parsed using runtime.GOMAXPROCS(0) goroutines.
+// The $badfilter rules are applied beforehand, see [rules.BadfilterIndex].
 func NewNetworkEngine(s *filterlist.RuleStorage) (engine *NetworkEngine) {
-	engine = NewNetworkEngineSkipStorageScan(s)
-	scanner := s.NewRuleStorageScanner()
-
-	for scanner.Scan() {
-		f, idx := scanner.Rule()
-		rule, ok := f.(*rules.NetworkRule)
-		if ok {
-			engine.AddRule(rule, idx)
-		}
-	}
+	return NewNetworkEngineWithConfig(&NetworkEngineConfig{
+		Storage: s,
+	})
+}
+
+// NetworkEngineConfig is the configuration structure for a *NetworkEngine.
+type NetworkEngineConfig struct {
+	// Storage is the storage of the rules.  It must not be nil.
+	Storage *filterlist.RuleStorage
+
+	// Workers is the number of goroutines parsing the rules of the storage.
+	// If it is zero, runtime.GOMAXPROCS(0) is used.  If it is one, the rules
+	// are parsed on the calling goroutine.  It must not be negative.
+	Workers int
+}
+
+// NewNetworkEngineWithConfig returns a *NetworkEngine built from the network
+// rules in the storage.  The rules are added to the lookup tables in the same
+// order regardless of the number of workers.  conf must not be nil.
+func NewNetworkEngineWithConfig(conf *NetworkEngineConfig) (engine *NetworkEngine) {
+	workers := scanWorkers(conf.Workers)
+	bf := newBadfilterResolver(conf.Storage, workers)
+
+	engine = newNetworkEngine(conf.Storage, bf)
+	conf.Storage.Scan(&filterlist.ScanConfig{
+		Handler: func(r rules.Rule, storageIdx int64) {
+			rule := bf.resolve(r.(*rules.NetworkRule), storageIdx)
+			if rule != nil {
+				engine.AddRule(rule, storageIdx)
+			}
+		},
+		Filter: func(r rules.Rule) (ok bool) {
+			_, ok = r.(*rules.NetworkRule)
+
+			return ok
+		},
+		Workers: workers,
+	})
+
+	engine.badfilterDisabled = bf.disabled
 
 	return engine
 }
 
+// BadfilterDisabled returns the rules disabled, completely or partially, by the
+// $badfilter rules while building the engine.  The result must not be
+// modified.
+func (n *NetworkEngine) BadfilterDisabled() (disabled []*rules.BadfilterDisabled) {
+	return n.badfilterDisabled
+}
+
+// scanWorkers returns the number of goroutines to parse the rules with, given
+// the configured number n.
+func scanWorkers(n int) (workers int) {
+	if n == 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+
+	return n
+}
+
 // NewNetworkEngineSkipStorageScan creates a new instance of *NetworkEngine, but
 // unlike [NewNetworkEngine] it does not scan the storage.
 func NewNetworkEngineSkipStorageScan(s *filterlist.RuleStorage) (engine *NetworkEngine) {
+	return newNetworkEngine(s, s)
+}
+
+// newNetworkEngine returns a new *NetworkEngine with no rules, the lookup tables
+// of which retrieve the rules from ls.
+func newNetworkEngine(s *filterlist.RuleStorage, ls lookup.RuleStorage) (engine *NetworkEngine) {
 	return &NetworkEngine{
 		ruleStorage: s,
 		rulesPool:   syncutil.NewSlicePool[*rules.NetworkRule](1),
 		lookupTables: []lookup.Table{
-			lookup.NewShortcutsTable(s),
-			lookup.NewDomainsTable(s),
+			lookup.NewHostnameTable(ls),
+			lookup.NewAhoCorasickTable(ls),
+			lookup.NewDomainsTable(ls),
+			lookup.NewRegexTable(ls),
 			&lookup.SeqScanTable{},
 		},
 	}
@@ -100,7 +162,9 @@ func (n *NetworkEngine) AppendAllMatching(
 	return res
 }
 
-// AddRule adds rule to the network engine.
+// AddRule adds rule to the network engine.  Unlike the $badfilter rules of the
+// storage scanned by [NewNetworkEngine], the ones added this way are applied
+// when matching, see [rules.NewMatchingResult].
 func (n *NetworkEngine) AddRule(f *rules.NetworkRule, storageIdx int64) {
 	for _, table := range n.lookupTables {
 		if table.Add(f, storageIdx) {
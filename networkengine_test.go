@@ -116,6 +116,122 @@ func TestMatchSimplePattern(t *testing.T) {
 	assert.NotNil(t, rule)
 }
 
+func TestNewNetworkEngine_badfilter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		partialRule  = "||ads.example^$domain=a.example|b.example"
+		disabledRule = "||tracker.example^"
+	)
+
+	rulesText := strings.Join([]string{
+		partialRule,
+		disabledRule,
+		"||ads.example^$domain=a.example,badfilter",
+		"||tracker.example^$badfilter",
+	}, "\n")
+
+	for _, workers := range []int{1, 4} {
+		ruleStorage := newTestRuleStorage(t, 1, rulesText)
+		engine := NewNetworkEngineWithConfig(&NetworkEngineConfig{
+			Storage: ruleStorage,
+			Workers: workers,
+		})
+
+		assert.Equal(t, 1, engine.RulesCount)
+
+		disabled := engine.BadfilterDisabled()
+		require.Len(t, disabled, 2)
+
+		assert.Equal(t, partialRule, disabled[0].Rule.Text())
+		require.NotNil(t, disabled[0].Remaining)
+		assert.Equal(t, []string{"b.example"}, disabled[0].Remaining.GetPermittedDomains())
+
+		assert.Equal(t, disabledRule, disabled[1].Rule.Text())
+		assert.Nil(t, disabled[1].Remaining)
+
+		testCases := []struct {
+			name      string
+			url       string
+			sourceURL string
+			wantOK    bool
+		}{{
+			name:      "partial_disabled",
+			url:       "https://ads.example/",
+			sourceURL: "https://a.example/",
+			wantOK:    false,
+		}, {
+			name:      "partial_remaining",
+			url:       "https://ads.example/",
+			sourceURL: "https://b.example/",
+			wantOK:    true,
+		}, {
+			name:      "disabled",
+			url:       "https://tracker.example/",
+			sourceURL: "",
+			wantOK:    false,
+		}}
+
+		for _, tc := range testCases {
+			r := rules.NewRequest(tc.url, tc.sourceURL, rules.TypeOther)
+			_, ok := engine.Match(r)
+			assert.Equalf(t, tc.wantOK, ok, "workers %d: %s", workers, tc.name)
+		}
+	}
+}
+
+func TestNetworkEngine_AddRule_badfilter(t *testing.T) {
+	t.Parallel()
+
+	rulesText := strings.Join([]string{
+		"||example.com^$badfilter",
+		"||example.org^",
+		"||example.org^$badfilter",
+		"||example.net^",
+	}, "\n")
+
+	ruleStorage := newTestRuleStorage(t, 1, rulesText)
+	engine := NewNetworkEngineSkipStorageScan(ruleStorage)
+	ruleStorage.Scan(&filterlist.ScanConfig{
+		Handler: func(r rules.Rule, storageIdx int64) {
+			engine.AddRule(r.(*rules.NetworkRule), storageIdx)
+		},
+		Filter: func(r rules.Rule) (ok bool) {
+			_, ok = r.(*rules.NetworkRule)
+
+			return ok
+		},
+		Workers: 1,
+	})
+
+	testCases := []struct {
+		name   string
+		url    string
+		wantOK bool
+	}{{
+		name:   "badfilter_only",
+		url:    "https://example.com/",
+		wantOK: false,
+	}, {
+		name:   "disabled",
+		url:    "https://example.org/",
+		wantOK: false,
+	}, {
+		name:   "not_disabled",
+		url:    "https://example.net/",
+		wantOK: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, ok := engine.Match(rules.NewRequest(tc.url, "", rules.TypeOther))
+			assert.Equal(t, tc.wantOK, ok)
+		})
+	}
+}
+
 // TODO(a.garipov):  Consider removing and replacing with tests similar to
 // [BenchmarkDNSEngine_heapAlloc].
 func TestBenchNetworkEngine(t *testing.T) {
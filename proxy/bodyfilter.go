@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/AdguardTeam/gomitmproxy/proxyutil"
+)
+
+// hlsMediaTypes are the lowercased media types of the HLS playlists.
+var hlsMediaTypes = map[string]struct{}{
+	"application/vnd.apple.mpegurl": {},
+	"application/x-mpegurl":         {},
+	"audio/mpegurl":                 {},
+	"audio/x-mpegurl":               {},
+}
+
+// isJSONMediaType returns true if the lowercased media type is the one of JSON
+// documents.
+func isJSONMediaType(mediaType string) (ok bool) {
+	return mediaType == "application/json" ||
+		mediaType == "text/json" ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// bodyFilter returns the function modifying the response body of the session
+// by the $jsonprune or the $hls rules, depending on the media type of the
+// response.  It returns nil if there is nothing to modify.
+func bodyFilter(session *Session) (filter func(b []byte) (res []byte)) {
+	result := session.Result
+	mediaType := strings.ToLower(session.MediaType)
+	if _, ok := hlsMediaTypes[mediaType]; ok && len(result.HLSRules) > 0 {
+		return result.FilterHLS
+	} else if !isJSONMediaType(mediaType) || len(result.JSONPruneRules) == 0 {
+		return nil
+	}
+
+	return func(b []byte) (res []byte) {
+		res, err := result.PruneJSON(b)
+		if err != nil {
+			log.Debug("urlfilter: id=%s: pruning json: %s", session.ID, err)
+		}
+
+		return res
+	}
+}
+
+// filterBody replaces the body of the session response with the one modified by
+// filter.
+func filterBody(session *Session, filter func(b []byte) (res []byte)) (err error) {
+	res := session.HTTPResponse
+
+	b, err := proxyutil.ReadDecompressedBody(res)
+	// Close the original body
+	_ = res.Body.Close()
+	if err != nil {
+		log.Error("urlfilter id=%s: could not read the full body: %v", session.ID, err)
+		return err
+	}
+
+	b = filter(b)
+
+	res.Body = io.NopCloser(bytes.NewReader(b))
+	res.Header.Del("Content-Encoding")
+	res.ContentLength = int64(len(b))
+
+	return nil
+}
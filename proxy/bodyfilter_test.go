@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterBody(t *testing.T) {
+	t.Parallel()
+
+	ruleTexts := []string{
+		`||example.org^$jsonprune=\$.ads`,
+		"||example.org^$hls=/ads/",
+	}
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{{
+		name:        "json",
+		contentType: "application/json; charset=utf-8",
+		body:        `{"ads":[1],"id":1}`,
+		want:        `{"id":1}`,
+	}, {
+		name:        "json_suffix",
+		contentType: "application/ld+json",
+		body:        `{"ads":[1],"id":1}`,
+		want:        `{"id":1}`,
+	}, {
+		name:        "json_invalid",
+		contentType: "application/json",
+		body:        `{"ads":`,
+		want:        `{"ads":`,
+	}, {
+		name:        "hls",
+		contentType: "application/vnd.apple.mpegURL",
+		body:        "#EXTM3U\n#EXTINF:5,\n/ads/1.ts\n#EXTINF:10,\n/video/1.ts\n",
+		want:        "#EXTM3U\n#EXTINF:10,\n/video/1.ts\n",
+	}, {
+		name:        "other",
+		contentType: "text/plain",
+		body:        `{"ads":[1],"id":1}`,
+		want:        "",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newTestSession(t, "https://example.org/data", ruleTexts...)
+			s.SetResponse(&http.Response{
+				Header: http.Header{"Content-Type": {tc.contentType}},
+				Body:   io.NopCloser(strings.NewReader(tc.body)),
+			})
+
+			filter := bodyFilter(s)
+			if tc.want == "" {
+				assert.Nil(t, filter)
+
+				return
+			}
+
+			require.NotNil(t, filter)
+			require.NoError(t, filterBody(s, filter))
+
+			b, err := io.ReadAll(s.HTTPResponse.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, string(b))
+			assert.Equal(t, int64(len(tc.want)), s.HTTPResponse.ContentLength)
+		})
+	}
+}
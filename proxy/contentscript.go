@@ -90,7 +90,10 @@ func (s *Server) buildContentScript(session *Session) *http.Response {
 		return res
 	}
 
-	cosmeticResult := s.engine.GetCosmeticResult(hostname, rules.CosmeticOption(option))
+	// TODO: Inject the JS rules once the scriptlets are implemented.  The
+	// content of the scriptlet rules isn't valid JavaScript.
+	cosmeticOption := rules.CosmeticOption(option) &^ rules.CosmeticOptionJS
+	cosmeticResult := s.engine.GetCosmeticResult(hostname, cosmeticOption)
 	bodyBytes := []byte(s.buildContentScriptCode(cosmeticResult))
 	contentLen := len(bodyBytes)
 
@@ -2,11 +2,20 @@ package proxy
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/AdguardTeam/golibs/log"
-
+	"github.com/AdguardTeam/golibs/testutil"
 	"github.com/AdguardTeam/urlfilter"
+	"github.com/AdguardTeam/urlfilter/filterlist"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestContentScriptTmpl(t *testing.T) {
@@ -61,3 +70,44 @@ func TestContentScriptTmpl(t *testing.T) {
 
 	// TODO: Run a nodejs script that will validate the data
 }
+
+func TestServer_buildContentScript_js(t *testing.T) {
+	t.Parallel()
+
+	lists := []filterlist.Interface{
+		filterlist.NewString(&filterlist.StringConfig{
+			RulesText: "example.org##.banner\n" +
+				"example.org##+js(set-constant, foo, true)\n" +
+				"example.org#%#window.ads = false;",
+			ID:      1,
+			Trusted: true,
+		}),
+	}
+
+	storage, err := filterlist.NewRuleStorage(lists)
+	require.NoError(t, err)
+
+	testutil.CleanupAndRequireSuccess(t, storage.Close)
+
+	s := &Server{
+		engine:    urlfilter.NewEngine(storage),
+		createdAt: time.Now(),
+	}
+
+	u := fmt.Sprintf(
+		"http://injections.adguard.org/content-script.js?hostname=example.org&option=%d&ts=%d",
+		rules.CosmeticOptionAll,
+		s.createdAt.Unix(),
+	)
+	req := httptest.NewRequest(http.MethodGet, u, nil)
+
+	res := s.buildContentScript(NewSession("test", req))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), ".banner")
+	assert.NotContains(t, string(body), "scriptlet")
+	assert.NotContains(t, string(body), "window.ads")
+}
@@ -3,6 +3,7 @@ package proxy
 import (
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/AdguardTeam/urlfilter/rules"
 
@@ -40,6 +41,14 @@ func (s *Server) onRequest(sess *gomitmproxy.Session) (*http.Request, *http.Resp
 		return nil, newBlockedResponse(session, rule)
 	}
 
+	if res := newModifiedURLResponse(session); res != nil {
+		sess.SetProp(requestBlockedKey, true)
+
+		return nil, res
+	}
+
+	removeHeaders(session, r.Header, true)
+
 	if s.shouldSuppressCache(session) {
 		suppressCache(r)
 	}
@@ -47,6 +56,44 @@ func (s *Server) onRequest(sess *gomitmproxy.Session) (*http.Request, *http.Resp
 	return r, nil
 }
 
+// newModifiedURLResponse returns a response redirecting the navigation to the
+// URL modified by the $urltransform rules and without the query parameters
+// removed by the $removeparam rules, or nil if the URL isn't modified.  Only
+// the GET and HEAD requests are redirected, since a redirect would lose the
+// body of the others.  To prevent redirect loops, the navigation isn't
+// redirected if the rules would modify the new URL again.
+func newModifiedURLResponse(session *Session) (res *http.Response) {
+	rt := session.Request.RequestType
+	if rt != rules.TypeDocument && rt != rules.TypeSubdocument {
+		return nil
+	}
+
+	method := session.HTTPRequest.Method
+	if method != http.MethodGet && method != http.MethodHead {
+		return nil
+	}
+
+	u := session.Request.URL
+	modified := modifyURL(session.Result, u)
+	if modified == u {
+		return nil
+	} else if modifyURL(session.Result, modified) != modified {
+		log.Debug("urlfilter: id=%s: not modifying url, would loop: %s", session.ID, u)
+
+		return nil
+	}
+
+	log.Debug("urlfilter: id=%s: modifying url: %s", session.ID, u)
+
+	return newRedirectResponse(session, modified)
+}
+
+// modifyURL returns u modified by the $urltransform and $removeparam rules of
+// res.
+func modifyURL(res *rules.MatchingResult, u string) (modified string) {
+	return res.RemoveParams(res.TransformURL(u))
+}
+
 // onResponse handles all the responses
 func (s *Server) onResponse(sess *gomitmproxy.Session) *http.Response {
 	if _, ok := sess.GetProp(requestBlockedKey); ok {
@@ -83,10 +130,27 @@ func (s *Server) onResponse(sess *gomitmproxy.Session) *http.Response {
 		return newBlockedResponse(session, rule)
 	}
 
-	// Filter HTML for main frames and iframes.
+	removeHeaders(session, session.HTTPResponse.Header, false)
+
 	rt := session.Request.RequestType
-	if (rt == rules.TypeDocument || rt == rules.TypeSubdocument) &&
-		session.Result.GetCosmeticOption() != rules.CosmeticOptionNone {
+	isDocument := rt == rules.TypeDocument || rt == rules.TypeSubdocument
+	if isDocument {
+		setPermissionsPolicy(session, session.HTTPResponse.Header)
+		setReferrerPolicy(session, session.HTTPResponse.Header)
+	}
+
+	// Filter JSON responses and HLS playlists.
+	if filter := bodyFilter(session); filter != nil {
+		err := filterBody(session, filter)
+		if err != nil {
+			return proxyutil.NewErrorResponse(session.HTTPRequest, err)
+		}
+
+		return session.HTTPResponse
+	}
+
+	// Filter HTML for main frames and iframes.
+	if isDocument && session.Result.GetCosmeticOption() != rules.CosmeticOptionNone {
 		err := s.filterHTML(session)
 		if err != nil {
 			return proxyutil.NewErrorResponse(session.HTTPRequest, err)
@@ -97,6 +161,78 @@ func (s *Server) onResponse(sess *gomitmproxy.Session) *http.Response {
 	return nil
 }
 
+// removeHeaders removes the headers listed by the $removeheader rules of the
+// session result from h, which are the headers of the request, if request is
+// true, or the response.
+func removeHeaders(session *Session, h http.Header, request bool) {
+	for _, name := range session.Result.RemovedHeaders(request) {
+		log.Debug("urlfilter: id=%s: removing header %s", session.ID, name)
+
+		h.Del(name)
+	}
+}
+
+// permissionsPolicyHeader is the name of the Permissions-Policy HTTP header.
+const permissionsPolicyHeader = "Permissions-Policy"
+
+// setPermissionsPolicy merges the directives of the $permissions rules of the
+// session result into the Permissions-Policy header of the response headers h.
+// The directives of the rules replace the ones of the response for the same
+// features.
+func setPermissionsPolicy(session *Session, h http.Header) {
+	policy := session.Result.PermissionsPolicy()
+	if policy == "" {
+		return
+	}
+
+	log.Debug("urlfilter: id=%s: setting permissions policy %s", session.ID, policy)
+
+	h.Set(permissionsPolicyHeader, mergePermissionsPolicy(h.Values(permissionsPolicyHeader), policy))
+}
+
+// mergePermissionsPolicy returns the Permissions-Policy header value with the
+// directives of policy and those of the header values orig, which are for the
+// other features.
+func mergePermissionsPolicy(orig []string, policy string) (merged string) {
+	features := map[string]struct{}{}
+	for _, d := range strings.Split(policy, ",") {
+		features[permissionsFeature(d)] = struct{}{}
+	}
+
+	directives := []string{}
+	for _, v := range orig {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(d)
+			if _, ok := features[permissionsFeature(d)]; !ok && d != "" {
+				directives = append(directives, d)
+			}
+		}
+	}
+
+	return strings.Join(append(directives, policy), ", ")
+}
+
+// permissionsFeature returns the lowercased feature name of the
+// Permissions-Policy directive d.
+func permissionsFeature(d string) (feature string) {
+	feature, _, _ = strings.Cut(d, "=")
+
+	return strings.ToLower(strings.TrimSpace(feature))
+}
+
+// setReferrerPolicy sets the Referrer-Policy header of the response headers h to
+// the value of the $referrerpolicy rule of the session result, if any.
+func setReferrerPolicy(session *Session, h http.Header) {
+	policy := session.Result.ReferrerPolicy()
+	if policy == "" {
+		return
+	}
+
+	log.Debug("urlfilter: id=%s: setting referrer policy %s", session.ID, policy)
+
+	h.Set("Referrer-Policy", policy)
+}
+
 // onConnect - the only purpose is to intercept and suppress connections to InjectionHost
 func (s *Server) onConnect(session *gomitmproxy.Session, proto, addr string) net.Conn {
 	host, _, err := net.SplitHostPort(addr)
@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSession returns a new session for a request to u with the result of
+// matching the rules.
+func newTestSession(tb testing.TB, u string, ruleTexts ...string) (s *Session) {
+	tb.Helper()
+
+	var rs []*rules.NetworkRule
+	for _, text := range ruleTexts {
+		r, err := rules.NewNetworkRule(text, -1)
+		require.NoError(tb, err)
+
+		rs = append(rs, r)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, u, nil)
+	req.Header.Set("Sec-Fetch-Dest", "document")
+
+	s = NewSession("test", req)
+	s.Result = rules.NewMatchingResult(rs, nil)
+
+	return s
+}
+
+func TestRemoveHeaders(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSession(
+		t,
+		"https://example.org/",
+		"$removeheader=refresh",
+		"$removeheader=request:x-client-data",
+	)
+
+	h := http.Header{}
+	h.Set("Refresh", "0")
+	h.Set("X-Client-Data", "1")
+	h.Set("X-Other", "2")
+
+	removeHeaders(s, h, true)
+	assert.Equal(t, http.Header{"Refresh": {"0"}, "X-Other": {"2"}}, h)
+
+	removeHeaders(s, h, false)
+	assert.Equal(t, http.Header{"X-Other": {"2"}}, h)
+}
+
+func TestNewModifiedURLResponse(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSession(t, "https://example.org/?utm_source=ad&id=1", "$removeparam=utm_source")
+
+	res := newModifiedURLResponse(s)
+	require.NotNil(t, res)
+
+	assert.Equal(t, http.StatusFound, res.StatusCode)
+	assert.Equal(t, "https://example.org/?id=1", res.Header.Get("Location"))
+
+	s = newTestSession(t, "https://example.org/?id=1", "$removeparam=utm_source")
+	assert.Nil(t, newModifiedURLResponse(s))
+
+	s = newTestSession(
+		t,
+		"https://click.example/go?to=https://example.org/?utm_source=ad&id=1",
+		`||click.example^$urltransform=/^https:\/\/click\.example\/go\?to=(.*)\$/\$1/`,
+		"$removeparam=utm_source",
+	)
+
+	res = newModifiedURLResponse(s)
+	require.NotNil(t, res)
+
+	assert.Equal(t, "https://example.org/?id=1", res.Header.Get("Location"))
+
+	t.Run("loop", func(t *testing.T) {
+		t.Parallel()
+
+		ls := newTestSession(t, "https://example.org/a", `||example.org^$urltransform=/a/aa/`)
+		assert.Nil(t, newModifiedURLResponse(ls))
+	})
+
+	t.Run("post", func(t *testing.T) {
+		t.Parallel()
+
+		ps := newTestSession(t, "https://example.org/?utm_source=ad", "$removeparam=utm_source")
+		ps.HTTPRequest.Method = http.MethodPost
+		assert.Nil(t, newModifiedURLResponse(ps))
+	})
+}
+
+func TestSetPermissionsPolicy(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSession(
+		t,
+		"https://example.org/",
+		"$permissions=browsing-topics=()",
+		"||example.org^$permissions=interest-cohort=()|geolocation=(self)",
+	)
+
+	h := http.Header{}
+	h.Set(permissionsPolicyHeader, `geolocation=*, camera=(self "https://example.org")`)
+
+	setPermissionsPolicy(s, h)
+	assert.Equal(
+		t,
+		`camera=(self "https://example.org"), `+
+			"browsing-topics=(), interest-cohort=(), geolocation=(self)",
+		h.Get(permissionsPolicyHeader),
+	)
+
+	s = newTestSession(t, "https://example.org/", "$permissions=browsing-topics=()")
+	h = http.Header{}
+
+	setPermissionsPolicy(s, h)
+	assert.Equal(t, "browsing-topics=()", h.Get(permissionsPolicyHeader))
+
+	s = newTestSession(t, "https://example.org/")
+	h = http.Header{}
+
+	setPermissionsPolicy(s, h)
+	assert.Empty(t, h)
+}
+
+func TestSetReferrerPolicy(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSession(
+		t,
+		"https://example.org/",
+		"$referrerpolicy=origin",
+		"||example.org^$referrerpolicy=no-referrer,important",
+	)
+
+	h := http.Header{}
+	h.Set("Referrer-Policy", "unsafe-url")
+
+	setReferrerPolicy(s, h)
+	assert.Equal(t, "no-referrer", h.Get("Referrer-Policy"))
+
+	s = newTestSession(t, "https://example.org/")
+	h = http.Header{}
+
+	setReferrerPolicy(s, h)
+	assert.Empty(t, h)
+}
@@ -42,3 +42,11 @@ func newBlockedResponse(session *Session, f *rules.NetworkRule) *http.Response {
 	res.Header.Set("Content-Type", "text/html; charset=utf-8")
 	return res
 }
+
+// newRedirectResponse creates an HTTP response redirecting the request to
+// location.
+func newRedirectResponse(session *Session, location string) *http.Response {
+	res := proxyutil.NewResponse(http.StatusFound, nil, session.HTTPRequest)
+	res.Header.Set("Location", location)
+	return res
+}
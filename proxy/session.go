@@ -65,6 +65,7 @@ func NewSession(id string, req *http.Request) *Session {
 		Request:     rules.NewRequest(req.URL.String(), req.Referer(), requestType),
 		HTTPRequest: req,
 	}
+	s.Request.Method = rules.ParseRequestMethod(req.Method)
 
 	return &s
 }
@@ -77,6 +78,9 @@ func (s *Session) SetResponse(res *http.Response) {
 	// Re-calculate RequestType once we have the response headers
 	s.Request.RequestType = assumeRequestType(s.HTTPRequest, s.HTTPResponse)
 
+	// Make the rules with the $header modifier match the request.
+	s.Request.ResponseHeaders = res.Header
+
 	contentType := res.Header.Get("Content-Type")
 	mediaType, params, _ := mime.ParseMediaType(contentType)
 
@@ -103,3 +103,25 @@ func TestAssumeRequestTypeFromURL(t *testing.T) {
 	u, _ = url.Parse("http://example.org/script.css")
 	require.Equal(t, rules.TypeStylesheet, assumeRequestTypeFromURL(u))
 }
+
+func TestSession_SetResponse(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.org/ad.js", nil)
+	s := NewSession("test", req)
+	require.Nil(t, s.Request.ResponseHeaders)
+
+	res := &http.Response{
+		Header: http.Header{
+			"Content-Type": {"application/javascript; charset=utf-8"},
+			"X-Ad-Id":      {"123"},
+		},
+	}
+
+	s.SetResponse(res)
+	require.Equal(t, res.Header, s.Request.ResponseHeaders)
+	require.Equal(t, rules.TypeScript, s.Request.RequestType)
+	require.Equal(t, "utf-8", s.Charset)
+
+	f, err := rules.NewNetworkRule("||example.org^$header=x-ad-id:123", -1)
+	require.NoError(t, err)
+	require.True(t, f.Match(s.Request))
+}
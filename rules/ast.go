@@ -0,0 +1,382 @@
+package rules
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// NetworkRuleNode is a read-only structured view of a [NetworkRule].  Its
+// String method is the canonical formatter of network rules.
+type NetworkRuleNode struct {
+	// DNSRewrite is the value of the $dnsrewrite modifier, if any.
+	DNSRewrite *DNSRewrite
+
+	// Pattern is the basic rule pattern without the exception marker and the
+	// modifiers.
+	Pattern string
+
+	// Header is the value of the $header modifier, if any.
+	Header string
+
+	// Modifiers are the modifiers of the rule in the canonical order.  The
+	// modifiers implying others, like $document, are replaced with the ones
+	// they imply.  The formatter only uses Modifiers.
+	Modifiers []*Modifier
+
+	// PermittedClients are the IP addresses, CIDR prefixes, and client names
+	// from the $client modifier.
+	PermittedClients []string
+
+	// RestrictedClients are the negated clients from the $client modifier.
+	RestrictedClients []string
+
+	// PermittedClientTags are the sorted tags from the $ctag modifier.
+	PermittedClientTags []string
+
+	// RestrictedClientTags are the sorted negated tags from the $ctag
+	// modifier.
+	RestrictedClientTags []string
+
+	// PermittedDomains are the domains from the $domain modifier.
+	PermittedDomains []string
+
+	// RestrictedDomains are the negated domains from the $domain modifier.
+	RestrictedDomains []string
+
+	// PermittedToDomains are the request domains from the $to modifier.
+	PermittedToDomains []string
+
+	// RestrictedToDomains are the negated request domains from the $to
+	// modifier and the domains from the $denyallow modifier.
+	RestrictedToDomains []string
+
+	// PermittedDNSTypes are the types from the $dnstype modifier.
+	PermittedDNSTypes []RRType
+
+	// RestrictedDNSTypes are the negated types from the $dnstype modifier.
+	RestrictedDNSTypes []RRType
+
+	// EnabledOptions are the enabled options of the rule.
+	EnabledOptions NetworkRuleOption
+
+	// DisabledOptions are the negated options of the rule.
+	DisabledOptions NetworkRuleOption
+
+	// PermittedRequestTypes are the request types the rule is limited to.  0
+	// means all.
+	PermittedRequestTypes RequestType
+
+	// RestrictedRequestTypes are the request types the rule doesn't apply to.
+	RestrictedRequestTypes RequestType
+
+	// PermittedMethods are the methods from the $method modifier.  0 means
+	// all.
+	PermittedMethods RequestMethod
+
+	// RestrictedMethods are the negated methods from the $method modifier.
+	RestrictedMethods RequestMethod
+
+	// Whitelist is true if this is an exception rule.
+	Whitelist bool
+}
+
+// String implements the [fmt.Stringer] interface for *NetworkRuleNode.  It
+// returns the rule text with canonical modifier names.
+func (n *NetworkRuleNode) String() (s string) {
+	b := &strings.Builder{}
+	if n.Whitelist {
+		b.WriteString(maskWhiteList)
+	}
+
+	b.WriteString(n.Pattern)
+	for i, m := range n.Modifiers {
+		if i == 0 {
+			b.WriteByte(optionsDelimiter)
+		} else {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(m.String())
+	}
+
+	return b.String()
+}
+
+// Modifier is a single modifier of a network rule, like "~third-party" or
+// "domain=example.org|~example.com".
+type Modifier struct {
+	// Name is the canonical name of the modifier without the negation mark.
+	// The uBlock Origin specific names are replaced with the AdGuard ones.
+	Name string
+
+	// Value is the unescaped value of the modifier.  It is empty if the
+	// modifier has no value.
+	Value string
+
+	// Values are the elements of Value for the modifiers which accept lists of
+	// values separated by "|", like $domain and $client.  It is nil for the
+	// other modifiers.  The formatter only uses Value.
+	Values []*ModifierValue
+
+	// Negated is true if the modifier is negated with "~".
+	Negated bool
+}
+
+// String implements the [fmt.Stringer] interface for *Modifier.
+func (m *Modifier) String() (s string) {
+	b := &strings.Builder{}
+	if m.Negated {
+		b.WriteByte('~')
+	}
+
+	b.WriteString(m.Name)
+	if m.Value != "" {
+		b.WriteByte('=')
+		b.WriteString(escapeModifierValue(m.Value))
+	}
+
+	return b.String()
+}
+
+// ModifierValue is a single element of a list modifier value.
+type ModifierValue struct {
+	// Value is the element without the negation mark.
+	Value string
+
+	// Negated is true if the element is negated with "~".
+	Negated bool
+}
+
+// String implements the [fmt.Stringer] interface for *ModifierValue.
+func (v *ModifierValue) String() (s string) {
+	if v.Negated {
+		return "~" + v.Value
+	}
+
+	return v.Value
+}
+
+// escapeModifierValue escapes the characters of v which have a special
+// meaning in the modifiers part of a network rule.
+func escapeModifierValue(v string) (escaped string) {
+	return strings.NewReplacer(",", `\,`, "$", `\$`).Replace(v)
+}
+
+// Node returns the structured view of f built from its parsed state.  The
+// caller may modify the result.
+func (f *NetworkRule) Node() (n *NetworkRuleNode) {
+	rs := f.getRestrictions()
+	n = &NetworkRuleNode{
+		Pattern:                f.pattern,
+		PermittedClients:       rs.permittedClients.strings(),
+		RestrictedClients:      rs.restrictedClients.strings(),
+		PermittedClientTags:    slices.Clone(rs.permittedClientTags),
+		RestrictedClientTags:   slices.Clone(rs.restrictedClientTags),
+		PermittedDomains:       slices.Clone(rs.permittedDomains),
+		RestrictedDomains:      slices.Clone(rs.restrictedDomains),
+		PermittedToDomains:     slices.Clone(rs.permittedToDomains),
+		RestrictedToDomains:    slices.Clone(rs.restrictedToDomains),
+		PermittedDNSTypes:      slices.Clone(rs.permittedDNSTypes),
+		RestrictedDNSTypes:     slices.Clone(rs.restrictedDNSTypes),
+		EnabledOptions:         f.enabledOptions,
+		DisabledOptions:        f.disabledOptions,
+		PermittedRequestTypes:  f.permittedRequestTypes,
+		RestrictedRequestTypes: f.restrictedRequestTypes,
+		PermittedMethods:       rs.permittedMethods,
+		RestrictedMethods:      rs.restrictedMethods,
+		Whitelist:              f.Whitelist,
+	}
+
+	if f.DNSRewrite != nil {
+		rw := *f.DNSRewrite
+		n.DNSRewrite = &rw
+	}
+
+	if rs.header != nil {
+		n.Header = rs.header.value
+	}
+
+	n.Modifiers = n.modifiers(f.getValues())
+
+	return n
+}
+
+// modifiers returns the modifiers of the rule described by n and the values of
+// its value modifiers v in the canonical order.
+func (n *NetworkRuleNode) modifiers(v *valueModifiers) (mods []*Modifier) {
+	enabled := n.EnabledOptions &^ (valueOptions | OptionRedirect)
+	permittedTypes := n.PermittedRequestTypes
+	switch {
+	case permittedTypes == typesAll:
+		mods = append(mods, &Modifier{Name: "all"})
+		enabled &^= OptionPopup
+		permittedTypes = 0
+	case permittedTypes&TypeDocument != 0:
+		// The document type is only set implicitly by the document-level
+		// options, $permissions, and $referrerpolicy, which replace the
+		// request types of the rule.
+		permittedTypes = 0
+	default:
+		// Go on.
+	}
+
+	// Ignore the errors, since a parsed rule only has the options and the
+	// request types with names.
+	optMods, _ := optionModifiers(enabled, n.DisabledOptions)
+	typeMods, _ := requestTypeModifiers(permittedTypes, n.RestrictedRequestTypes)
+	mods = append(mods, optMods...)
+	mods = append(mods, typeMods...)
+
+	mods = n.appendRestrictionModifiers(mods)
+
+	if n.DNSRewrite != nil {
+		// Ignore the error, since the values of a parsed rule are supported.
+		val, _ := formatDNSRewrite(n.DNSRewrite)
+		mods = append(mods, &Modifier{Name: "dnsrewrite", Value: val})
+	}
+
+	if n.EnabledOptions&OptionRedirect != 0 {
+		mods = append(mods, &Modifier{Name: "redirect", Value: v.redirect})
+	}
+
+	for _, opt := range slices.Sorted(maps.Keys(valueOptionNames)) {
+		if n.EnabledOptions&opt != 0 {
+			mods = append(mods, &Modifier{
+				Name:  valueOptionNames[opt],
+				Value: v.get(opt).getValue(),
+			})
+		}
+	}
+
+	return mods
+}
+
+// appendRestrictionModifiers appends the modifiers restricting the requests
+// the rule described by n matches to mods.
+func (n *NetworkRuleNode) appendRestrictionModifiers(mods []*Modifier) (res []*Modifier) {
+	mods = appendListModifier(mods, "domain", n.PermittedDomains, n.RestrictedDomains)
+	mods = appendListModifier(mods, "to", n.PermittedToDomains, n.RestrictedToDomains)
+	mods = appendListModifier(
+		mods,
+		"dnstype",
+		dnsTypeNames(n.PermittedDNSTypes),
+		dnsTypeNames(n.RestrictedDNSTypes),
+	)
+	mods = appendListModifier(mods, "ctag", n.PermittedClientTags, n.RestrictedClientTags)
+	mods = appendListModifier(
+		mods,
+		"client",
+		quoteClients(n.PermittedClients),
+		quoteClients(n.RestrictedClients),
+	)
+	mods = appendListModifier(
+		mods,
+		"method",
+		methodNamesOf(n.PermittedMethods),
+		methodNamesOf(n.RestrictedMethods),
+	)
+
+	if n.Header != "" {
+		mods = append(mods, &Modifier{Name: "header", Value: n.Header})
+	}
+
+	return mods
+}
+
+// valueOptionNames are the modifier names of the options of the value
+// modifiers.
+var valueOptionNames = map[NetworkRuleOption]string{
+	OptionRemoveParam:    "removeparam",
+	OptionRemoveHeader:   "removeheader",
+	OptionPermissions:    "permissions",
+	OptionURLTransform:   urlTransformOption,
+	OptionReferrerPolicy: "referrerpolicy",
+	OptionJSONPrune:      "jsonprune",
+	OptionHLS:            "hls",
+}
+
+// cutNegation returns s without the negation mark "~" and true if s had it.
+func cutNegation(s string) (res string, negated bool) {
+	return strings.CutPrefix(s, "~")
+}
+
+// CosmeticRuleNode is a read-only structured view of a [CosmeticRule].  Its
+// String method is the canonical formatter of cosmetic rules.
+type CosmeticRuleNode struct {
+	// Marker is the cosmetic rule marker, like "##" or "#@$#".
+	Marker string
+
+	// Content is the original content of the rule after the marker.
+	Content string
+
+	// Domains are the domains the rule is limited to.
+	Domains []*ModifierValue
+}
+
+// String implements the [fmt.Stringer] interface for *CosmeticRuleNode.
+func (n *CosmeticRuleNode) String() (s string) {
+	b := &strings.Builder{}
+	for i, d := range n.Domains {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(d.String())
+	}
+
+	b.WriteString(n.Marker)
+	b.WriteString(n.Content)
+
+	return b.String()
+}
+
+// Node returns the structured view of f.  The result is built from the rule
+// text on every call and is not used by f, so the caller may modify it.
+func (f *CosmeticRule) Node() (n *CosmeticRuleNode) {
+	index, marker := findCosmeticRuleMarker(f.RuleText)
+	if index == -1 {
+		return &CosmeticRuleNode{}
+	}
+
+	n = &CosmeticRuleNode{
+		Marker:  marker,
+		Content: strings.TrimSpace(f.RuleText[index+len(marker):]),
+	}
+
+	if index == 0 {
+		return n
+	}
+
+	for _, d := range strings.Split(f.RuleText[:index], ",") {
+		v := &ModifierValue{}
+		v.Value, v.Negated = cutNegation(d)
+		n.Domains = append(n.Domains, v)
+	}
+
+	return n
+}
+
+// HostRuleNode is a read-only structured view of a [HostRule].  Its String
+// method is the canonical formatter of host rules.
+type HostRuleNode struct {
+	// IP is the address of the rule.
+	IP netip.Addr
+
+	// Hostnames are the hostnames associated with IP.
+	Hostnames []string
+}
+
+// String implements the [fmt.Stringer] interface for *HostRuleNode.
+func (n *HostRuleNode) String() (s string) {
+	return n.IP.String() + " " + strings.Join(n.Hostnames, " ")
+}
+
+// Node returns the structured view of f.  The caller may modify the result.
+func (f *HostRule) Node() (n *HostRuleNode) {
+	return &HostRuleNode{
+		IP:        f.IP,
+		Hostnames: slices.Clone(f.Hostnames),
+	}
+}
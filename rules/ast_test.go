@@ -0,0 +1,184 @@
+package rules_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkRule_Node(t *testing.T) {
+	t.Parallel()
+
+	r, err := rules.NewNetworkRule(
+		`@@||example.org^$3p,domain=a.example|~b.example,client='Frank\'s laptop'|~1.2.3.4,`+
+			`important,~script,dnstype=A|AAAA`,
+		testFilterListID,
+	)
+	require.NoError(t, err)
+
+	want := &rules.NetworkRuleNode{
+		Pattern: "||example.org^",
+		Modifiers: []*rules.Modifier{{
+			Name: "third-party",
+		}, {
+			Name: "important",
+		}, {
+			Name:    "script",
+			Negated: true,
+		}, {
+			Name:  "domain",
+			Value: "a.example|~b.example",
+			Values: []*rules.ModifierValue{{
+				Value: "a.example",
+			}, {
+				Value:   "b.example",
+				Negated: true,
+			}},
+		}, {
+			Name:  "dnstype",
+			Value: "A|AAAA",
+			Values: []*rules.ModifierValue{{
+				Value: "A",
+			}, {
+				Value: "AAAA",
+			}},
+		}, {
+			Name:  "client",
+			Value: `'Frank\'s laptop'|~1.2.3.4`,
+			Values: []*rules.ModifierValue{{
+				Value: `'Frank\'s laptop'`,
+			}, {
+				Value:   "1.2.3.4",
+				Negated: true,
+			}},
+		}},
+		PermittedClients:       []string{"Frank's laptop"},
+		RestrictedClients:      []string{"1.2.3.4"},
+		PermittedDomains:       []string{"a.example"},
+		RestrictedDomains:      []string{"b.example"},
+		PermittedDNSTypes:      []rules.RRType{dns.TypeA, dns.TypeAAAA},
+		EnabledOptions:         rules.OptionThirdParty | rules.OptionImportant,
+		RestrictedRequestTypes: rules.TypeScript,
+		Whitelist:              true,
+	}
+
+	n := r.Node()
+	assert.Equal(t, want, n)
+
+	n.PermittedDomains[0] = "changed.example"
+	assert.Equal(t, []string{"a.example"}, r.GetPermittedDomains())
+}
+
+func TestNetworkRuleNode_String(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		in   string
+		want string
+	}{{
+		in:   "||example.org^",
+		want: "||example.org^",
+	}, {
+		in:   "@@||example.org^$~third-party,script",
+		want: "@@||example.org^$~third-party,script",
+	}, {
+		in:   "||example.org^$1p,~xhr,from=example.com",
+		want: "||example.org^$~third-party,~xmlhttprequest,domain=example.com",
+	}, {
+		in:   "||example.org^$dnsrewrite=1.2.3.4",
+		want: "||example.org^$dnsrewrite=NOERROR;A;1.2.3.4",
+	}, {
+		in:   "@@||example.org^$document",
+		want: "@@||example.org^$elemhide,jsinject,urlblock,content,extension",
+	}, {
+		in:   "||example.org^$all,~script",
+		want: "||example.org^$all,~script",
+	}, {
+		in:   "||example.org^$permissions=autoplay=()|camera=()",
+		want: `||example.org^$permissions=autoplay=()\, camera=()`,
+	}, {
+		in:   "||example.org^$denyallow=a.example,to=b.example,method=post|get",
+		want: "||example.org^$to=b.example|~a.example,method=get|post",
+	}, {
+		in:   "||example.org^$header=set-cookie:foo,redirect=noopjs,removeparam=utm",
+		want: "||example.org^$header=set-cookie:foo,redirect=noopjs,removeparam=utm",
+	}, {
+		in:   "||example.org^$dnsrewrite=NOERROR;MX;10 mail.example",
+		want: "||example.org^$dnsrewrite=NOERROR;MX;10 mail.example",
+	}, {
+		in:   `||example.org^$client='a\,b'|~c|~1.2.3.0/24`,
+		want: `||example.org^$client='a\,b'|~'c'|~1.2.3.0/24`,
+	}, {
+		in:   `/ads$/`,
+		want: `/ads$/`,
+	}, {
+		in:   `||example.org/a$b$script`,
+		want: `||example.org/a$b$script`,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.in, testFilterListID)
+			require.NoError(t, err)
+
+			n := r.Node()
+			assert.Equal(t, tc.want, n.String())
+
+			// Check that the canonical text parses into the same rule.
+			canonical, err := rules.NewNetworkRule(n.String(), testFilterListID)
+			require.NoError(t, err)
+
+			assert.Equal(t, n, canonical.Node())
+		})
+	}
+}
+
+func TestCosmeticRule_Node(t *testing.T) {
+	t.Parallel()
+
+	const text = "example.org,~sub.example.org#$#.banner { display: none; }"
+
+	r, err := rules.NewCosmeticRule(text, testFilterListID)
+	require.NoError(t, err)
+
+	n := r.Node()
+	assert.Equal(t, &rules.CosmeticRuleNode{
+		Marker:  "#$#",
+		Content: ".banner { display: none; }",
+		Domains: []*rules.ModifierValue{{
+			Value: "example.org",
+		}, {
+			Value:   "sub.example.org",
+			Negated: true,
+		}},
+	}, n)
+	assert.Equal(t, text, n.String())
+
+	r, err = rules.NewCosmeticRule("##.banner", testFilterListID)
+	require.NoError(t, err)
+
+	assert.Equal(t, "##.banner", r.Node().String())
+}
+
+func TestHostRule_Node(t *testing.T) {
+	t.Parallel()
+
+	r, err := rules.NewHostRule("127.0.0.1  localhost   local.example", testFilterListID)
+	require.NoError(t, err)
+
+	n := r.Node()
+	assert.Equal(t, &rules.HostRuleNode{
+		IP:        netip.MustParseAddr("127.0.0.1"),
+		Hostnames: []string{"localhost", "local.example"},
+	}, n)
+	assert.Equal(t, "127.0.0.1 localhost local.example", n.String())
+
+	n.Hostnames[0] = "changed.example"
+	assert.Equal(t, "localhost", r.Hostnames[0])
+}
@@ -0,0 +1,280 @@
+package rules
+
+import (
+	"slices"
+	"strings"
+)
+
+// BadfilterIndex contains the $badfilter rules and applies them to the other
+// network rules before those are added to the lookup tables.  Besides the
+// rules negated completely, as described in [NetworkRule.negatesBadfilter], it
+// supports disabling a rule only for some of its permitted domains:
+//
+//   - a $badfilter rule with the same pattern and modifiers, but only some of
+//     the permitted domains, removes these domains from the rule, for example
+//     "||example.org^$domain=a.com,badfilter" turns
+//     "||example.org^$domain=a.com|b.com" into "||example.org^$domain=b.com";
+//
+//   - a wildcard $badfilter rule, which has no pattern and no modifiers besides
+//     $domain, removes its domains from all rules of the same kind, blocking or
+//     exception, for example "*$domain=a.com,badfilter" turns any
+//     "...$domain=a.com|b.com" into "...$domain=b.com".
+//
+// A rule, all permitted domains of which have been removed, is disabled
+// completely.  A BadfilterIndex must not be modified concurrently with
+// applying.
+type BadfilterIndex struct {
+	// byPattern are the non-wildcard $badfilter rules by their patterns.
+	byPattern map[string][]*NetworkRule
+
+	// wildcards are the wildcard $badfilter rules.
+	wildcards []*NetworkRule
+}
+
+// NewBadfilterIndex returns a new empty *BadfilterIndex.
+func NewBadfilterIndex() (idx *BadfilterIndex) {
+	return &BadfilterIndex{
+		byPattern: map[string][]*NetworkRule{},
+	}
+}
+
+// BadfilterDisabled describes a rule disabled, completely or partially, by the
+// $badfilter rules.
+type BadfilterDisabled struct {
+	// Rule is the original rule.
+	Rule *NetworkRule
+
+	// Remaining is the rule with the domains, which have not been disabled.
+	// It is nil if Rule has been disabled completely.
+	Remaining *NetworkRule
+
+	// Badfilters are the $badfilter rules which have disabled Rule.
+	Badfilters []*NetworkRule
+}
+
+// Add adds f to the index.  ok is false if f is not a $badfilter rule.
+func (idx *BadfilterIndex) Add(f *NetworkRule) (ok bool) {
+	if !f.IsOptionEnabled(OptionBadfilter) {
+		return false
+	}
+
+	if f.isWildcardBadfilter() {
+		idx.wildcards = append(idx.wildcards, f)
+	} else {
+		idx.byPattern[f.pattern] = append(idx.byPattern[f.pattern], f)
+	}
+
+	return true
+}
+
+// Len returns the number of $badfilter rules in the index.
+func (idx *BadfilterIndex) Len() (n int) {
+	n = len(idx.wildcards)
+	for _, bs := range idx.byPattern {
+		n += len(bs)
+	}
+
+	return n
+}
+
+// Apply applies the $badfilter rules in the index to f.  res is the rule which
+// should be used instead of f, or nil if f has been disabled completely, or if
+// f is a $badfilter rule itself.  disabled is nil if f hasn't been affected by
+// any $badfilter rules.
+func (idx *BadfilterIndex) Apply(f *NetworkRule) (res *NetworkRule, disabled *BadfilterDisabled) {
+	if f.IsOptionEnabled(OptionBadfilter) {
+		return nil, nil
+	}
+
+	res = f
+	for _, b := range idx.byPattern[f.pattern] {
+		res, disabled = applyBadfilter(b, f, res, disabled)
+		if res == nil {
+			return nil, disabled
+		}
+	}
+
+	for _, b := range idx.wildcards {
+		res, disabled = applyBadfilter(b, f, res, disabled)
+		if res == nil {
+			return nil, disabled
+		}
+	}
+
+	return res, disabled
+}
+
+// applyBadfilter applies the $badfilter rule b to cur, which is either orig or
+// the rule derived from it by the previously applied $badfilter rules.  prev is
+// the description of the previous changes, if any.
+func applyBadfilter(
+	b *NetworkRule,
+	orig *NetworkRule,
+	cur *NetworkRule,
+	prev *BadfilterDisabled,
+) (res *NetworkRule, disabled *BadfilterDisabled) {
+	remaining, ok := b.remainingDomains(cur)
+	if !ok {
+		return cur, prev
+	}
+
+	disabled = prev
+	if disabled == nil {
+		disabled = &BadfilterDisabled{
+			Rule: orig,
+		}
+	}
+
+	disabled.Badfilters = append(disabled.Badfilters, b)
+	if len(remaining) > 0 {
+		res = cur.withPermittedDomains(remaining)
+	}
+
+	disabled.Remaining = res
+
+	return res, disabled
+}
+
+// remainingDomains returns the permitted domains of r which remain after
+// applying the $badfilter rule f.  ok is false if f doesn't affect r.  If r is
+// disabled completely, remaining is empty.
+func (f *NetworkRule) remainingDomains(r *NetworkRule) (remaining []string, ok bool) {
+	if f.negatesBadfilter(r) {
+		return nil, true
+	}
+
+	if !f.disablesDomainsOf(r) {
+		return nil, false
+	}
+
+	rDomains := r.getRestrictions().permittedDomains
+	for _, d := range rDomains {
+		if !f.permitsDomain(d) {
+			remaining = append(remaining, d)
+		}
+	}
+
+	return remaining, true
+}
+
+// disablesDomainsOf returns true if the $badfilter rule f disables some of the
+// permitted domains of r.
+func (f *NetworkRule) disablesDomainsOf(r *NetworkRule) (ok bool) {
+	fDomains := f.getRestrictions().permittedDomains
+	rDomains := r.getRestrictions().permittedDomains
+	switch {
+	case len(fDomains) == 0, len(rDomains) == 0:
+		return false
+	case f.isWildcardBadfilter():
+		return f.Whitelist == r.Whitelist && slices.ContainsFunc(rDomains, f.permitsDomain)
+	default:
+		return f.negatesBadfilterExceptDomains(r) && isSubset(fDomains, rDomains)
+	}
+}
+
+// permitsDomain returns true if d is one of the permitted domains of f.
+func (f *NetworkRule) permitsDomain(d string) (ok bool) {
+	return slices.Contains(f.getRestrictions().permittedDomains, d)
+}
+
+// isSubset returns true if all elements of sub are in set.
+func isSubset(sub, set []string) (ok bool) {
+	for _, s := range sub {
+		if !slices.Contains(set, s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isWildcardBadfilter returns true if f is a $badfilter rule which matches any
+// URL and only has the permitted domains besides that.
+func (f *NetworkRule) isWildcardBadfilter() (ok bool) {
+	if f.pattern != MaskAnyCharacter && f.pattern != "" {
+		return false
+	}
+
+	rs := f.getRestrictions()
+
+	return f.enabledOptions == OptionBadfilter &&
+		f.disabledOptions == 0 &&
+		f.permittedRequestTypes == 0 &&
+		f.restrictedRequestTypes == 0 &&
+		len(rs.permittedDomains) > 0 &&
+		len(rs.restrictedDomains) == 0 &&
+		len(rs.permittedToDomains) == 0 &&
+		len(rs.restrictedToDomains) == 0 &&
+		rs.header == nil &&
+		rs.permittedMethods == 0 &&
+		rs.restrictedMethods == 0 &&
+		len(rs.permittedDNSTypes) == 0 &&
+		len(rs.restrictedDNSTypes) == 0 &&
+		len(rs.permittedClientTags) == 0 &&
+		len(rs.restrictedClientTags) == 0 &&
+		rs.permittedClients.Len() == 0 &&
+		rs.restrictedClients.Len() == 0
+}
+
+// withPermittedDomains returns a copy of f with the permitted domains replaced
+// by domains, which must not be empty.  The text of the copy is changed
+// accordingly.
+func (f *NetworkRule) withPermittedDomains(domains []string) (r *NetworkRule) {
+	// Parse the rule anew, since it's the simplest way to get a deep copy
+	// without the compiled regular expression.  The text only differs from the
+	// one of f, which has already been parsed, in the permitted domains.
+	r, _ = NewNetworkRule(f.textWithPermittedDomains(domains), f.FilterListID)
+
+	return r
+}
+
+// textWithPermittedDomains returns the text of f with the permitted domains of
+// its $domain modifier replaced by domains.  The restricted domains and the
+// other modifiers are kept as is.
+func (f *NetworkRule) textWithPermittedDomains(domains []string) (text string) {
+	idx := optionsDelimiterIndex(f.RuleText)
+	if idx == -1 {
+		return f.RuleText
+	}
+
+	b := &strings.Builder{}
+	b.WriteString(f.RuleText[:idx+1])
+
+	options := f.RuleText[idx+1:]
+	start := 0
+	for i := 0; i <= len(options); i++ {
+		if i < len(options) && (options[i] != ',' || i > 0 && options[i-1] == escapeCharacter) {
+			continue
+		}
+
+		if start > 0 {
+			b.WriteByte(',')
+		}
+
+		writeOptionWithPermittedDomains(b, options[start:i], domains)
+		start = i + 1
+	}
+
+	return b.String()
+}
+
+// writeOptionWithPermittedDomains writes the modifier text opt to b.  If it's
+// the $domain modifier, its permitted domains are replaced by domains.
+func writeOptionWithPermittedDomains(b *strings.Builder, opt string, domains []string) {
+	name, value, ok := strings.Cut(opt, "=")
+	if !ok || canonicalOptionName(name) != "domain" {
+		b.WriteString(opt)
+
+		return
+	}
+
+	b.WriteString(name)
+	b.WriteByte('=')
+	b.WriteString(strings.Join(domains, "|"))
+	for d := range strings.SplitSeq(value, "|") {
+		if strings.HasPrefix(d, "~") {
+			b.WriteByte('|')
+			b.WriteString(d)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBadfilterIndex_Apply(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		rule       string
+		badfilters []string
+		// wantRemaining is the text of the remaining rule.  It is empty if the
+		// rule is disabled completely.
+		wantRemaining string
+		wantDisabled  bool
+	}{{
+		name:          "no_badfilters",
+		rule:          "||example.org^",
+		badfilters:    nil,
+		wantRemaining: "||example.org^",
+		wantDisabled:  false,
+	}, {
+		name:          "exact",
+		rule:          "||example.org^",
+		badfilters:    []string{"||example.org^$badfilter"},
+		wantRemaining: "",
+		wantDisabled:  true,
+	}, {
+		name:          "other_pattern",
+		rule:          "||example.org^",
+		badfilters:    []string{"||example.com^$badfilter"},
+		wantRemaining: "||example.org^",
+		wantDisabled:  false,
+	}, {
+		name:          "other_options",
+		rule:          "||example.org^$image",
+		badfilters:    []string{"||example.org^$badfilter"},
+		wantRemaining: "||example.org^$image",
+		wantDisabled:  false,
+	}, {
+		name:          "exception",
+		rule:          "@@||example.org^",
+		badfilters:    []string{"||example.org^$badfilter"},
+		wantRemaining: "@@||example.org^",
+		wantDisabled:  false,
+	}, {
+		name:          "partial_domains",
+		rule:          "||example.org^$domain=a.com|b.com|c.com",
+		badfilters:    []string{"||example.org^$domain=a.com|c.com,badfilter"},
+		wantRemaining: "||example.org^$domain=b.com",
+		wantDisabled:  true,
+	}, {
+		name:          "partial_domains_all",
+		rule:          "||example.org^$domain=a.com|b.com",
+		badfilters:    []string{"||example.org^$domain=b.com|a.com,badfilter"},
+		wantRemaining: "",
+		wantDisabled:  true,
+	}, {
+		name:          "partial_domains_several",
+		rule:          "||example.org^$domain=a.com|b.com",
+		badfilters:    []string{"||example.org^$domain=a.com,badfilter", "||example.org^$domain=b.com,badfilter"},
+		wantRemaining: "",
+		wantDisabled:  true,
+	}, {
+		name:          "partial_domains_not_subset",
+		rule:          "||example.org^$domain=a.com|b.com",
+		badfilters:    []string{"||example.org^$domain=a.com|d.com,badfilter"},
+		wantRemaining: "||example.org^$domain=a.com|b.com",
+		wantDisabled:  false,
+	}, {
+		name:          "wildcard",
+		rule:          "||example.org^$image,domain=a.com|b.com",
+		badfilters:    []string{"*$domain=a.com|d.com,badfilter"},
+		wantRemaining: "||example.org^$image,domain=b.com",
+		wantDisabled:  true,
+	}, {
+		name:          "wildcard_restricted",
+		rule:          "||example.org^$from=a.com|b.com|~c.b.com,image",
+		badfilters:    []string{"*$domain=a.com,badfilter"},
+		wantRemaining: "||example.org^$from=b.com|~c.b.com,image",
+		wantDisabled:  true,
+	}, {
+		name:          "wildcard_escaped",
+		rule:          `/ads/$domain=a.com|b.com,urltransform=/a\,b\$/c/`,
+		badfilters:    []string{"*$domain=b.com,badfilter"},
+		wantRemaining: `/ads/$domain=a.com,urltransform=/a\,b\$/c/`,
+		wantDisabled:  true,
+	}, {
+		name:          "wildcard_all",
+		rule:          "/ads/$domain=a.com",
+		badfilters:    []string{"*$domain=a.com,badfilter"},
+		wantRemaining: "",
+		wantDisabled:  true,
+	}, {
+		name:          "wildcard_no_domains",
+		rule:          "||example.org^",
+		badfilters:    []string{"*$domain=a.com,badfilter"},
+		wantRemaining: "||example.org^",
+		wantDisabled:  false,
+	}, {
+		name:          "wildcard_exception",
+		rule:          "@@||example.org^$domain=a.com",
+		badfilters:    []string{"*$domain=a.com,badfilter"},
+		wantRemaining: "@@||example.org^$domain=a.com",
+		wantDisabled:  false,
+	}, {
+		name:          "wildcard_exception_success",
+		rule:          "@@||example.org^$domain=a.com|b.com",
+		badfilters:    []string{"@@*$domain=a.com,badfilter"},
+		wantRemaining: "@@||example.org^$domain=b.com",
+		wantDisabled:  true,
+	}, {
+		name:          "not_wildcard",
+		rule:          "||example.org^$domain=a.com|b.com",
+		badfilters:    []string{"*$image,domain=a.com,badfilter"},
+		wantRemaining: "||example.org^$domain=a.com|b.com",
+		wantDisabled:  false,
+	}, {
+		name:          "badfilter",
+		rule:          "||example.org^$badfilter",
+		badfilters:    []string{"||example.org^$badfilter"},
+		wantRemaining: "",
+		wantDisabled:  false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			idx := rules.NewBadfilterIndex()
+			for _, text := range tc.badfilters {
+				b, err := rules.NewNetworkRule(text, -1)
+				require.NoError(t, err)
+
+				require.True(t, idx.Add(b))
+			}
+
+			require.Equal(t, len(tc.badfilters), idx.Len())
+
+			r, err := rules.NewNetworkRule(tc.rule, -1)
+			require.NoError(t, err)
+
+			res, disabled := idx.Apply(r)
+			if !tc.wantDisabled {
+				assert.Nil(t, disabled)
+			} else {
+				require.NotNil(t, disabled)
+
+				assert.Same(t, r, disabled.Rule)
+				assert.Same(t, res, disabled.Remaining)
+				assert.NotEmpty(t, disabled.Badfilters)
+			}
+
+			if tc.wantRemaining == "" {
+				assert.Nil(t, res)
+
+				return
+			}
+
+			require.NotNil(t, res)
+
+			want, err := rules.NewNetworkRule(tc.wantRemaining, -1)
+			require.NoError(t, err)
+
+			assert.Equal(t, want.GetPermittedDomains(), res.GetPermittedDomains())
+			assert.Equal(t, tc.wantRemaining, res.Text())
+		})
+	}
+}
+
+func TestBadfilterIndex_Add(t *testing.T) {
+	t.Parallel()
+
+	r, err := rules.NewNetworkRule("||example.org^", -1)
+	require.NoError(t, err)
+
+	idx := rules.NewBadfilterIndex()
+	assert.False(t, idx.Add(r))
+	assert.Zero(t, idx.Len())
+}
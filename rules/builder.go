@@ -0,0 +1,362 @@
+package rules
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/netutil"
+	"github.com/miekg/dns"
+)
+
+// NetworkRuleBuilder constructs network rules from typed fields.  The zero
+// values of the fields mean that the corresponding modifiers are not used.
+type NetworkRuleBuilder struct {
+	// DNSRewrite is the value of the $dnsrewrite modifier.  An empty non-nil
+	// value produces the $dnsrewrite modifier without a value.
+	DNSRewrite *DNSRewrite
+
+	// Pattern is the basic rule pattern, for example "||example.org^".
+	Pattern string
+
+	// PermittedClients are the clients for the $client modifier.  These are
+	// IP addresses, CIDR prefixes, or client names, which are quoted and
+	// escaped as necessary.
+	PermittedClients []string
+
+	// RestrictedClients are the negated clients for the $client modifier.
+	RestrictedClients []string
+
+	// PermittedClientTags are the tags for the $ctag modifier.
+	PermittedClientTags []string
+
+	// RestrictedClientTags are the negated tags for the $ctag modifier.
+	RestrictedClientTags []string
+
+	// PermittedDomains are the domains for the $domain modifier.
+	PermittedDomains []string
+
+	// RestrictedDomains are the negated domains for the $domain modifier.
+	RestrictedDomains []string
+
+	// DenyAllowDomains are the domains for the $denyallow modifier.
+	DenyAllowDomains []string
+
+	// PermittedToDomains are the request domains for the $to modifier.
+	PermittedToDomains []string
+
+	// RestrictedToDomains are the negated request domains for the $to modifier.
+	RestrictedToDomains []string
+
+	// PermittedDNSTypes are the types for the $dnstype modifier.
+	PermittedDNSTypes []RRType
+
+	// RestrictedDNSTypes are the negated types for the $dnstype modifier.
+	RestrictedDNSTypes []RRType
+
+	// EnabledOptions are the options without values to enable.
+	EnabledOptions NetworkRuleOption
+
+	// DisabledOptions are the options to negate.  Only [OptionThirdParty],
+	// [OptionStrictThirdParty], and [OptionMatchCase] can be negated.
+	DisabledOptions NetworkRuleOption
+
+	// PermittedRequestTypes are the request types to limit the rule to.
+	// [TypeDocument] is not supported.
+	PermittedRequestTypes RequestType
+
+	// RestrictedRequestTypes are the request types to exclude.  [TypeDocument]
+	// is not supported.
+	RestrictedRequestTypes RequestType
+
+	// FilterListID is the identifier of the filter list of the rule.
+	FilterListID int
+
+	// Whitelist is true if the rule is an exception rule.
+	Whitelist bool
+}
+
+// optionNames are the modifier names of the options which have no values.
+var optionNames = map[NetworkRuleOption]string{
+	OptionThirdParty:       "third-party",
+	OptionStrictThirdParty: "strict-third-party",
+	OptionMatchCase:        "match-case",
+	OptionImportant:        "important",
+	OptionBadfilter:        "badfilter",
+	OptionElemhide:         "elemhide",
+	OptionGenerichide:      "generichide",
+	OptionSpecifichide:     "specifichide",
+	OptionGenericblock:     "genericblock",
+	OptionJsinject:         "jsinject",
+	OptionUrlblock:         "urlblock",
+	OptionContent:          "content",
+	OptionExtension:        "extension",
+	OptionStealth:          "stealth",
+	OptionEmpty:            "empty",
+	OptionMp4:              "mp4",
+	OptionPopup:            "popup",
+}
+
+// requestTypeNames are the modifier names of the request types.
+var requestTypeNames = map[RequestType]string{
+	TypeSubdocument:    "subdocument",
+	TypeScript:         "script",
+	TypeStylesheet:     "stylesheet",
+	TypeObject:         "object",
+	TypeImage:          "image",
+	TypeXmlhttprequest: "xmlhttprequest",
+	TypeMedia:          "media",
+	TypeFont:           "font",
+	TypeWebsocket:      "websocket",
+	TypePing:           "ping",
+	TypeOther:          "other",
+}
+
+// Build returns the text of the rule and the rule parsed from it.  It returns
+// an error if the fields cannot be represented in the rule syntax or if the
+// resulting rule is invalid.
+func (b *NetworkRuleBuilder) Build() (text string, r *NetworkRule, err error) {
+	n, err := b.node()
+	if err != nil {
+		return "", nil, err
+	}
+
+	text = n.String()
+	r, err = NewNetworkRule(text, b.FilterListID)
+	if err != nil {
+		return "", nil, fmt.Errorf("parsing %q: %w", text, err)
+	}
+
+	// Make sure that no value has been misinterpreted as a part of the
+	// pattern or a separate modifier.
+	pattern, options, _, _ := parseRuleText(text)
+	if pattern != n.Pattern || countOptions(options) != len(n.Modifiers) {
+		return "", nil, fmt.Errorf("rule %q is ambiguous", text)
+	}
+
+	return text, r, nil
+}
+
+// countOptions returns the number of the modifiers in options.
+func countOptions(options string) (n int) {
+	return len(splitWithEscapeCharacter(options, ',', '\\', false))
+}
+
+// node returns the structured view of the rule.
+func (b *NetworkRuleBuilder) node() (n *NetworkRuleNode, err error) {
+	n = &NetworkRuleNode{
+		Pattern:   b.Pattern,
+		Whitelist: b.Whitelist,
+	}
+
+	mods, err := optionModifiers(b.EnabledOptions, b.DisabledOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Modifiers = append(n.Modifiers, mods...)
+
+	mods, err = requestTypeModifiers(b.PermittedRequestTypes, b.RestrictedRequestTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Modifiers = append(n.Modifiers, mods...)
+
+	n.Modifiers = appendListModifier(n.Modifiers, "domain", b.PermittedDomains, b.RestrictedDomains)
+	n.Modifiers = appendListModifier(n.Modifiers, "denyallow", b.DenyAllowDomains, nil)
+	n.Modifiers = appendListModifier(n.Modifiers, "to", b.PermittedToDomains, b.RestrictedToDomains)
+	n.Modifiers = appendListModifier(
+		n.Modifiers,
+		"dnstype",
+		dnsTypeNames(b.PermittedDNSTypes),
+		dnsTypeNames(b.RestrictedDNSTypes),
+	)
+	n.Modifiers = appendListModifier(
+		n.Modifiers,
+		"ctag",
+		b.PermittedClientTags,
+		b.RestrictedClientTags,
+	)
+	n.Modifiers = appendListModifier(
+		n.Modifiers,
+		"client",
+		quoteClients(b.PermittedClients),
+		quoteClients(b.RestrictedClients),
+	)
+
+	if b.DNSRewrite != nil {
+		var v string
+		v, err = formatDNSRewrite(b.DNSRewrite)
+		if err != nil {
+			return nil, fmt.Errorf("dnsrewrite: %w", err)
+		}
+
+		n.Modifiers = append(n.Modifiers, &Modifier{Name: "dnsrewrite", Value: v})
+	}
+
+	return n, nil
+}
+
+// optionModifiers returns the modifiers for the enabled and disabled options.
+func optionModifiers(enabled, disabled NetworkRuleOption) (mods []*Modifier, err error) {
+	for _, o := range slices.Sorted(maps.Keys(optionNames)) {
+		if enabled&o == o {
+			mods = append(mods, &Modifier{Name: optionNames[o]})
+		}
+
+		if disabled&o == o {
+			if o != OptionThirdParty && o != OptionStrictThirdParty && o != OptionMatchCase {
+				return nil, fmt.Errorf("option %q cannot be disabled", optionNames[o])
+			}
+
+			mods = append(mods, &Modifier{Name: optionNames[o], Negated: true})
+		}
+
+		enabled &^= o
+		disabled &^= o
+	}
+
+	if enabled|disabled != 0 {
+		return nil, fmt.Errorf("options %#x require values or are unknown", enabled|disabled)
+	}
+
+	return mods, nil
+}
+
+// requestTypeModifiers returns the modifiers for the permitted and restricted
+// request types.
+func requestTypeModifiers(permitted, restricted RequestType) (mods []*Modifier, err error) {
+	for _, t := range slices.Sorted(maps.Keys(requestTypeNames)) {
+		if permitted&t == t {
+			mods = append(mods, &Modifier{Name: requestTypeNames[t]})
+		}
+
+		if restricted&t == t {
+			mods = append(mods, &Modifier{Name: requestTypeNames[t], Negated: true})
+		}
+
+		permitted &^= t
+		restricted &^= t
+	}
+
+	if permitted|restricted != 0 {
+		return nil, fmt.Errorf("request types %#x are not supported", permitted|restricted)
+	}
+
+	return mods, nil
+}
+
+// appendListModifier appends the list modifier with the name and the given
+// values to mods, unless both lists are empty.
+func appendListModifier(mods []*Modifier, name string, permitted, restricted []string) (res []*Modifier) {
+	if len(permitted) == 0 && len(restricted) == 0 {
+		return mods
+	}
+
+	m := &Modifier{
+		Name: name,
+	}
+
+	for _, v := range permitted {
+		m.Values = append(m.Values, &ModifierValue{Value: v})
+	}
+
+	for _, v := range restricted {
+		m.Values = append(m.Values, &ModifierValue{Value: v, Negated: true})
+	}
+
+	vals := make([]string, 0, len(m.Values))
+	for _, v := range m.Values {
+		vals = append(vals, v.String())
+	}
+
+	m.Value = strings.Join(vals, "|")
+
+	return append(mods, m)
+}
+
+// dnsTypeNames returns the names of the DNS types.
+func dnsTypeNames(types []RRType) (names []string) {
+	for _, t := range types {
+		names = append(names, dns.Type(t).String())
+	}
+
+	return names
+}
+
+// quoteClients returns the clients quoted and escaped as expected by
+// [loadClients].  IP addresses and CIDR prefixes are not quoted.
+func quoteClients(clients []string) (quoted []string) {
+	for _, c := range clients {
+		if netutil.IsValidIPString(c) {
+			quoted = append(quoted, c)
+
+			continue
+		} else if _, err := netip.ParsePrefix(c); err == nil {
+			quoted = append(quoted, c)
+
+			continue
+		}
+
+		c = strings.NewReplacer(`'`, `\'`, "|", `\|`).Replace(c)
+		quoted = append(quoted, "'"+c+"'")
+	}
+
+	return quoted
+}
+
+// formatDNSRewrite returns the value of the $dnsrewrite modifier for rw as
+// expected by [loadDNSRewrite].
+func formatDNSRewrite(rw *DNSRewrite) (v string, err error) {
+	switch {
+	case *rw == DNSRewrite{}:
+		return "", nil
+	case rw.NewCNAME != "":
+		return rw.NewCNAME, nil
+	case rw.RCode != dns.RcodeSuccess, rw.RRType == 0:
+		rcode, ok := dns.RcodeToString[rw.RCode]
+		if !ok {
+			return "", fmt.Errorf("rcode: %w: %d", errors.ErrBadEnumValue, rw.RCode)
+		}
+
+		return rcode + ";;", nil
+	}
+
+	val, err := formatRRValue(rw.Value)
+	if err != nil {
+		return "", err
+	}
+
+	return "NOERROR;" + dns.Type(rw.RRType).String() + ";" + val, nil
+}
+
+// formatRRValue returns the text of the resource record value v as expected by
+// the corresponding [dnsRewriteRRHandler].
+func formatRRValue(v RRValue) (s string, err error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case netip.Addr:
+		return v.String(), nil
+	case string:
+		return v, nil
+	case *DNSMX:
+		return strconv.FormatUint(uint64(v.Preference), 10) + " " + v.Exchange, nil
+	case *DNSSRV:
+		return fmt.Sprintf("%d %d %d %s", v.Priority, v.Weight, v.Port, v.Target), nil
+	case *DNSSVCB:
+		parts := []string{strconv.FormatUint(uint64(v.Priority), 10), v.Target}
+		for _, k := range slices.Sorted(maps.Keys(v.Params)) {
+			parts = append(parts, k+"="+v.Params[k])
+		}
+
+		return strings.Join(parts, " "), nil
+	default:
+		return "", fmt.Errorf("value: %w: %T", errors.ErrBadEnumValue, v)
+	}
+}
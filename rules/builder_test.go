@@ -0,0 +1,230 @@
+package rules_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkRuleBuilder_Build(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		builder *rules.NetworkRuleBuilder
+		name    string
+		want    string
+	}{{
+		builder: &rules.NetworkRuleBuilder{
+			Pattern: "||example.org^",
+		},
+		name: "pattern",
+		want: "||example.org^",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:           "||example.org^",
+			PermittedClients:  []string{"1.2.3.4", "Laptop"},
+			PermittedDNSTypes: []rules.RRType{dns.TypeAAAA},
+			EnabledOptions:    rules.OptionImportant,
+		},
+		name: "dashboard",
+		want: "||example.org^$important,dnstype=AAAA,client=1.2.3.4|'Laptop'",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:           "||example.org^",
+			PermittedClients:  []string{"192.168.0.0/16"},
+			RestrictedClients: []string{"Frank's laptop, old|new"},
+		},
+		name: "client_escaping",
+		want: `||example.org^$client=192.168.0.0/16|~'Frank\'s laptop\, old\|new'`,
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:           "||example.org^",
+			PermittedClients:  []string{"a$b"},
+			RestrictedClients: []string{"c$d"},
+		},
+		name: "client_dollar",
+		want: `||example.org^$client='a\$b'|~'c\$d'`,
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:                "||example.org^",
+			PermittedDomains:       []string{"a.example"},
+			RestrictedDomains:      []string{"b.a.example"},
+			PermittedRequestTypes:  rules.TypeScript | rules.TypeImage,
+			DisabledOptions:        rules.OptionThirdParty,
+			RestrictedClientTags:   []string{"device_pc"},
+			RestrictedRequestTypes: 0,
+		},
+		name: "domains_and_types",
+		want: "||example.org^$~third-party,script,image,domain=a.example|~b.a.example,ctag=~device_pc",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:          "*",
+			DenyAllowDomains: []string{"a.example", "b.example"},
+		},
+		name: "denyallow",
+		want: "*$denyallow=a.example|b.example",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:             "/banner/",
+			PermittedToDomains:  []string{"a.example"},
+			RestrictedToDomains: []string{"b.a.example"},
+		},
+		name: "to",
+		want: "/banner/$to=a.example|~b.a.example",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:         "||example.org^",
+			DisabledOptions: rules.OptionStrictThirdParty,
+		},
+		name: "strict_first_party",
+		want: "||example.org^$~strict-third-party",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:    "||example.org^",
+			DNSRewrite: &rules.DNSRewrite{RCode: dns.RcodeNameError},
+		},
+		name: "dnsrewrite_rcode",
+		want: "||example.org^$dnsrewrite=NXDOMAIN;;",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern: "||example.org^",
+			DNSRewrite: &rules.DNSRewrite{
+				RCode:  dns.RcodeSuccess,
+				RRType: dns.TypeA,
+				Value:  netip.MustParseAddr("1.2.3.4"),
+			},
+		},
+		name: "dnsrewrite_a",
+		want: "||example.org^$dnsrewrite=NOERROR;A;1.2.3.4",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern: "||example.org^",
+			DNSRewrite: &rules.DNSRewrite{
+				RCode:  dns.RcodeSuccess,
+				RRType: dns.TypeTXT,
+				Value:  "a,b$c",
+			},
+		},
+		name: "dnsrewrite_txt",
+		want: `||example.org^$dnsrewrite=NOERROR;TXT;a\,b\$c`,
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern: "||example.org^",
+			DNSRewrite: &rules.DNSRewrite{
+				RCode:  dns.RcodeSuccess,
+				RRType: dns.TypeHTTPS,
+				Value: &rules.DNSSVCB{
+					Params:   map[string]string{"alpn": "h2", "ech": "abc"},
+					Target:   "svc.example",
+					Priority: 1,
+				},
+			},
+		},
+		name: "dnsrewrite_https",
+		want: "||example.org^$dnsrewrite=NOERROR;HTTPS;1 svc.example alpn=h2 ech=abc",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:    "||example.org^",
+			DNSRewrite: &rules.DNSRewrite{},
+			Whitelist:  true,
+		},
+		name: "dnsrewrite_exception",
+		want: "@@||example.org^$dnsrewrite",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.builder.FilterListID = testFilterListID
+			text, r, err := tc.builder.Build()
+			require.NoError(t, err)
+			require.NotNil(t, r)
+
+			assert.Equal(t, tc.want, text)
+			assert.Equal(t, text, r.RuleText)
+			assert.Equal(t, testFilterListID, r.FilterListID)
+			assert.Equal(t, tc.builder.DNSRewrite, r.DNSRewrite)
+		})
+	}
+}
+
+func TestNetworkRuleBuilder_Build_client(t *testing.T) {
+	t.Parallel()
+
+	const name = "Frank's laptop, old|new"
+
+	_, r, err := (&rules.NetworkRuleBuilder{
+		Pattern:          "||example.org^",
+		PermittedClients: []string{name},
+	}).Build()
+	require.NoError(t, err)
+
+	req := rules.NewRequestForHostname("example.org")
+	assert.False(t, r.Match(req))
+
+	req.ClientName = name
+	assert.True(t, r.Match(req))
+}
+
+func TestNetworkRuleBuilder_Build_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		builder *rules.NetworkRuleBuilder
+		name    string
+	}{{
+		builder: &rules.NetworkRuleBuilder{
+			Pattern: "",
+		},
+		name: "too_wide",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:        "||example.org^",
+			EnabledOptions: rules.OptionElemhide,
+		},
+		name: "blacklist_elemhide",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:         "||example.org^",
+			DisabledOptions: rules.OptionImportant,
+		},
+		name: "disabled_important",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:        "||example.org^",
+			EnabledOptions: rules.OptionRedirect,
+		},
+		name: "option_with_value",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:               "||example.org^",
+			PermittedRequestTypes: rules.TypeDocument,
+		},
+		name: "document",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern:          "||example.org^",
+			PermittedDomains: []string{"bad domain"},
+		},
+		name: "bad_domain",
+	}, {
+		builder: &rules.NetworkRuleBuilder{
+			Pattern: "||example.org^$important",
+		},
+		name: "ambiguous",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := tc.builder.Build()
+			assert.Error(t, err)
+		})
+	}
+}
@@ -73,6 +73,25 @@ func (c *clients) add(client string) {
 	c.hosts = append(c.hosts, client)
 }
 
+// strings returns the clients as strings: the client names followed by the IP
+// addresses and the CIDR prefixes.  It returns nil if c is nil.
+func (c *clients) strings() (strs []string) {
+	if c == nil {
+		return nil
+	}
+
+	strs = slices.Clone(c.hosts)
+	for _, n := range c.nets {
+		if n.IsSingleIP() {
+			strs = append(strs, n.Addr().String())
+		} else {
+			strs = append(strs, n.String())
+		}
+	}
+
+	return strs
+}
+
 // newClients creates a new clients set from a list of clients.
 func newClients(clientStrs ...string) (c *clients) {
 	c = &clients{}
@@ -45,6 +45,12 @@ const (
 	markerHTMLException cosmeticRuleMarker = "$@$"
 )
 
+// scriptletPrefix is the prefix of the content of the JS rules, which run a
+// scriptlet instead of the arbitrary JavaScript code.
+//
+// See https://adguard.com/kb/general/ad-filtering/create-own-filters/#scriptlets.
+const scriptletPrefix = "//scriptlet("
+
 // contains all possible cosmetic rule markers
 var cosmeticRulesMarkers = []string{
 	string(markerElementHiding), string(markerElementHidingException),
@@ -134,12 +140,29 @@ func NewCosmeticRule(ruleText string, filterListID int) (*CosmeticRule, error) {
 		return nil, &RuleSyntaxError{msg: "empty rule content", ruleText: ruleText}
 	}
 
-	switch cosmeticRuleMarker(m) {
-	case markerElementHiding:
+	switch marker := cosmeticRuleMarker(m); marker {
+	case markerElementHiding, markerElementHidingException:
 		f.Type = CosmeticElementHiding
-	case markerElementHidingException:
+		f.Whitelist = marker == markerElementHidingException
+
+		err := f.loadUBOSyntax()
+		if err != nil {
+			return nil, &RuleSyntaxError{msg: err.Error(), ruleText: ruleText}
+		}
+	case markerElementHidingExtCSS, markerElementHidingExtCSSException:
 		f.Type = CosmeticElementHiding
-		f.Whitelist = true
+		f.Whitelist = marker == markerElementHidingExtCSSException
+		f.ExtendedCSS = true
+	case markerCSS, markerCSSException:
+		f.Type = CosmeticCSS
+		f.Whitelist = marker == markerCSSException
+	case markerCSSExtCSS, markerCSSExtCSSException:
+		f.Type = CosmeticCSS
+		f.Whitelist = marker == markerCSSExtCSSException
+		f.ExtendedCSS = true
+	case markerJS, markerJSException:
+		f.Type = CosmeticJS
+		f.Whitelist = marker == markerJSException
 	default:
 		return nil, ErrUnsupportedRule
 	}
@@ -180,31 +203,16 @@ func (f *CosmeticRule) IsGeneric() bool {
 	return len(f.permittedDomains) == 0
 }
 
+// IsScriptlet returns true if f is a JS rule, which runs a scriptlet instead of
+// the arbitrary JavaScript code.
+func (f *CosmeticRule) IsScriptlet() (ok bool) {
+	return f.Type == CosmeticJS && strings.HasPrefix(f.Content, scriptletPrefix)
+}
+
 // Match returns true if this rule can be used on the specified hostname
 func (f *CosmeticRule) Match(hostname string) bool {
 	// TODO: Improve hosts matching, start using a better approach (token-based maps)
-
-	if len(f.permittedDomains) == 0 && len(f.restrictedDomains) == 0 {
-		return true
-	}
-
-	if len(f.restrictedDomains) > 0 {
-		if isDomainOrSubdomainOfAny(hostname, f.restrictedDomains) {
-			// Domain or host is restricted
-			// i.e. $domain=~example.org
-			return false
-		}
-	}
-
-	if len(f.permittedDomains) > 0 {
-		if !isDomainOrSubdomainOfAny(hostname, f.permittedDomains) {
-			// Domain is not among permitted
-			// i.e. $domain=example.org and we're checking example.com
-			return false
-		}
-	}
-
-	return true
+	return matchDomains(hostname, f.permittedDomains, f.restrictedDomains)
 }
 
 // isCosmetic checks if this is a cosmetic filtering rule
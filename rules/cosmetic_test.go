@@ -36,6 +36,17 @@ func TestCosmeticRule_Match_wildcardTLD(t *testing.T) {
 	assert.False(t, f.Match("example.local.test"))
 }
 
+func TestCosmeticRule_Match_idn(t *testing.T) {
+	t.Parallel()
+
+	rule, err := rules.NewCosmeticRule(testIDNHostname+",~sub."+testIDNHostname+"##banner", 0)
+	require.NoError(t, err)
+
+	assert.True(t, rule.Match(testIDNHostnameASCII))
+	assert.False(t, rule.Match("sub."+testIDNHostnameASCII))
+	assert.False(t, rule.Match("example.org"))
+}
+
 func FuzzCosmeticRule_Match(f *testing.F) {
 	r, err := rules.NewCosmeticRule("example.*##banner", testFilterListID)
 	require.NoError(f, err)
@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// headerMatcher is the parsed value of the $header modifier.  It matches the
+// response headers of a request.
+//
+// See https://adguard.com/kb/general/ad-filtering/create-own-filters/#header-modifier.
+type headerMatcher struct {
+	// re matches the values of the header.  It is nil if the values are
+	// matched exactly.
+	re *regexp.Regexp
+
+	// value is the original value of the modifier.
+	value string
+
+	// name is the canonical name of the header.
+	name string
+
+	// headerValue is the exact value of the header.  If both headerValue and
+	// re are empty, the header matches with any value.
+	headerValue string
+}
+
+// newHeaderMatcher parses the value of the $header modifier, which has the
+// "name", "name:value", or "name:/regex/" form.
+func newHeaderMatcher(value string) (h *headerMatcher, err error) {
+	name, headerValue, hasValue := strings.Cut(value, ":")
+	if name == "" || strings.ContainsAny(name, " \t") || (hasValue && headerValue == "") {
+		return nil, fmt.Errorf("invalid $header value: %q", value)
+	}
+
+	h = &headerMatcher{
+		value: value,
+		name:  textproto.CanonicalMIMEHeaderKey(name),
+	}
+
+	re, ok, err := parseValueRegexp(headerValue)
+	if err != nil {
+		return nil, fmt.Errorf("$header: %w", err)
+	} else if ok {
+		h.re = re
+	} else {
+		h.headerValue = headerValue
+	}
+
+	return h, nil
+}
+
+// equal returns true if h and other are the same modifier values.  Either of
+// them may be nil.
+func (h *headerMatcher) equal(other *headerMatcher) (ok bool) {
+	if h == nil || other == nil {
+		return h == other
+	}
+
+	return h.value == other.value
+}
+
+// match returns true if headers contain the header matching h.
+func (h *headerMatcher) match(headers http.Header) (ok bool) {
+	for _, v := range headers.Values(h.name) {
+		switch {
+		case h.re != nil:
+			ok = h.re.MatchString(v)
+		case h.headerValue != "":
+			ok = v == h.headerValue
+		default:
+			ok = true
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+package rules_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkRule_Match_header(t *testing.T) {
+	t.Parallel()
+
+	headers := http.Header{
+		"Server":  {"AdServer/1.0"},
+		"X-Ad-Id": {"123", "456"},
+	}
+
+	testCases := []struct {
+		headers http.Header
+		name    string
+		rule    string
+		want    bool
+	}{{
+		headers: headers,
+		name:    "name",
+		rule:    "||example.org^$header=x-ad-id",
+		want:    true,
+	}, {
+		headers: headers,
+		name:    "name_missing",
+		rule:    "||example.org^$header=x-other",
+		want:    false,
+	}, {
+		headers: headers,
+		name:    "value",
+		rule:    "||example.org^$header=X-Ad-Id:456",
+		want:    true,
+	}, {
+		headers: headers,
+		name:    "value_other",
+		rule:    "||example.org^$header=x-ad-id:789",
+		want:    false,
+	}, {
+		headers: headers,
+		name:    "regexp",
+		rule:    "$header=server:/^adserver\\//i",
+		want:    true,
+	}, {
+		headers: headers,
+		name:    "regexp_other",
+		rule:    "$header=server:/^nginx/",
+		want:    false,
+	}, {
+		headers: nil,
+		name:    "no_headers",
+		rule:    "||example.org^$header=server",
+		want:    false,
+	}, {
+		headers: nil,
+		name:    "no_modifier",
+		rule:    "||example.org^",
+		want:    true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := rules.NewNetworkRule(tc.rule, -1)
+			require.NoError(t, err)
+
+			r := rules.NewRequest("https://example.org/ad.js", "", rules.TypeScript)
+			r.ResponseHeaders = tc.headers
+
+			assert.Equal(t, tc.want, f.Match(r))
+		})
+	}
+}
+
+func TestNetworkRule_header(t *testing.T) {
+	t.Parallel()
+
+	r, err := rules.NewNetworkRule("||example.org^$header=server", -1)
+	require.NoError(t, err)
+
+	assert.True(t, r.IsRestricted())
+	assert.False(t, r.IsHostLevelNetworkRule())
+
+	for _, text := range []string{
+		"||example.org^$header",
+		"||example.org^$header=:value",
+		"||example.org^$header=server:",
+		"||example.org^$header=x ad",
+		"||example.org^$header=server:/(/",
+	} {
+		_, err = rules.NewNetworkRule(text, -1)
+		assert.Error(t, err, text)
+	}
+}
@@ -14,9 +14,24 @@ func splitWithEscapeCharacter(str string, sep, escapeCharacter byte, preserveAll
 		return parts
 	}
 
+	if strings.IndexByte(str, escapeCharacter) == -1 {
+		// Most strings have no escape characters, so the parts are just the
+		// substrings of str, which don't take any additional memory.
+		for part := range strings.SplitSeq(str, string(sep)) {
+			if preserveAllTokens || part != "" {
+				parts = append(parts, part)
+			}
+		}
+
+		return parts
+	}
+
 	var sb strings.Builder
 	escaped := false
-	for i := range str {
+
+	// Iterate over bytes and not runes, since the separators are ASCII and
+	// multibyte characters must be kept intact.
+	for i := range len(str) {
 		c := str[i]
 
 		if c == escapeCharacter {
@@ -47,6 +62,25 @@ func splitWithEscapeCharacter(str string, sep, escapeCharacter byte, preserveAll
 	return parts
 }
 
+// matchDomains returns true if domain matches the domain lists of a modifier
+// like $domain or $to, that is if it's not among the restricted domains and,
+// unless permitted is empty, is among the permitted ones.  The subdomains of
+// the listed domains are considered to be among them.
+func matchDomains(domain string, permitted, restricted []string) (ok bool) {
+	if len(restricted) > 0 && isDomainOrSubdomainOfAny(domain, restricted) {
+		// Domain or host is restricted, i.e. $domain=~example.org.
+		return false
+	}
+
+	if len(permitted) > 0 && !isDomainOrSubdomainOfAny(domain, permitted) {
+		// Domain is not among permitted, i.e. $domain=example.org and we're
+		// checking example.com.
+		return false
+	}
+
+	return true
+}
+
 // isDomainOrSubdomainOfAny checks if "domain" is domain or subdomain or any of the "domains"
 func isDomainOrSubdomainOfAny(domain string, domains []string) bool {
 	for _, d := range domains {
@@ -31,4 +31,8 @@ func TestSplitWithEscapeCharacter(t *testing.T) {
 	assert.Equal(t, "\\opt2", parts[1])
 	assert.Equal(t, "", parts[2])
 	assert.Equal(t, "", parts[3])
+
+	str = "domain=пример.рф,image"
+	parts = splitWithEscapeCharacter(str, ',', '\\', false)
+	assert.Equal(t, []string{"domain=пример.рф", "image"}, parts)
 }
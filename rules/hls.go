@@ -0,0 +1,138 @@
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// hls is the parsed value of the $hls modifier.  It matches the URIs of the
+// media segments, which must be removed from the HLS playlists.
+//
+// See https://adguard.com/kb/general/ad-filtering/create-own-filters/#hls-modifier.
+type hls struct {
+	// re matches the URIs of the segments.  It is nil if the value isn't a
+	// regular expression.
+	re *regexp.Regexp
+
+	// modifierValue is the original value of the modifier.
+	modifierValue
+}
+
+// hlsHeader is the tag every HLS playlist starts with.
+const hlsHeader = "#EXTM3U"
+
+// hlsSegmentTags are the tags, which apply to the media segment or the variant
+// stream following them, and are removed together with it.
+var hlsSegmentTags = []string{
+	"#EXTINF",
+	"#EXT-X-BYTERANGE",
+	"#EXT-X-DISCONTINUITY",
+	"#EXT-X-PROGRAM-DATE-TIME",
+	"#EXT-X-STREAM-INF",
+}
+
+// newHLS parses the non-empty value of the $hls modifier, which is either a
+// substring of the segment URIs or a regular expression like "/regex/" or
+// "/regex/i".
+func newHLS(value string) (h *hls, err error) {
+	re, _, err := parseValueRegexp(value)
+	if err != nil {
+		return nil, fmt.Errorf("$hls: %w", err)
+	}
+
+	return &hls{
+		re:            re,
+		modifierValue: modifierValue{value: value},
+	}, nil
+}
+
+// matches returns true if the segment URI matches h.
+func (h *hls) matches(uri string) (ok bool) {
+	if h.re != nil {
+		return h.re.MatchString(uri)
+	}
+
+	return strings.Contains(uri, h.value)
+}
+
+// isHLSSegmentTag returns true if the line is one of [hlsSegmentTags].
+func isHLSSegmentTag(line string) (ok bool) {
+	for _, tag := range hlsSegmentTags {
+		if line == tag || strings.HasPrefix(line, tag+":") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterHLS returns the playlist without the media segments, the URIs of which
+// match the $hls rules.  The tags preceding a removed segment starting from the
+// first one of [hlsSegmentTags] are removed with it.  playlist is returned as
+// is if nothing is removed or if it isn't an HLS playlist.
+func filterHLS(playlist []byte, hlsRules []*NetworkRule) (res []byte) {
+	if len(hlsRules) == 0 || !bytes.HasPrefix(playlist, []byte(hlsHeader)) {
+		return playlist
+	}
+
+	res = make([]byte, 0, len(playlist))
+
+	// segStart is the start of the current segment within playlist, or -1 if
+	// there is no segment tag since the previous URI.
+	segStart := -1
+	removed := false
+	for start := 0; start < len(playlist); {
+		end := bytes.IndexByte(playlist[start:], '\n') + 1
+		if end == 0 {
+			end = len(playlist)
+		} else {
+			end += start
+		}
+
+		line := strings.TrimSpace(string(playlist[start:end]))
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			if segStart == -1 && isHLSSegmentTag(line) {
+				segStart = start
+			}
+
+			if segStart == -1 {
+				res = append(res, playlist[start:end]...)
+			}
+		case isHLSSegmentRemoved(line, hlsRules):
+			removed = true
+			segStart = -1
+		default:
+			if segStart == -1 {
+				segStart = start
+			}
+
+			res = append(res, playlist[segStart:end]...)
+			segStart = -1
+		}
+
+		start = end
+	}
+
+	if !removed {
+		return playlist
+	} else if segStart != -1 {
+		res = append(res, playlist[segStart:]...)
+	}
+
+	return res
+}
+
+// isHLSSegmentRemoved returns true if any of the $hls rules matches the segment
+// URI.
+func isHLSSegmentRemoved(uri string, hlsRules []*NetworkRule) (ok bool) {
+	for _, r := range hlsRules {
+		if r.getValues().hls.matches(uri) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,150 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchingResult_FilterHLS(t *testing.T) {
+	t.Parallel()
+
+	const playlist = "#EXTM3U\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXTINF:10,\n" +
+		"video/1.ts\n" +
+		"#EXT-X-DISCONTINUITY\n" +
+		"#EXTINF:5,\n" +
+		"ads/ad1.ts\n" +
+		"#EXT-X-DISCONTINUITY\n" +
+		"#EXTINF:10,\n" +
+		"video/2.ts\n" +
+		"#EXT-X-ENDLIST\n"
+
+	const filtered = "#EXTM3U\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXTINF:10,\n" +
+		"video/1.ts\n" +
+		"#EXT-X-DISCONTINUITY\n" +
+		"#EXTINF:10,\n" +
+		"video/2.ts\n" +
+		"#EXT-X-ENDLIST\n"
+
+	testCases := []struct {
+		name     string
+		playlist string
+		want     string
+		rules    []string
+	}{{
+		name:     "none",
+		playlist: playlist,
+		want:     playlist,
+		rules:    nil,
+	}, {
+		name:     "substring",
+		playlist: playlist,
+		want:     filtered,
+		rules:    []string{"||example.org^$hls=/ads/"},
+	}, {
+		name:     "regexp",
+		playlist: playlist,
+		want:     filtered,
+		rules:    []string{`||example.org^$hls=/AD\d\.ts/i`},
+	}, {
+		name:     "crlf",
+		playlist: "#EXTM3U\r\n#EXTINF:5,\r\nad.ts\r\n#EXTINF:10,\r\nvideo.ts\r\n",
+		want:     "#EXTM3U\r\n#EXTINF:10,\r\nvideo.ts\r\n",
+		rules:    []string{"||example.org^$hls=ad.ts"},
+	}, {
+		name:     "no_match",
+		playlist: playlist,
+		want:     playlist,
+		rules:    []string{"||example.org^$hls=banner"},
+	}, {
+		name:     "not_playlist",
+		playlist: "ads/ad1.ts\n",
+		want:     "ads/ad1.ts\n",
+		rules:    []string{"||example.org^$hls=/ads/"},
+	}, {
+		name:     "exception",
+		playlist: playlist,
+		want:     playlist,
+		rules: []string{
+			"||example.org^$hls=/ads/",
+			"@@||example.org^$hls=/ads/",
+		},
+	}, {
+		name:     "exception_all",
+		playlist: playlist,
+		want:     playlist,
+		rules: []string{
+			"||example.org^$hls=/ads/",
+			"@@||example.org^$hls",
+		},
+	}, {
+		name:     "badfilter",
+		playlist: playlist,
+		want:     playlist,
+		rules: []string{
+			"||example.org^$hls=/ads/",
+			"||example.org^$hls=/ads/,badfilter",
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := rules.NewMatchingResult(newNetworkRules(t, tc.rules), nil)
+			assert.Equal(t, tc.want, string(res.FilterHLS([]byte(tc.playlist))))
+			assert.Nil(t, res.GetBasicResult())
+		})
+	}
+}
+
+func TestNetworkRule_hls(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		in         string
+		wantErrMsg string
+	}{{
+		name:       "substring",
+		in:         "||example.org^$hls=ad.ts",
+		wantErrMsg: "",
+	}, {
+		name:       "regexp",
+		in:         "||example.org^$hls=/ads/",
+		wantErrMsg: "",
+	}, {
+		name:       "exception_all",
+		in:         "@@||example.org^$hls",
+		wantErrMsg: "",
+	}, {
+		name:       "empty",
+		in:         "||example.org^$hls",
+		wantErrMsg: "empty $hls value",
+	}, {
+		name:       "bad_regexp",
+		in:         "||example.org^$hls=/(ads/",
+		wantErrMsg: "$hls: compiling regexp \"/(ads/\": error parsing regexp: missing closing ): `(ads`",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.in, -1)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+			if err != nil {
+				return
+			}
+
+			assert.True(t, r.IsOptionEnabled(rules.OptionHLS))
+			assert.Nil(t, rules.GetDNSBasicRule([]*rules.NetworkRule{r}))
+		})
+	}
+}
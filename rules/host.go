@@ -60,6 +60,9 @@ func splitNextByWhitespace(ps *string) string {
 // NewHostRule parses the rule and creates a new HostRule instance
 // The format is:
 // IP_address canonical_hostname [aliases...]
+//
+// The hostnames are normalized to their lowercased ASCII form, so that the
+// internationalized domain names are in Punycode.
 func NewHostRule(ruleText string, filterListID int) (h *HostRule, err error) {
 	h = &HostRule{
 		RuleText:     ruleText,
@@ -74,6 +77,7 @@ func NewHostRule(ruleText string, filterListID int) (h *HostRule, err error) {
 
 	first := splitNextByWhitespace(&ruleText)
 	if len(ruleText) == 0 {
+		first = ufnet.NormalizeDomain(first)
 		if !ufnet.IsDomainName(first) {
 			return nil, &RuleSyntaxError{msg: "invalid syntax", ruleText: ruleText}
 		}
@@ -89,7 +93,7 @@ func NewHostRule(ruleText string, filterListID int) (h *HostRule, err error) {
 
 		for len(ruleText) != 0 {
 			host := splitNextByWhitespace(&ruleText)
-			h.Hostnames = append(h.Hostnames, host)
+			h.Hostnames = append(h.Hostnames, ufnet.NormalizeDomain(host))
 		}
 	}
 
@@ -87,6 +87,21 @@ func TestNewHostRule(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestNewHostRule_idn(t *testing.T) {
+	t.Parallel()
+
+	rule, err := rules.NewHostRule(testIDNHostname, testFilterListID)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{testIDNHostnameASCII}, rule.Hostnames)
+	assert.True(t, rule.Match(testIDNHostnameASCII))
+
+	rule, err = rules.NewHostRule("0.0.0.0 "+testIDNHostname+" WWW.Example.org", testFilterListID)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{testIDNHostnameASCII, "www.example.org"}, rule.Hostnames)
+}
+
 func TestHostRule_Match(t *testing.T) {
 	rule, err := rules.NewHostRule(
 		"127.0.1.1       thishost.mydomain.org  thishost",
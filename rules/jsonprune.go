@@ -0,0 +1,238 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// jsonPrune is the parsed value of the $jsonprune modifier.  It is the
+// JSONPath-like expression matching the properties and the array elements,
+// which must be removed from the JSON responses.
+//
+// See https://adguard.com/kb/general/ad-filtering/create-own-filters/#jsonprune-modifier.
+type jsonPrune struct {
+	// modifierValue is the original value of the modifier.
+	modifierValue
+
+	// path is the parsed expression.  It is empty for the exception rules,
+	// which disable all $jsonprune rules.
+	path []jsonPathSegment
+}
+
+// jsonPathSegment is a segment of a JSONPath-like expression.
+type jsonPathSegment struct {
+	// name is the name of the property or the index of the array element
+	// matched by the segment.  "*" matches all of them.
+	name string
+
+	// recursive is true if the segment matches the descendants at any depth,
+	// like "..name".
+	recursive bool
+}
+
+// jsonPathWildcard is the name of the segment matching all properties and
+// array elements.
+const jsonPathWildcard = "*"
+
+// newJSONPrune parses the non-empty value of the $jsonprune modifier.  The
+// supported syntax is the root "$" followed by the segments of the ".name",
+// "..name", "[N]", "['name']", and "*" forms.
+func newJSONPrune(value string) (jp *jsonPrune, err error) {
+	path, err := parseJSONPath(value)
+	if err != nil {
+		return nil, fmt.Errorf("$jsonprune: parsing %q: %w", value, err)
+	}
+
+	return &jsonPrune{
+		modifierValue: modifierValue{value: value},
+		path:          path,
+	}, nil
+}
+
+// parseJSONPath parses the JSONPath-like expression.  See [newJSONPrune].
+func parseJSONPath(expr string) (path []jsonPathSegment, err error) {
+	rest, ok := strings.CutPrefix(expr, "$")
+	if !ok {
+		return nil, errors.Error("no root")
+	}
+
+	for rest != "" {
+		var seg jsonPathSegment
+		seg, rest, err = parseJSONPathSegment(rest)
+		if err != nil {
+			return nil, err
+		}
+
+		path = append(path, seg)
+	}
+
+	if len(path) == 0 {
+		return nil, errors.Error("no segments")
+	}
+
+	return path, nil
+}
+
+// parseJSONPathSegment parses the first segment of the expression and returns
+// the rest of it.
+func parseJSONPathSegment(expr string) (seg jsonPathSegment, rest string, err error) {
+	switch {
+	case strings.HasPrefix(expr, ".."):
+		seg.recursive = true
+		expr = expr[2:]
+	case strings.HasPrefix(expr, "."):
+		expr = expr[1:]
+	case strings.HasPrefix(expr, "["):
+		// Go on.
+	default:
+		return seg, "", fmt.Errorf("unexpected %q", expr)
+	}
+
+	if name, ok := strings.CutPrefix(expr, "["); ok {
+		seg.name, rest, err = parseJSONPathBracket(name)
+
+		return seg, rest, err
+	}
+
+	end := strings.IndexAny(expr, ".[")
+	if end == -1 {
+		end = len(expr)
+	}
+
+	seg.name, rest = expr[:end], expr[end:]
+	if seg.name == "" {
+		return seg, "", errors.Error("empty name")
+	}
+
+	return seg, rest, nil
+}
+
+// parseJSONPathBracket parses the name of the segment in the brackets, which
+// may be "*", an index, or a quoted name, and returns the rest of the
+// expression after the closing bracket.
+func parseJSONPathBracket(expr string) (name, rest string, err error) {
+	name, rest, ok := strings.Cut(expr, "]")
+	if !ok {
+		return "", "", errors.Error("unclosed bracket")
+	}
+
+	if len(name) >= 2 && (name[0] == '\'' || name[0] == '"') && name[len(name)-1] == name[0] {
+		return name[1 : len(name)-1], rest, nil
+	}
+
+	if name == jsonPathWildcard {
+		return name, rest, nil
+	}
+
+	if _, err = strconv.ParseUint(name, 10, 0); err != nil {
+		return "", "", fmt.Errorf("bad index %q", name)
+	}
+
+	return name, rest, nil
+}
+
+// matches returns true if the segment matches the property or the array
+// element with the name.
+func (seg jsonPathSegment) matches(name string) (ok bool) {
+	return seg.name == jsonPathWildcard || seg.name == name
+}
+
+// prune removes the values matching path from node and returns the result,
+// which is only different from node for the arrays.  removed is true if any
+// values have been removed.
+func prune(node any, path []jsonPathSegment) (res any, removed bool) {
+	switch n := node.(type) {
+	case map[string]any:
+		for k, v := range n {
+			v, del, ok := pruneChild(v, path, k)
+			if del {
+				delete(n, k)
+			} else {
+				n[k] = v
+			}
+
+			removed = removed || del || ok
+		}
+
+		return n, removed
+	case []any:
+		elems := make([]any, 0, len(n))
+		for i, v := range n {
+			v, del, ok := pruneChild(v, path, strconv.Itoa(i))
+			if !del {
+				elems = append(elems, v)
+			}
+
+			removed = removed || del || ok
+		}
+
+		return elems, removed
+	default:
+		return node, false
+	}
+}
+
+// pruneChild prunes the child value v of a node, which has the name within the
+// node.  del is true if v itself must be removed, removed is true if any of its
+// descendants have been removed.
+func pruneChild(v any, path []jsonPathSegment, name string) (res any, del, removed bool) {
+	seg := path[0]
+	res = v
+	if seg.matches(name) {
+		if len(path) == 1 {
+			return nil, true, false
+		}
+
+		res, removed = prune(res, path[1:])
+	}
+
+	if seg.recursive {
+		var ok bool
+		res, ok = prune(res, path)
+		removed = removed || ok
+	}
+
+	return res, false, removed
+}
+
+// pruneJSON returns data with the values matching the $jsonprune rules
+// removed.  data is returned as is if nothing is removed.  Otherwise, the
+// properties of the objects are sorted by their names.
+func pruneJSON(data []byte, jsonPruneRules []*NetworkRule) (res []byte, err error) {
+	if len(jsonPruneRules) == 0 {
+		return data, nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v any
+	if err = d.Decode(&v); err != nil {
+		return data, fmt.Errorf("decoding json: %w", err)
+	}
+
+	var removed bool
+	for _, r := range jsonPruneRules {
+		var ok bool
+		v, ok = prune(v, r.getValues().jsonPrune.path)
+		removed = removed || ok
+	}
+
+	if !removed {
+		return data, nil
+	}
+
+	buf := &bytes.Buffer{}
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(false)
+	if err = e.Encode(v); err != nil {
+		return data, fmt.Errorf("encoding json: %w", err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
@@ -0,0 +1,174 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchingResult_PruneJSON(t *testing.T) {
+	t.Parallel()
+
+	const data = `{"ads":[1,2],"items":[{"id":1,"ad":true},{"id":2}],"meta":{"ads":{"x":1},"n":1.50}}`
+
+	testCases := []struct {
+		name  string
+		data  string
+		want  string
+		rules []string
+	}{{
+		name:  "none",
+		data:  data,
+		want:  data,
+		rules: nil,
+	}, {
+		name:  "property",
+		data:  data,
+		want:  `{"items":[{"ad":true,"id":1},{"id":2}],"meta":{"ads":{"x":1},"n":1.50}}`,
+		rules: []string{`||example.org^$jsonprune=\$.ads`},
+	}, {
+		name:  "recursive",
+		data:  data,
+		want:  `{"items":[{"ad":true,"id":1},{"id":2}],"meta":{"n":1.50}}`,
+		rules: []string{`||example.org^$jsonprune=\$..ads`},
+	}, {
+		name:  "wildcard",
+		data:  data,
+		want:  `{"ads":[1,2],"items":[{"id":1},{"id":2}],"meta":{"ads":{"x":1},"n":1.50}}`,
+		rules: []string{`||example.org^$jsonprune=\$.items.*.ad`},
+	}, {
+		name:  "index",
+		data:  data,
+		want:  `{"ads":[2],"items":[{"id":2}],"meta":{"ads":{"x":1},"n":1.50}}`,
+		rules: []string{`||example.org^$jsonprune=\$..[0]`},
+	}, {
+		name:  "quoted",
+		data:  `{"a.b":1,"c":2}`,
+		want:  `{"c":2}`,
+		rules: []string{`||example.org^$jsonprune=\$['a.b']`},
+	}, {
+		name:  "no_match",
+		data:  data,
+		want:  data,
+		rules: []string{`||example.org^$jsonprune=\$.banners`},
+	}, {
+		name: "several",
+		data: data,
+		want: `{"meta":{"ads":{"x":1},"n":1.50}}`,
+		rules: []string{
+			`||example.org^$jsonprune=\$.ads`,
+			`||example.org^$jsonprune=\$.items`,
+		},
+	}, {
+		name: "exception",
+		data: data,
+		want: `{"ads":[1,2],"meta":{"ads":{"x":1},"n":1.50}}`,
+		rules: []string{
+			`||example.org^$jsonprune=\$.ads`,
+			`||example.org^$jsonprune=\$.items`,
+			`@@||example.org^$jsonprune=\$.ads`,
+		},
+	}, {
+		name: "exception_all",
+		data: data,
+		want: data,
+		rules: []string{
+			`||example.org^$jsonprune=\$.ads`,
+			`@@||example.org^$jsonprune`,
+		},
+	}, {
+		name: "badfilter",
+		data: data,
+		want: data,
+		rules: []string{
+			`||example.org^$jsonprune=\$.ads`,
+			`||example.org^$jsonprune=\$.ads,badfilter`,
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := rules.NewMatchingResult(newNetworkRules(t, tc.rules), nil)
+			got, err := res.PruneJSON([]byte(tc.data))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, string(got))
+			assert.Nil(t, res.GetBasicResult())
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		res := rules.NewMatchingResult(newNetworkRules(t, []string{`||example.org^$jsonprune=\$.ads`}), nil)
+		got, err := res.PruneJSON([]byte("<html>"))
+		assert.Error(t, err)
+		assert.Equal(t, "<html>", string(got))
+	})
+}
+
+func TestNetworkRule_jsonPrune(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		in         string
+		wantErrMsg string
+	}{{
+		name:       "valid",
+		in:         `||example.org^$jsonprune=\$.ads`,
+		wantErrMsg: "",
+	}, {
+		name:       "exception_all",
+		in:         "@@||example.org^$jsonprune",
+		wantErrMsg: "",
+	}, {
+		name:       "empty",
+		in:         "||example.org^$jsonprune",
+		wantErrMsg: "empty $jsonprune value",
+	}, {
+		name:       "no_root",
+		in:         "||example.org^$jsonprune=ads",
+		wantErrMsg: `$jsonprune: parsing "ads": no root`,
+	}, {
+		name:       "root_only",
+		in:         `||example.org^$jsonprune=\$`,
+		wantErrMsg: `$jsonprune: parsing "$": no segments`,
+	}, {
+		name:       "empty_name",
+		in:         `||example.org^$jsonprune=\$.`,
+		wantErrMsg: `$jsonprune: parsing "$.": empty name`,
+	}, {
+		name:       "unclosed_bracket",
+		in:         `||example.org^$jsonprune=\$.ads[`,
+		wantErrMsg: `$jsonprune: parsing "$.ads[": unclosed bracket`,
+	}, {
+		name:       "bad_index",
+		in:         `||example.org^$jsonprune=\$.ads[x]`,
+		wantErrMsg: `$jsonprune: parsing "$.ads[x]": bad index "x"`,
+	}, {
+		name:       "filter",
+		in:         `||example.org^$jsonprune=\$.ads[?(@.id)]`,
+		wantErrMsg: `$jsonprune: parsing "$.ads[?(@.id)]": bad index "?(@.id)"`,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.in, -1)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+			if err != nil {
+				return
+			}
+
+			assert.True(t, r.IsOptionEnabled(rules.OptionJSONPrune))
+			assert.Nil(t, rules.GetDNSBasicRule([]*rules.NetworkRule{r}))
+		})
+	}
+}
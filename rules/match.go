@@ -23,6 +23,11 @@ const (
 	CosmeticOptionSourceCSS
 	CosmeticOptionSourceJS
 
+	// CosmeticOptionNoSpecificCSS - if specific elemhide and CSS rules are
+	// disabled by a $specifichide rule.  Unlike the other flags, it disables
+	// the rules, so that the options without it still enable them.
+	CosmeticOptionNoSpecificCSS
+
 	// CosmeticOptionAll - everything is enabled
 	CosmeticOptionAll = CosmeticOptionGenericCSS | CosmeticOptionCSS | CosmeticOptionJS
 
@@ -66,6 +71,41 @@ type MatchingResult struct {
 	// ReplaceRules -- a set of rules modifying the response's content
 	// See $replace modifier
 	ReplaceRules []*NetworkRule
+
+	// RemoveParamRules are the rules removing the query parameters from the
+	// request URL, which haven't been disabled by the exception rules.  See
+	// the $removeparam modifier and [MatchingResult.RemoveParams].
+	RemoveParamRules []*NetworkRule
+
+	// RemoveHeaderRules are the rules removing the headers from the request
+	// and the response, which haven't been disabled by the exception rules.
+	// See the $removeheader modifier and [MatchingResult.RemovedHeaders].
+	RemoveHeaderRules []*NetworkRule
+
+	// PermissionsRules are the rules adding the Permissions-Policy directives
+	// to the responses, which haven't been disabled by the exception rules.
+	// See the $permissions modifier and [MatchingResult.PermissionsPolicy].
+	PermissionsRules []*NetworkRule
+
+	// URLTransformRules are the rules modifying the request URL, which haven't
+	// been disabled by the exception rules.  See the $urltransform modifier
+	// and [MatchingResult.TransformURL].
+	URLTransformRules []*NetworkRule
+
+	// ReferrerPolicyRules are the rules setting the Referrer-Policy of the
+	// responses, which haven't been disabled by the exception rules.  See the
+	// $referrerpolicy modifier and [MatchingResult.ReferrerPolicy].
+	ReferrerPolicyRules []*NetworkRule
+
+	// JSONPruneRules are the rules removing the properties from the JSON
+	// responses, which haven't been disabled by the exception rules.  See the
+	// $jsonprune modifier and [MatchingResult.PruneJSON].
+	JSONPruneRules []*NetworkRule
+
+	// HLSRules are the rules removing the segments from the HLS playlists,
+	// which haven't been disabled by the exception rules.  See the $hls
+	// modifier and [MatchingResult.FilterHLS].
+	HLSRules []*NetworkRule
 }
 
 // NewMatchingResult creates an instance of the MatchingResult struct and fills it with the rules.
@@ -107,9 +147,26 @@ func NewMatchingResult(rules, sourceRules []*NetworkRule) (result *MatchingResul
 		}
 	}
 
+	var removeParamRules, removeHeaderRules, permissionsRules []*NetworkRule
+	var urlTransformRules, referrerPolicyRules, jsonPruneRules, hlsRules []*NetworkRule
+
 	// Iterate through the list of rules and fill the MatchingResult struct
 	for _, rule := range rules {
 		switch {
+		case rule.IsOptionEnabled(OptionRemoveParam):
+			removeParamRules = append(removeParamRules, rule)
+		case rule.IsOptionEnabled(OptionRemoveHeader):
+			removeHeaderRules = append(removeHeaderRules, rule)
+		case rule.IsOptionEnabled(OptionPermissions):
+			permissionsRules = append(permissionsRules, rule)
+		case rule.IsOptionEnabled(OptionURLTransform):
+			urlTransformRules = append(urlTransformRules, rule)
+		case rule.IsOptionEnabled(OptionReferrerPolicy):
+			referrerPolicyRules = append(referrerPolicyRules, rule)
+		case rule.IsOptionEnabled(OptionJSONPrune):
+			jsonPruneRules = append(jsonPruneRules, rule)
+		case rule.IsOptionEnabled(OptionHLS):
+			hlsRules = append(hlsRules, rule)
 		case rule.IsOptionEnabled(OptionCookie):
 			result.CookieRules = append(result.CookieRules, rule)
 		case rule.IsOptionEnabled(OptionReplace):
@@ -135,9 +192,111 @@ func NewMatchingResult(rules, sourceRules []*NetworkRule) (result *MatchingResul
 		}
 	}
 
+	// Like the blocking rules, the modifying ones are disabled by $urlblock.
+	if basicAllowed {
+		result.RemoveParamRules = applyExceptions(removeParamRules, OptionRemoveParam)
+		result.RemoveHeaderRules = applyExceptions(removeHeaderRules, OptionRemoveHeader)
+		result.PermissionsRules = applyExceptions(permissionsRules, OptionPermissions)
+		result.URLTransformRules = applyExceptions(urlTransformRules, OptionURLTransform)
+		result.ReferrerPolicyRules = applyExceptions(referrerPolicyRules, OptionReferrerPolicy)
+		result.JSONPruneRules = applyExceptions(jsonPruneRules, OptionJSONPrune)
+		result.HLSRules = applyExceptions(hlsRules, OptionHLS)
+	}
+
 	return result
 }
 
+// applyExceptions returns the non-exception rules from rs which are not
+// disabled by the exception rules from rs.  An exception rule disables the
+// rules with the same value of the modifier enabled by opt, or all of them if
+// its value is empty.  An $important rule can only be disabled by an
+// $important exception rule.
+func applyExceptions(rs []*NetworkRule, opt NetworkRuleOption) (res []*NetworkRule) {
+	var exceptions []*NetworkRule
+	for _, r := range rs {
+		if r.Whitelist {
+			exceptions = append(exceptions, r)
+		}
+	}
+
+	for _, r := range rs {
+		if !r.Whitelist && !isDisabledByExceptions(r, exceptions, opt) {
+			res = append(res, r)
+		}
+	}
+
+	return res
+}
+
+// isDisabledByExceptions returns true if any of the exception rules disables r.
+// See [applyExceptions].
+func isDisabledByExceptions(r *NetworkRule, exceptions []*NetworkRule, opt NetworkRuleOption) (ok bool) {
+	v := r.getValues().get(opt).getValue()
+	important := r.IsOptionEnabled(OptionImportant)
+	for _, e := range exceptions {
+		if important && !e.IsOptionEnabled(OptionImportant) {
+			continue
+		}
+
+		if ev := e.getValues().get(opt).getValue(); ev == "" || ev == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RemoveParams returns u with the query parameters removed by the $removeparam
+// rules of m.  u is returned as is if no parameters are removed.
+func (m *MatchingResult) RemoveParams(u string) (res string) {
+	return removeQueryParams(u, m.RemoveParamRules)
+}
+
+// RemovedHeaders returns the lowercased names of the headers, which must be
+// removed by the $removeheader rules of m from the request, if request is true,
+// or from the response.
+func (m *MatchingResult) RemovedHeaders(request bool) (names []string) {
+	return removedHeaders(m.RemoveHeaderRules, request)
+}
+
+// PermissionsPolicy returns the Permissions-Policy header value with the
+// directives of the $permissions rules of m.  It returns an empty string if
+// there are none.
+func (m *MatchingResult) PermissionsPolicy() (policy string) {
+	return permissionsPolicy(m.PermissionsRules)
+}
+
+// TransformURL returns u modified by the $urltransform rules of m.  u is
+// returned as is if there are none.
+func (m *MatchingResult) TransformURL(u string) (res string) {
+	return transformURL(u, m.URLTransformRules)
+}
+
+// ReferrerPolicy returns the Referrer-Policy header value of the
+// $referrerpolicy rule of m with the highest priority.  It returns an empty
+// string if there are none.
+func (m *MatchingResult) ReferrerPolicy() (policy string) {
+	r := referrerPolicyRule(m.ReferrerPolicyRules)
+	if r == nil {
+		return ""
+	}
+
+	return r.getValues().referrerPolicy.value
+}
+
+// PruneJSON returns data with the properties and the array elements removed by
+// the $jsonprune rules of m.  data is returned as is if nothing is removed.  err
+// is not nil if data isn't a valid JSON document.
+func (m *MatchingResult) PruneJSON(data []byte) (res []byte, err error) {
+	return pruneJSON(data, m.JSONPruneRules)
+}
+
+// FilterHLS returns the HLS playlist without the media segments removed by the
+// $hls rules of m.  playlist is returned as is if nothing is removed.
+func (m *MatchingResult) FilterHLS(playlist []byte) (res []byte) {
+	return filterHLS(playlist, m.HLSRules)
+}
+
 // GetDNSBasicRule returns a rule that should be applied to the DNS request.
 func GetDNSBasicRule(rules []*NetworkRule) (basicRule *NetworkRule) {
 	rules = removeBadfilterRules(rules)
@@ -151,7 +310,14 @@ func GetDNSBasicRule(rules []*NetworkRule) (basicRule *NetworkRule) {
 			return nil
 		case rule.IsOptionEnabled(OptionCookie) ||
 			rule.IsOptionEnabled(OptionCsp) ||
-			rule.IsOptionEnabled(OptionStealth):
+			rule.IsOptionEnabled(OptionStealth) ||
+			rule.IsOptionEnabled(OptionRemoveParam) ||
+			rule.IsOptionEnabled(OptionRemoveHeader) ||
+			rule.IsOptionEnabled(OptionPermissions) ||
+			rule.IsOptionEnabled(OptionURLTransform) ||
+			rule.IsOptionEnabled(OptionReferrerPolicy) ||
+			rule.IsOptionEnabled(OptionJSONPrune) ||
+			rule.IsOptionEnabled(OptionHLS):
 			// Skip rules with other options.
 			continue
 		default:
@@ -199,49 +365,60 @@ func (m *MatchingResult) GetCosmeticOption() CosmeticOption {
 	option := CosmeticOptionAll
 
 	if m.BasicRule.IsOptionEnabled(OptionElemhide) {
-		option = option ^ CosmeticOptionCSS
-		option = option ^ CosmeticOptionGenericCSS
+		option &^= CosmeticOptionCSS | CosmeticOptionGenericCSS
+	} else if m.BasicRule.IsOptionEnabled(OptionSpecifichide) {
+		option |= CosmeticOptionNoSpecificCSS
 	}
 
 	if m.BasicRule.IsOptionEnabled(OptionGenerichide) {
-		option = option ^ CosmeticOptionGenericCSS
+		option &^= CosmeticOptionGenericCSS
 	}
 
 	if m.BasicRule.IsOptionEnabled(OptionJsinject) {
-		option = option ^ CosmeticOptionJS
+		option &^= CosmeticOptionJS
 	}
 
 	return option
 }
 
-// removeBadfilterRules looks if there are any matching $badfilter rules and removes
-// matching bad filters from the array (see the $badfilter description for more info)
-func removeBadfilterRules(rules []*NetworkRule) []*NetworkRule {
-	var badfilterRules []*NetworkRule
+// removeBadfilterRules returns rules without the $badfilter rules and the rules
+// disabled by them completely.  The engines apply the $badfilter rules of their
+// storages beforehand, see [BadfilterIndex], so this only matters for the rules
+// added to them or matched in other ways.  Unlike the engines, it doesn't
+// disable the rules partially, since the request isn't known here.  It returns
+// the original slice if there are no $badfilter rules.
+func removeBadfilterRules(rules []*NetworkRule) (filtered []*NetworkRule) {
+	var badfilters []*NetworkRule
+	for _, r := range rules {
+		if r.IsOptionEnabled(OptionBadfilter) {
+			badfilters = append(badfilters, r)
+		}
+	}
 
-	for _, badfilter := range rules {
-		if badfilter.IsOptionEnabled(OptionBadfilter) {
-			// lazily create the badfilterRules array
-			if badfilterRules == nil {
-				badfilterRules = []*NetworkRule{}
-			}
-			badfilterRules = append(badfilterRules, badfilter)
+	if len(badfilters) == 0 {
+		return rules
+	}
+
+	filtered = make([]*NetworkRule, 0, len(rules)-len(badfilters))
+	for _, r := range rules {
+		if !r.IsOptionEnabled(OptionBadfilter) && !isDisabledByBadfilters(r, badfilters) {
+			filtered = append(filtered, r)
 		}
 	}
 
-	if len(badfilterRules) > 0 {
-		filteredRules := []*NetworkRule{}
-		for _, badfilter := range badfilterRules {
-			for _, rule := range rules {
-				if !badfilter.negatesBadfilter(rule) && !rule.IsOptionEnabled(OptionBadfilter) {
-					filteredRules = append(filteredRules, rule)
-				}
-			}
+	return filtered
+}
+
+// isDisabledByBadfilters returns true if any of the $badfilter rules disables r
+// completely.
+func isDisabledByBadfilters(r *NetworkRule, badfilters []*NetworkRule) (ok bool) {
+	for _, b := range badfilters {
+		if remaining, disables := b.remainingDomains(r); disables && len(remaining) == 0 {
+			return true
 		}
-		return filteredRules
 	}
 
-	return rules
+	return false
 }
 
 // removeDNSRewriteRules removes DNS rewrite rules from rules and
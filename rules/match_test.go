@@ -51,6 +51,30 @@ func TestGetCosmeticOption(t *testing.T) {
 	result = NewMatchingResult(rules, sourceRules)
 	assert.Equal(t, CosmeticOptionCSS|CosmeticOptionJS, result.GetCosmeticOption())
 
+	// $specifichide
+	rules = testNewNetworkRules(t, []string{
+		"@@||example.org^$specifichide",
+	}, 0)
+	sourceRules = []*NetworkRule{}
+	result = NewMatchingResult(rules, sourceRules)
+	assert.Equal(t, CosmeticOptionAll|CosmeticOptionNoSpecificCSS, result.GetCosmeticOption())
+
+	// $generichide and $specifichide
+	rules = testNewNetworkRules(t, []string{
+		"@@||example.org^$generichide,specifichide",
+	}, 0)
+	sourceRules = []*NetworkRule{}
+	result = NewMatchingResult(rules, sourceRules)
+	assert.Equal(t, CosmeticOptionCSS|CosmeticOptionJS|CosmeticOptionNoSpecificCSS, result.GetCosmeticOption())
+
+	// $elemhide and $specifichide
+	rules = testNewNetworkRules(t, []string{
+		"@@||example.org^$elemhide,specifichide",
+	}, 0)
+	sourceRules = []*NetworkRule{}
+	result = NewMatchingResult(rules, sourceRules)
+	assert.Equal(t, CosmeticOptionJS, result.GetCosmeticOption())
+
 	// $jsinject
 	rules = testNewNetworkRules(t, []string{
 		"@@||example.org^$jsinject",
@@ -0,0 +1,99 @@
+package rules
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// RequestMethod is the HTTP method of a request.  The methods are flags, so
+// that a set of them can be stored as a single value.
+type RequestMethod uint16
+
+// RequestMethod enumeration.  The zero value means that the method is unknown.
+const (
+	MethodConnect RequestMethod = 1 << iota
+	MethodDelete
+	MethodGet
+	MethodHead
+	MethodOptions
+	MethodPatch
+	MethodPost
+	MethodPut
+	MethodTrace
+)
+
+// methodNames maps the lowercased method names to the methods.
+var methodNames = map[string]RequestMethod{
+	"connect": MethodConnect,
+	"delete":  MethodDelete,
+	"get":     MethodGet,
+	"head":    MethodHead,
+	"options": MethodOptions,
+	"patch":   MethodPatch,
+	"post":    MethodPost,
+	"put":     MethodPut,
+	"trace":   MethodTrace,
+}
+
+// ParseRequestMethod returns the method with the given case-insensitive name,
+// for example "POST".  It returns zero if the method is unknown.
+func ParseRequestMethod(name string) (m RequestMethod) {
+	return methodNames[strings.ToLower(name)]
+}
+
+// loadMethods parses the value of the $method modifier, for example
+// "post|put" or "~get".  The permitted and the restricted methods cannot be
+// mixed.
+func loadMethods(value string) (permitted, restricted RequestMethod, err error) {
+	if value == "" {
+		return 0, 0, errors.Error("empty $method value")
+	}
+
+	for _, v := range strings.Split(value, "|") {
+		name, negated := cutNegation(v)
+		m, ok := methodNames[strings.ToLower(name)]
+		if !ok {
+			return 0, 0, fmt.Errorf("$method: unknown method %q", v)
+		}
+
+		if negated {
+			restricted |= m
+		} else {
+			permitted |= m
+		}
+	}
+
+	if permitted != 0 && restricted != 0 {
+		return 0, 0, fmt.Errorf("$method: cannot mix permitted and restricted methods: %q", value)
+	}
+
+	return permitted, restricted, nil
+}
+
+// methodNamesOf returns the lowercased names of the methods in m in the
+// alphabetical order.
+func methodNamesOf(m RequestMethod) (names []string) {
+	for _, name := range slices.Sorted(maps.Keys(methodNames)) {
+		if m&methodNames[name] != 0 {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// matchMethod returns true if the rule has no $method modifier or if m is
+// allowed by it.  The requests with the unknown method only match the rules
+// with the restricted methods.
+func (f *NetworkRule) matchMethod(m RequestMethod) (ok bool) {
+	rs := f.getRestrictions()
+	if rs.restrictedMethods&m != 0 {
+		return false
+	}
+
+	return rs.permittedMethods == 0 || rs.permittedMethods&m != 0
+}
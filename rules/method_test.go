@@ -0,0 +1,107 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkRule_Match_method(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		rule   string
+		method rules.RequestMethod
+		want   bool
+	}{{
+		name:   "permitted",
+		rule:   "||example.org^$method=post|put",
+		method: rules.MethodPost,
+		want:   true,
+	}, {
+		name:   "permitted_other",
+		rule:   "||example.org^$method=post|put",
+		method: rules.MethodGet,
+		want:   false,
+	}, {
+		name:   "permitted_unknown",
+		rule:   "||example.org^$method=post",
+		method: 0,
+		want:   false,
+	}, {
+		name:   "restricted",
+		rule:   "||example.org^$method=~get|~head",
+		method: rules.MethodGet,
+		want:   false,
+	}, {
+		name:   "restricted_other",
+		rule:   "||example.org^$method=~get|~head",
+		method: rules.MethodPost,
+		want:   true,
+	}, {
+		name:   "restricted_unknown",
+		rule:   "||example.org^$method=~get",
+		method: 0,
+		want:   true,
+	}, {
+		name:   "case",
+		rule:   "||example.org^$method=POST",
+		method: rules.MethodPost,
+		want:   true,
+	}, {
+		name:   "no_modifier",
+		rule:   "||example.org^",
+		method: rules.MethodPost,
+		want:   true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := rules.NewNetworkRule(tc.rule, -1)
+			require.NoError(t, err)
+
+			r := rules.NewRequest("https://example.org/collect", "", rules.TypePing)
+			r.Method = tc.method
+
+			assert.Equal(t, tc.want, f.Match(r))
+		})
+	}
+}
+
+func TestNetworkRule_method(t *testing.T) {
+	t.Parallel()
+
+	r, err := rules.NewNetworkRule("$method=post,domain=example.org", -1)
+	require.NoError(t, err)
+
+	assert.True(t, r.IsRestricted())
+
+	r, err = rules.NewNetworkRule("||example.org^$method=post", -1)
+	require.NoError(t, err)
+
+	assert.False(t, r.IsHostLevelNetworkRule())
+
+	for _, text := range []string{
+		"||example.org^$method",
+		"||example.org^$method=fetch",
+		"||example.org^$method=post|~get",
+		"||example.org^$method=post|",
+	} {
+		_, err = rules.NewNetworkRule(text, -1)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestParseRequestMethod(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, rules.MethodPost, rules.ParseRequestMethod("POST"))
+	assert.Equal(t, rules.MethodGet, rules.ParseRequestMethod("get"))
+	assert.Zero(t, rules.ParseRequestMethod("FETCH"))
+	assert.Zero(t, rules.ParseRequestMethod(""))
+}
@@ -3,27 +3,31 @@ package rules
 import (
 	"fmt"
 	"math/bits"
+	"net/http"
 	"net/netip"
 	"regexp"
 	"slices"
 	"strings"
-	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 
 	"github.com/AdguardTeam/golibs/errors"
 	"github.com/AdguardTeam/golibs/netutil"
+	"github.com/AdguardTeam/urlfilter/internal/ufnet"
 )
 
 const (
-	maskWhiteList    = "@@"
-	maskRegexRule    = "/"
-	replaceOption    = "replace"
-	optionsDelimiter = '$'
-	escapeCharacter  = '\\'
+	maskWhiteList      = "@@"
+	maskRegexRule      = "/"
+	replaceOption      = "replace"
+	urlTransformOption = "urltransform"
+	optionsDelimiter   = '$'
+	escapeCharacter    = '\\'
 )
 
 // ErrTooWideRule is returned if the rule matches all urls but has no domain,
-// denyallow, client or ctag restrictions.
-var ErrTooWideRule errors.Error = "the rule is too wide, add domain, denyallow, client, " +
+// to, denyallow, client or ctag restrictions.
+var ErrTooWideRule errors.Error = "the rule is too wide, add domain, to, denyallow, client, " +
 	"or ctag restrictions or make it more specific"
 
 var (
@@ -73,18 +77,38 @@ const (
 	OptionCookie   // $cookie
 	OptionRedirect // $redirect
 
+	// Modifying the requests and the responses.
+	OptionRemoveParam    // $removeparam
+	OptionRemoveHeader   // $removeheader
+	OptionPermissions    // $permissions
+	OptionURLTransform   // $urltransform
+	OptionReferrerPolicy // $referrerpolicy
+	OptionJSONPrune      // $jsonprune
+	OptionHLS            // $hls
+
+	// Modifiers added to the groups above later.  They are kept at the end so
+	// that the values of the other options don't change.
+
+	OptionSpecifichide     // $specifichide modifier
+	OptionStrictThirdParty // $strict-third-party modifier
+
 	// Blacklist-only options
 	OptionBlacklistOnly = OptionPopup | OptionEmpty | OptionMp4
 
 	// Whitelist-only options
 	OptionWhitelistOnly = OptionElemhide | OptionGenericblock | OptionGenerichide |
 		OptionJsinject | OptionUrlblock | OptionContent | OptionExtension |
-		OptionStealth
+		OptionStealth | OptionSpecifichide
 
 	// Options supported by host-level network rules
 	OptionHostLevelRulesOnly = OptionImportant | OptionBadfilter
 )
 
+// valueOptions are the options of the modifiers modifying the requests and the
+// responses, the values of which are kept in [valueModifiers].
+const valueOptions = OptionRemoveParam | OptionRemoveHeader | OptionPermissions |
+	OptionURLTransform | OptionReferrerPolicy | OptionJSONPrune | OptionHLS
+
 // Count returns the count of enabled options.
 func (o NetworkRuleOption) Count() int {
 	return bits.OnesCount64(uint64(o))
@@ -93,22 +117,27 @@ func (o NetworkRuleOption) Count() int {
 // NetworkRule is a basic filtering rule
 // https://kb.adguard.com/en/general/how-to-create-your-own-ad-filters#basic-rules
 type NetworkRule struct {
-	// permittedClients are permitted clients from the $client modifier.
-	//
-	// See https://github.com/AdguardTeam/AdGuardHome/issues/1761.
-	permittedClients *clients
-	// restrictedClients are restricted clients from the $client modifier.
-	//
-	// See https://github.com/AdguardTeam/AdGuardHome/issues/1761.
-	restrictedClients *clients
+	// restrictions are the modifiers restricting the requests the rule
+	// matches.  It is nil if there are none, which is the most common case.
+	// Use [NetworkRule.getRestrictions] to read it.
+	restrictions *restrictions
+
+	// values are the values of the modifiers modifying the requests and the
+	// responses.  It is nil if there are none, which is the most common case.
+	// Use [NetworkRule.getValues] to read it.
+	values *valueModifiers
 
 	// DNSRewrite is the DNS rewrite rule, if any.
 	DNSRewrite *DNSRewrite
 
-	// regex is the regular expression compiled from the pattern.
-	regex *regexp.Regexp
+	// regex is the regular expression compiled from the pattern.  It is nil
+	// until the pattern is first matched, [regexAny] if the pattern matches
+	// any URL, and [regexInvalid] if the pattern cannot be compiled.
+	regex atomic.Pointer[regexp.Regexp]
 
-	// RuleText is the original rule text.
+	// RuleText is the original rule text.  Shortcut, pattern, and the domains
+	// of the modifiers are its substrings unless they have been normalized or
+	// unescaped, so the text is stored only once.
 	RuleText string
 	// Shortcut is the longest substring of the rule pattern with no special
 	// characters.
@@ -116,15 +145,52 @@ type NetworkRule struct {
 	// pattern is the basic rule pattern ready to be compiled to regex.
 	pattern string
 
+	// enabledOptions are the flags with all enabled rule options.
+	enabledOptions NetworkRuleOption
+	// disabledOptions are the flags with all disabled rule options.
+	disabledOptions NetworkRuleOption
+
+	// permittedRequestTypes are the flags with all permitted request types. 0
+	// means ALL.
+	permittedRequestTypes RequestType
+	// restrictedRequestTypes are the flags with all restricted request types. 0
+	// means NONE.
+	restrictedRequestTypes RequestType
+
+	// FilterListID is a filter list identifier.
+	FilterListID int
+
+	// Whitelist is true if this is an exception rule.
+	Whitelist bool
+}
+
+// restrictions are the modifiers of a [NetworkRule] which restrict the requests
+// it matches.  Most rules have none of them, so they are kept separately to
+// make the rules smaller.
+type restrictions struct {
+	// permittedClients are permitted clients from the $client modifier.
+	//
+	// See https://github.com/AdguardTeam/AdGuardHome/issues/1761.
+	permittedClients *clients
+	// restrictedClients are restricted clients from the $client modifier.
+	//
+	// See https://github.com/AdguardTeam/AdGuardHome/issues/1761.
+	restrictedClients *clients
+
 	// permittedDomains is a list of permitted domains from the $domain
 	// modifier.
 	permittedDomains []string
 	// restrictedDomains is a list of restricted domains from the $domain
 	// modifier.
 	restrictedDomains []string
-	// denyAllowDomains is a list of excluded domains from the $denyallow
+	// permittedToDomains is a list of permitted request domains from the $to
 	// modifier.
-	denyAllowDomains []string
+	permittedToDomains []string
+	// restrictedToDomains is a list of restricted request domains from the
+	// $to modifier.  The domains from the $denyallow modifier are also added
+	// here, since "$denyallow=example.org" is the same as
+	// "$to=~example.org".
+	restrictedToDomains []string
 
 	// permittedDNSTypes is the list of permitted DNS record type names from
 	// the $dnstype modifier.
@@ -144,29 +210,204 @@ type NetworkRule struct {
 	// See https://github.com/AdguardTeam/AdGuardHome/issues/1081#issuecomment-575142737.
 	restrictedClientTags []string
 
-	// Mutex protects the fields.
-	sync.Mutex
+	// header is the response header from the $header modifier.  If it's not
+	// nil, the rule only matches the requests with such response headers.
+	header *headerMatcher
+
+	// ipNet is the network from a CIDR pattern, such as "192.0.2.0/24".  If
+	// it's valid, the rule only matches the requests for the IP addresses
+	// within it.  It's kept here, since such patterns are rare.
+	ipNet netip.Prefix
+
+	// permittedMethods are the permitted HTTP methods from the $method
+	// modifier.  0 means all.
+	permittedMethods RequestMethod
+	// restrictedMethods are the restricted HTTP methods from the $method
+	// modifier.  0 means none.
+	restrictedMethods RequestMethod
+}
 
-	// enabledOptions are the flags with all enabled rule options.
-	enabledOptions NetworkRuleOption
-	// disabledOptions are the flags with all disabled rule options.
-	disabledOptions NetworkRuleOption
+// valueModifiers are the values of the modifiers of a [NetworkRule] which
+// modify the requests and the responses instead of blocking them.  Most rules
+// have none of them, so they are kept separately to make the rules smaller.
+type valueModifiers struct {
+	// removeParam is the value of the $removeparam modifier.  It is nil if the
+	// rule has no such modifier.
+	removeParam *removeParam
+
+	// removeHeader is the value of the $removeheader modifier.  It is nil if
+	// the rule has no such modifier.
+	removeHeader *removeHeader
+
+	// permissions is the value of the $permissions modifier.  It is nil if the
+	// rule has no such modifier.
+	permissions *permissions
+
+	// urlTransform is the value of the $urltransform modifier.  It is nil if
+	// the rule has no such modifier.
+	urlTransform *urlTransform
+
+	// referrerPolicy is the value of the $referrerpolicy modifier.  It is nil
+	// if the rule has no such modifier.
+	referrerPolicy *referrerPolicy
+
+	// jsonPrune is the value of the $jsonprune modifier.  It is nil if the
+	// rule has no such modifier.
+	jsonPrune *jsonPrune
+
+	// hls is the value of the $hls modifier.  It is nil if the rule has no
+	// such modifier.
+	hls *hls
+
+	// redirect is the value of the $redirect modifier.  The rules don't apply
+	// it, so it's only kept for [NetworkRule.Node].
+	redirect string
+}
 
-	// permittedRequestTypes are the flags with all permitted request types. 0
-	// means ALL.
-	permittedRequestTypes RequestType
-	// restrictedRequestTypes are the flags with all restricted request types. 0
-	// means NONE.
-	restrictedRequestTypes RequestType
+// equal returns true if v and other contain the same values.
+func (v *valueModifiers) equal(other *valueModifiers) (ok bool) {
+	return equalValues(v.removeParam, other.removeParam) &&
+		equalValues(v.removeHeader, other.removeHeader) &&
+		equalValues(v.permissions, other.permissions) &&
+		equalValues(v.urlTransform, other.urlTransform) &&
+		equalValues(v.referrerPolicy, other.referrerPolicy) &&
+		equalValues(v.jsonPrune, other.jsonPrune) &&
+		equalValues(v.hls, other.hls) &&
+		v.redirect == other.redirect
+}
 
-	// FilterListID is a filter list identifier.
-	FilterListID int
+// get returns the value of the modifier enabled by opt, which must be one of
+// the options of the value modifiers.
+func (v *valueModifiers) get(opt NetworkRuleOption) (m valueModifier) {
+	switch opt {
+	case OptionRemoveParam:
+		return v.removeParam
+	case OptionRemoveHeader:
+		return v.removeHeader
+	case OptionPermissions:
+		return v.permissions
+	case OptionURLTransform:
+		return v.urlTransform
+	case OptionReferrerPolicy:
+		return v.referrerPolicy
+	case OptionJSONPrune:
+		return v.jsonPrune
+	case OptionHLS:
+		return v.hls
+	default:
+		panic(fmt.Errorf("valueModifiers.get: bad option %d", opt))
+	}
+}
 
-	// Whitelist is true if this is an exception rule.
-	Whitelist bool
-	// invalid marks that the rule is invalid.  Match will always return false
-	// in this case.
-	invalid bool
+// valueModifier is the parsed value of a modifier in [valueModifiers].
+type valueModifier interface {
+	// getValue returns the normalized value of the modifier.  An exception rule
+	// with an empty value disables all rules with the modifier.
+	getValue() (v string)
+}
+
+// modifierValue is embedded into the parsed values of the modifiers to
+// implement [valueModifier].
+type modifierValue struct {
+	// value is the normalized value of the modifier.
+	value string
+}
+
+// type check
+var _ valueModifier = (*modifierValue)(nil)
+
+// getValue implements the [valueModifier] interface for *modifierValue.
+func (mv *modifierValue) getValue() (v string) {
+	return mv.value
+}
+
+// equalValues returns true if a and b are the same modifier values.  Either of
+// them may be nil.
+func equalValues[T any, M interface {
+	*T
+	valueModifier
+}](a, b M) (ok bool) {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.getValue() == b.getValue()
+}
+
+// newValueModifier parses the value of the modifier with the name using parse.
+// An empty value is only allowed in the exception rules, which disable all
+// rules with the modifier, and results in the zero T.
+func newValueModifier[T any](
+	name string,
+	value string,
+	whitelist bool,
+	parse func(value string) (m *T, err error),
+) (m *T, err error) {
+	if value != "" {
+		return parse(value)
+	} else if !whitelist {
+		return nil, fmt.Errorf("empty $%s value", name)
+	}
+
+	return new(T), nil
+}
+
+// noValues is the value returned by [NetworkRule.getValues] for the rules
+// without value modifiers.  It must not be modified.
+var noValues = &valueModifiers{}
+
+// noRestrictions is the value returned by [NetworkRule.getRestrictions] for the
+// rules without restrictions.  It must not be modified.
+var noRestrictions = &restrictions{}
+
+// Sentinel values of [NetworkRule.regex].
+var (
+	// regexAny means that the pattern matches any URL.
+	regexAny = &regexp.Regexp{}
+
+	// regexInvalid means that the pattern is not a valid regular expression,
+	// so the rule never matches.
+	regexInvalid = &regexp.Regexp{}
+)
+
+// getRestrictions returns the restrictions of the rule.  The result must not be
+// modified.
+func (f *NetworkRule) getRestrictions() (rs *restrictions) {
+	if f.restrictions == nil {
+		return noRestrictions
+	}
+
+	return f.restrictions
+}
+
+// mutableRestrictions returns the restrictions of the rule, allocating them if
+// necessary.
+func (f *NetworkRule) mutableRestrictions() (rs *restrictions) {
+	if f.restrictions == nil {
+		f.restrictions = &restrictions{}
+	}
+
+	return f.restrictions
+}
+
+// getValues returns the value modifiers of the rule.  The result must not be
+// modified.
+func (f *NetworkRule) getValues() (v *valueModifiers) {
+	if f.values == nil {
+		return noValues
+	}
+
+	return f.values
+}
+
+// mutableValues returns the value modifiers of the rule, allocating them if
+// necessary.
+func (f *NetworkRule) mutableValues() (v *valueModifiers) {
+	if f.values == nil {
+		f.values = &valueModifiers{}
+	}
+
+	return f.values
 }
 
 // NewNetworkRule parses the rule text and returns a filter rule
@@ -184,7 +425,7 @@ func NewNetworkRule(ruleText string, filterListID int) (r *NetworkRule, err erro
 		RuleText:     ruleText,
 		Whitelist:    whitelist,
 		FilterListID: filterListID,
-		pattern:      pattern,
+		pattern:      normalizePatternHostname(pattern),
 	}
 
 	// Parse options.
@@ -201,21 +442,22 @@ func NewNetworkRule(ruleText string, filterListID int) (r *NetworkRule, err erro
 	if pattern == MaskStartURL || pattern == MaskPipe ||
 		pattern == MaskAnyCharacter || pattern == "" ||
 		len(pattern) < 3 {
-		if len(r.permittedDomains) == 0 &&
-			len(r.restrictedDomains) == 0 &&
-			r.permittedClients.Len() == 0 &&
-			r.restrictedClients.Len() == 0 &&
-			len(r.permittedClientTags) == 0 &&
-			len(r.restrictedClientTags) == 0 &&
-			len(r.permittedDNSTypes) == 0 &&
-			len(r.restrictedDNSTypes) == 0 &&
-			len(r.denyAllowDomains) == 0 {
+		if !r.IsRestricted() && r.enabledOptions&valueOptions == 0 {
 			// Rule matches too much and does not have any domain, client or ctag restrictions
-			// We should not allow this kind of rules
+			// We should not allow this kind of rules.  The rules modifying
+			// the requests, like "$removeparam=utm_source", are fine though.
 			return nil, ErrTooWideRule
 		}
 	}
 
+	if ipNet, ok := parsePatternPrefix(r.pattern); ok {
+		// The URLs of the matching requests don't contain the pattern, so
+		// don't load the shortcut.
+		r.mutableRestrictions().ipNet = ipNet
+
+		return r, nil
+	}
+
 	r.loadShortcut()
 	return r, nil
 }
@@ -243,12 +485,16 @@ func (f *NetworkRule) Match(r *Request) (ok bool) {
 		!f.matchShortcut(r),
 		f.IsOptionEnabled(OptionThirdParty) && !r.ThirdParty,
 		f.IsOptionDisabled(OptionThirdParty) && r.ThirdParty,
+		f.IsOptionEnabled(OptionStrictThirdParty) && !r.StrictThirdParty,
+		f.IsOptionDisabled(OptionStrictThirdParty) && r.StrictThirdParty,
 		!f.matchRequestType(r.RequestType),
-		!f.matchRequestDomain(r.Hostname, r.IsHostnameRequest),
+		!f.matchMethod(r.Method),
+		!f.matchTargetDomain(r.Hostname, r.IsHostnameRequest),
 		!f.matchSourceDomain(r.SourceHostname),
 		!f.matchDNSType(r.DNSType),
 		!f.matchClientTags(r.SortedClientTags),
 		!f.matchClient(r.ClientName, r.ClientIP),
+		!f.matchResponseHeaders(r.ResponseHeaders),
 		!f.matchPattern(r):
 		return false
 	}
@@ -268,12 +514,18 @@ func (f *NetworkRule) IsOptionDisabled(option NetworkRuleOption) bool {
 
 // GetPermittedDomains - returns an array of domains this rule is allowed on
 func (f *NetworkRule) GetPermittedDomains() []string {
-	return f.permittedDomains
+	return f.getRestrictions().permittedDomains
 }
 
 // IsHostLevelNetworkRule checks if this rule can be used for hosts-level blocking
 func (f *NetworkRule) IsHostLevelNetworkRule() bool {
-	if len(f.permittedDomains) > 0 || len(f.restrictedDomains) > 0 {
+	rs := f.getRestrictions()
+	if len(rs.permittedDomains) > 0 || len(rs.restrictedDomains) > 0 {
+		return false
+	}
+
+	// DNS requests have neither response headers nor methods.
+	if rs.header != nil || rs.permittedMethods != 0 || rs.restrictedMethods != 0 {
 		return false
 	}
 
@@ -293,6 +545,50 @@ func (f *NetworkRule) IsHostLevelNetworkRule() bool {
 	return true
 }
 
+// IsRestricted returns true if the rule only applies to some of the requests
+// matching its pattern because of the $client, $ctag, $denyallow, $dnstype,
+// $domain, $header, $method, or $to modifiers.
+func (f *NetworkRule) IsRestricted() (ok bool) {
+	rs := f.getRestrictions()
+
+	return len(rs.permittedDomains) != 0 ||
+		len(rs.restrictedDomains) != 0 ||
+		rs.permittedClients.Len() != 0 ||
+		rs.restrictedClients.Len() != 0 ||
+		len(rs.permittedClientTags) != 0 ||
+		len(rs.restrictedClientTags) != 0 ||
+		len(rs.permittedDNSTypes) != 0 ||
+		len(rs.restrictedDNSTypes) != 0 ||
+		len(rs.permittedToDomains) != 0 ||
+		len(rs.restrictedToDomains) != 0 ||
+		rs.header != nil ||
+		rs.permittedMethods != 0 ||
+		rs.restrictedMethods != 0
+}
+
+// HostnamePattern returns the lowercased hostname from the rule pattern if the
+// pattern has the "||hostname^" form, which matches the hostname and all of its
+// subdomains, or the "|hostname^" form, which only matches the hostname itself
+// in the hostname requests.  subdomains is true for the former.  Otherwise, ok
+// is false.
+func (f *NetworkRule) HostnamePattern() (hostname string, subdomains, ok bool) {
+	hostname, subdomains = strings.CutPrefix(f.pattern, MaskStartURL)
+	if !subdomains {
+		hostname, ok = strings.CutPrefix(f.pattern, MaskPipe)
+		if !ok {
+			return "", false, false
+		}
+	}
+
+	hostname = strings.TrimSuffix(hostname, MaskPipe)
+	hostname, ok = strings.CutSuffix(hostname, MaskSeparator)
+	if !ok || !ufnet.IsDomainName(hostname) {
+		return "", false, false
+	}
+
+	return strings.ToLower(hostname), subdomains, true
+}
+
 // isRegexPattern returns true if pattern may be treated as a regular expression
 // rule.
 func isRegexPattern(pattern string) bool {
@@ -308,7 +604,7 @@ func (f *NetworkRule) IsRegexRule() (ok bool) {
 // "generic" means that the rule is not restricted to a limited set of domains
 // Please note that it might be forbidden on some domains, though.
 func (f *NetworkRule) IsGeneric() bool {
-	return len(f.permittedDomains) == 0
+	return len(f.getRestrictions().permittedDomains) == 0
 }
 
 // IsHigherPriority checks if the rule has higher priority that the specified rule
@@ -356,41 +652,54 @@ func (f *NetworkRule) IsHigherPriority(r *NetworkRule) bool {
 		return true
 	}
 
-	// More specific rules (i.e. with more modifiers) have higher priority
-	count := f.enabledOptions.Count() + f.disabledOptions.Count() +
-		f.permittedRequestTypes.Count() + f.restrictedRequestTypes.Count()
-	if len(f.permittedDomains) != 0 || len(f.restrictedDomains) != 0 {
+	// More specific rules (i.e. with more modifiers) have higher priority.  The
+	// $client and the $to and $denyallow modifiers are only counted for f.
+	count := f.modifiersCount()
+	rs := f.getRestrictions()
+	if rs.permittedClients.Len() != 0 || rs.restrictedClients.Len() != 0 {
 		count++
 	}
-	if len(f.permittedDNSTypes) != 0 || len(f.restrictedDNSTypes) != 0 {
+	if len(rs.permittedToDomains) != 0 || len(rs.restrictedToDomains) != 0 {
 		count++
 	}
-	if len(f.permittedClientTags) != 0 || len(f.restrictedClientTags) != 0 {
-		count++
-	}
-	if f.permittedClients.Len() != 0 || f.restrictedClients.Len() != 0 {
-		count++
-	}
-	if len(f.denyAllowDomains) != 0 {
-		count++
-	}
-	rCount := r.enabledOptions.Count() + r.disabledOptions.Count() +
-		r.permittedRequestTypes.Count() + r.restrictedRequestTypes.Count()
-	if len(r.permittedDomains) != 0 || len(r.restrictedDomains) != 0 {
-		rCount++
+
+	return count > r.modifiersCount()
+}
+
+// modifiersCount returns the number of the modifiers of the rule for
+// [NetworkRule.IsHigherPriority].  Each option and request type is counted
+// separately, while each of the $domain, $dnstype, and $ctag modifiers is
+// counted once regardless of the number of its values.
+func (f *NetworkRule) modifiersCount() (n int) {
+	rs := f.getRestrictions()
+
+	n = f.enabledOptions.Count() + f.disabledOptions.Count() +
+		f.permittedRequestTypes.Count() + f.restrictedRequestTypes.Count()
+	if len(rs.permittedDomains) != 0 || len(rs.restrictedDomains) != 0 {
+		n++
 	}
-	if len(r.permittedDNSTypes) != 0 || len(r.restrictedDNSTypes) != 0 {
-		rCount++
+	if len(rs.permittedDNSTypes) != 0 || len(rs.restrictedDNSTypes) != 0 {
+		n++
 	}
-	if len(r.permittedClientTags) != 0 || len(r.restrictedClientTags) != 0 {
-		rCount++
+	if len(rs.permittedClientTags) != 0 || len(rs.restrictedClientTags) != 0 {
+		n++
 	}
-	return count > rCount
+
+	return n
 }
 
 // negatesBadfilter only makes sense when the "f" rule has a `badfilter` modifier
 // it returns true if the "f" rule negates the specified "r" rule
 func (f *NetworkRule) negatesBadfilter(r *NetworkRule) bool {
+	return f.negatesBadfilterExceptDomains(r) &&
+		slices.Equal(f.getRestrictions().permittedDomains, r.getRestrictions().permittedDomains)
+}
+
+// negatesBadfilterExceptDomains is like [NetworkRule.negatesBadfilter] but
+// ignores the permitted domains of the rules.
+func (f *NetworkRule) negatesBadfilterExceptDomains(r *NetworkRule) (ok bool) {
+	fRs, rRs := f.getRestrictions(), r.getRestrictions()
+
 	switch {
 	case
 		!f.IsOptionEnabled(OptionBadfilter),
@@ -400,12 +709,17 @@ func (f *NetworkRule) negatesBadfilter(r *NetworkRule) bool {
 		f.restrictedRequestTypes != r.restrictedRequestTypes,
 		(f.enabledOptions ^ OptionBadfilter) != r.enabledOptions,
 		f.disabledOptions != r.disabledOptions,
-		!slices.Equal(f.permittedDomains, r.permittedDomains),
-		!slices.Equal(f.restrictedDomains, r.restrictedDomains),
-		!slices.Equal(f.permittedClientTags, r.permittedClientTags),
-		!slices.Equal(f.restrictedClientTags, r.restrictedClientTags),
-		!f.permittedClients.Equal(r.permittedClients),
-		!f.restrictedClients.Equal(r.restrictedClients):
+		!slices.Equal(fRs.restrictedDomains, rRs.restrictedDomains),
+		!slices.Equal(fRs.permittedToDomains, rRs.permittedToDomains),
+		!slices.Equal(fRs.restrictedToDomains, rRs.restrictedToDomains),
+		!slices.Equal(fRs.permittedClientTags, rRs.permittedClientTags),
+		!slices.Equal(fRs.restrictedClientTags, rRs.restrictedClientTags),
+		!fRs.permittedClients.Equal(rRs.permittedClients),
+		!fRs.restrictedClients.Equal(rRs.restrictedClients),
+		!fRs.header.equal(rRs.header),
+		fRs.permittedMethods != rRs.permittedMethods,
+		fRs.restrictedMethods != rRs.restrictedMethods,
+		!f.getValues().equal(r.getValues()):
 		return false
 	}
 
@@ -421,51 +735,68 @@ func (f *NetworkRule) isDocumentWhitelistRule() bool {
 		f.IsOptionEnabled(OptionGenericblock))
 }
 
-func (f *NetworkRule) preparePattern() (res int) {
-	f.Lock()
-	defer f.Unlock()
-
-	switch {
-	case f.regex != nil:
-		return 1
-	case f.invalid:
-		return -1
-	default:
-		// Go on.
+// compiledRegex returns the regular expression compiled from the pattern,
+// compiling it if necessary.  It returns [regexAny] if the pattern matches any
+// URL and [regexInvalid] if the pattern is invalid.
+func (f *NetworkRule) compiledRegex() (re *regexp.Regexp) {
+	re = f.regex.Load()
+	if re != nil {
+		return re
+	}
+
+	// Concurrent calls may compile the same expression more than once, which
+	// is still cheaper than locking on every match.
+	pattern, ok := f.RegexpText()
+	if !ok {
+		re = regexAny
+	} else if compiled, err := regexp.Compile(pattern); err == nil {
+		re = compiled
+	} else {
+		re = regexInvalid
 	}
 
-	pattern := patternToRegexp(f.pattern)
-	if pattern == RegexAnyCharacter {
-		return 0
-	}
+	f.regex.Store(re)
 
-	if !f.IsOptionEnabled(OptionMatchCase) {
-		pattern = "(?i)" + pattern
-	}
+	return re
+}
 
-	var err error
-	if f.regex, err = regexp.Compile(pattern); err != nil {
-		f.invalid = true
+// RegexpText returns the text of the regular expression which the rule pattern
+// is matched with, including the case-insensitivity flag if necessary.  If the
+// pattern matches any URL, ok is false.
+func (f *NetworkRule) RegexpText() (text string, ok bool) {
+	text = patternToRegexp(f.pattern)
+	if text == RegexAnyCharacter {
+		return "", false
+	}
 
-		return -1
+	if !f.IsOptionEnabled(OptionMatchCase) {
+		text = "(?i)" + text
 	}
 
-	return 1
+	return text, true
 }
 
 // matchPattern uses the regex pattern to match the request URL
 func (f *NetworkRule) matchPattern(r *Request) bool {
-	if res := f.preparePattern(); res == -1 {
+	if ipNet := f.getRestrictions().ipNet; ipNet.IsValid() {
+		ip, ok := hostnameAddr(r.Hostname)
+
+		return ok && ipNet.Contains(ip.Unmap())
+	}
+
+	re := f.compiledRegex()
+	switch re {
+	case regexInvalid:
 		return false
-	} else if res == 0 {
+	case regexAny:
 		return true
 	}
 
 	if f.shouldMatchHostname(r) {
-		return f.regex.MatchString(r.Hostname)
+		return re.MatchString(r.Hostname)
 	}
 
-	return f.regex.MatchString(r.URL)
+	return re.MatchString(r.URL)
 }
 
 // shouldMatchHostname checks if we should match hostnames and not the URL
@@ -507,16 +838,17 @@ func (f *NetworkRule) matchShortcut(r *Request) bool {
 	return strings.Contains(r.URLLowerCase, f.Shortcut)
 }
 
-// matchRequestDomain checks if the filtering rule is allowed to match this
-// request domain, e.g. it checks it against the $denyallow modifier. Please,
-// pay attention at how $denyallow works:  the rule will work if the request
-// hostname **does not** belong to $denyallow domains.  The idea is to allow
-// rules that block anything EXCEPT FOR some domains.  For instance, if we have
-// a website that we know to load a lot of third-party crap, but some of the
-// domains are crucial for this website, we may want to add something like this:
-// "*$script,domain=example.org,denyallow=essential1.com|essential2.com".
-func (f *NetworkRule) matchRequestDomain(domain string, hostnameRequest bool) (ok bool) {
-	if len(f.denyAllowDomains) == 0 {
+// matchTargetDomain checks if the filtering rule is allowed to match this
+// request domain, e.g. it checks it against the $to and $denyallow modifiers.
+// Please, pay attention at how $denyallow works:  the rule will work if the
+// request hostname **does not** belong to $denyallow domains.  The idea is to
+// allow rules that block anything EXCEPT FOR some domains.  For instance, if we
+// have a website that we know to load a lot of third-party crap, but some of
+// the domains are crucial for this website, we may want to add something like
+// this: "*$script,domain=example.org,denyallow=essential1.com|essential2.com".
+func (f *NetworkRule) matchTargetDomain(domain string, hostnameRequest bool) (ok bool) {
+	rs := f.getRestrictions()
+	if len(rs.permittedToDomains) == 0 && len(rs.restrictedToDomains) == 0 {
 		return true
 	}
 
@@ -525,55 +857,38 @@ func (f *NetworkRule) matchRequestDomain(domain string, hostnameRequest bool) (o
 	// only come from CNAME filtering.  So regardless of whether it actually
 	// matches the "denyallow" list, we consider that it does not.
 	// Original issue: https://github.com/AdguardTeam/AdGuardHome/issues/3175.
-	if hostnameRequest && netutil.IsValidIPString(domain) {
+	if hostnameRequest && len(rs.restrictedToDomains) > 0 && netutil.IsValidIPString(domain) {
 		return false
 	}
 
-	return !isDomainOrSubdomainOfAny(domain, f.denyAllowDomains)
+	return matchDomains(domain, rs.permittedToDomains, rs.restrictedToDomains)
 }
 
 // matchSourceDomain checks if the specified filtering rule is allowed on this
 // domain e.g. it checks the domain against what's specified in the $domain
 // modifier.
 func (f *NetworkRule) matchSourceDomain(domain string) bool {
-	if len(f.permittedDomains) == 0 && len(f.restrictedDomains) == 0 {
-		return true
-	}
-
-	if len(f.restrictedDomains) > 0 {
-		if isDomainOrSubdomainOfAny(domain, f.restrictedDomains) {
-			// Domain or host is restricted
-			// i.e. $domain=~example.org
-			return false
-		}
-	}
+	rs := f.getRestrictions()
 
-	if len(f.permittedDomains) > 0 {
-		if !isDomainOrSubdomainOfAny(domain, f.permittedDomains) {
-			// Domain is not among permitted
-			// i.e. $domain=example.org and we're checking example.com
-			return false
-		}
-	}
-
-	return true
+	return matchDomains(domain, rs.permittedDomains, rs.restrictedDomains)
 }
 
 // matchDNSType checks if the specified filtering rule is allowed for this DNS
 // request record type.
 func (f *NetworkRule) matchDNSType(rtype uint16) (allowed bool) {
-	if len(f.permittedDNSTypes) == 0 && len(f.restrictedDNSTypes) == 0 {
+	rs := f.getRestrictions()
+	if len(rs.permittedDNSTypes) == 0 && len(rs.restrictedDNSTypes) == 0 {
 		return true
 	}
 
-	for _, t := range f.restrictedDNSTypes {
+	for _, t := range rs.restrictedDNSTypes {
 		if rtype == t {
 			return false
 		}
 	}
 
-	if len(f.permittedDNSTypes) > 0 {
-		for _, t := range f.permittedDNSTypes {
+	if len(rs.permittedDNSTypes) > 0 {
+		for _, t := range rs.permittedDNSTypes {
 			if rtype == t {
 				return true
 			}
@@ -605,19 +920,20 @@ func matchClientTagsSpecific(sortedRuleTags, sortedClientTags []string) bool {
 
 // Return TRUE if this rule matches with the tags associated with a client
 func (f *NetworkRule) matchClientTags(sortedTags []string) bool {
-	if len(f.restrictedClientTags) == 0 && len(f.permittedClientTags) == 0 {
+	rs := f.getRestrictions()
+	if len(rs.restrictedClientTags) == 0 && len(rs.permittedClientTags) == 0 {
 		// the rule doesn't contain $ctag extension
 		return true
 	}
-	if matchClientTagsSpecific(f.restrictedClientTags, sortedTags) {
+	if matchClientTagsSpecific(rs.restrictedClientTags, sortedTags) {
 		// matched by restricted client tag
 		return false
 	}
-	if len(f.permittedClientTags) != 0 {
+	if len(rs.permittedClientTags) != 0 {
 		// If the rule is permitted for specific tags only,
 		// we should check whether our tag is among permitted or not
 		// and return the result the result immediately
-		return matchClientTagsSpecific(f.permittedClientTags, sortedTags)
+		return matchClientTagsSpecific(rs.permittedClientTags, sortedTags)
 	}
 	return true
 }
@@ -625,15 +941,16 @@ func (f *NetworkRule) matchClientTags(sortedTags []string) bool {
 // matchClient returns true if the rule is specified for client defined by
 // host or ip.  Both host and ip can be empty.
 func (f *NetworkRule) matchClient(host string, ip netip.Addr) bool {
-	restLen := f.restrictedClients.Len()
-	permLen := f.permittedClients.Len()
+	rs := f.getRestrictions()
+	restLen := rs.restrictedClients.Len()
+	permLen := rs.permittedClients.Len()
 
 	if restLen == 0 && permLen == 0 {
 		// The rule has no $client modifier.
 		return true
 	}
 
-	if f.restrictedClients.containsAny(host, ip) {
+	if rs.restrictedClients.containsAny(host, ip) {
 		// The client is in the restricted set.
 		return false
 	}
@@ -641,7 +958,7 @@ func (f *NetworkRule) matchClient(host string, ip netip.Addr) bool {
 	if permLen != 0 {
 		// If the rule is permitted for specific client only, check whether the
 		// client is among permitted.
-		return f.permittedClients.containsAny(host, ip)
+		return rs.permittedClients.containsAny(host, ip)
 	}
 
 	// If we got here, permitted list is empty and the client is not among
@@ -649,6 +966,15 @@ func (f *NetworkRule) matchClient(host string, ip netip.Addr) bool {
 	return true
 }
 
+// matchResponseHeaders returns true if the rule has no $header modifier or if
+// headers contain the header matching it.  The rules with the $header modifier
+// never match the requests without the response headers.
+func (f *NetworkRule) matchResponseHeaders(headers http.Header) (ok bool) {
+	h := f.getRestrictions().header
+
+	return h == nil || h.match(headers)
+}
+
 // matchRequestType checks if the specified request type matches the rule properties
 func (f *NetworkRule) matchRequestType(requestType RequestType) bool {
 	if f.permittedRequestTypes != 0 {
@@ -715,6 +1041,8 @@ func (f *NetworkRule) loadOptions(options string) (err error) {
 	}
 
 	switch {
+	case f.permittedRequestTypes == typesAll:
+		// $all rules block the requests of all types as well as the popups.
 	case
 		f.IsOptionEnabled(OptionJsinject),
 		f.IsOptionEnabled(OptionElemhide),
@@ -722,10 +1050,17 @@ func (f *NetworkRule) loadOptions(options string) (err error) {
 		f.IsOptionEnabled(OptionUrlblock),
 		f.IsOptionEnabled(OptionGenericblock),
 		f.IsOptionEnabled(OptionGenerichide),
+		f.IsOptionEnabled(OptionSpecifichide),
 		f.IsOptionEnabled(OptionExtension),
 		f.IsOptionEnabled(OptionPopup):
 		// Rules of these types can be applied to documents only.
 		f.permittedRequestTypes = TypeDocument
+	case
+		f.IsOptionEnabled(OptionPermissions) && f.permittedRequestTypes == 0,
+		f.IsOptionEnabled(OptionReferrerPolicy) && f.permittedRequestTypes == 0:
+		// Permissions-Policy and Referrer-Policy headers only make sense for
+		// the documents and the frames.
+		f.permittedRequestTypes = TypeDocument | TypeSubdocument
 	default:
 		// Go on.
 	}
@@ -736,12 +1071,16 @@ func (f *NetworkRule) loadOptions(options string) (err error) {
 // loadOption loads specified option with its value (optional)
 // nolint:gocyclo
 func (f *NetworkRule) loadOption(name, value string) error {
-	switch name {
+	switch name = canonicalOptionName(name); name {
 	// General options
 	case "third-party", "~first-party":
 		return f.setOptionEnabled(OptionThirdParty, true)
 	case "~third-party", "first-party":
 		return f.setOptionEnabled(OptionThirdParty, false)
+	case "strict-third-party", "~strict-first-party":
+		return f.setOptionEnabled(OptionStrictThirdParty, true)
+	case "~strict-third-party", "strict-first-party":
+		return f.setOptionEnabled(OptionStrictThirdParty, false)
 	case "match-case":
 		return f.setOptionEnabled(OptionMatchCase, true)
 	case "~match-case":
@@ -753,8 +1092,8 @@ func (f *NetworkRule) loadOption(name, value string) error {
 	// $dnstype, the DNS request record type filter.
 	case "dnstype":
 		permitted, restricted, err := loadDNSTypes(value)
-		f.permittedDNSTypes = permitted
-		f.restrictedDNSTypes = restricted
+		rs := f.mutableRestrictions()
+		rs.permittedDNSTypes, rs.restrictedDNSTypes = permitted, restricted
 
 		return err
 	// $dnsrewrite, the DNS request rewrite filter.
@@ -766,8 +1105,8 @@ func (f *NetworkRule) loadOption(name, value string) error {
 	// $domain -- limits the rule for selected source domains
 	case "domain":
 		permitted, restricted, err := loadDomains(value, "|")
-		f.permittedDomains = permitted
-		f.restrictedDomains = restricted
+		rs := f.mutableRestrictions()
+		rs.permittedDomains, rs.restrictedDomains = permitted, restricted
 		return err
 
 	// $denyallow -- disables the rule for the selected request domains
@@ -779,15 +1118,42 @@ func (f *NetworkRule) loadOption(name, value string) error {
 		if len(restricted) > 0 || len(permitted) == 0 {
 			return fmt.Errorf("invalid $denyallow value: %s", value)
 		}
-		f.denyAllowDomains = permitted
+		rs := f.mutableRestrictions()
+		rs.restrictedToDomains = append(rs.restrictedToDomains, permitted...)
 		return nil
 
+	// $to -- limits the rule for selected request domains
+	case "to":
+		permitted, restricted, err := loadDomains(value, "|")
+		if err != nil {
+			return err
+		}
+		rs := f.mutableRestrictions()
+		rs.permittedToDomains = append(rs.permittedToDomains, permitted...)
+		rs.restrictedToDomains = append(rs.restrictedToDomains, restricted...)
+		return nil
+
+	// $method, the HTTP method filter.
+	case "method":
+		permitted, restricted, err := loadMethods(value)
+		rs := f.mutableRestrictions()
+		rs.permittedMethods, rs.restrictedMethods = permitted, restricted
+
+		return err
+
+	// $header, the response header filter.
+	case "header":
+		h, err := newHeaderMatcher(value)
+		f.mutableRestrictions().header = h
+
+		return err
+
 	// $ctag - limits the rule for selected "Client tags"
 	case "ctag":
 		permitted, restricted, err := loadCTags(value, "|")
 		if err == nil {
-			f.permittedClientTags = permitted
-			f.restrictedClientTags = restricted
+			rs := f.mutableRestrictions()
+			rs.permittedClientTags, rs.restrictedClientTags = permitted, restricted
 		}
 		return err
 
@@ -795,8 +1161,8 @@ func (f *NetworkRule) loadOption(name, value string) error {
 	case "client":
 		permitted, restricted, err := loadClients(value, '|')
 		if err == nil {
-			f.permittedClients = permitted
-			f.restrictedClients = restricted
+			rs := f.mutableRestrictions()
+			rs.permittedClients, rs.restrictedClients = permitted, restricted
 		}
 		return err
 
@@ -805,6 +1171,8 @@ func (f *NetworkRule) loadOption(name, value string) error {
 		return f.setOptionEnabled(OptionElemhide, true)
 	case "generichide":
 		return f.setOptionEnabled(OptionGenerichide, true)
+	case "specifichide":
+		return f.setOptionEnabled(OptionSpecifichide, true)
 	case "genericblock":
 		return f.setOptionEnabled(OptionGenericblock, true)
 	case "jsinject":
@@ -841,6 +1209,16 @@ func (f *NetworkRule) loadOption(name, value string) error {
 	case "popup":
 		return f.setOptionEnabled(OptionPopup, true)
 
+	// $all, the shortcut for $document, $popup, and all content types.
+	case "all":
+		if f.Whitelist {
+			return errors.Error("$all cannot be used in exception rules")
+		}
+
+		f.setRequestType(typesAll, true)
+
+		return f.setOptionEnabled(OptionPopup, true)
+
 	// $empty and $mp4
 	// TODO: Deprecate in favor of $redirect
 	case "empty":
@@ -884,7 +1262,109 @@ func (f *NetworkRule) loadOption(name, value string) error {
 		return nil
 	}
 
-	return fmt.Errorf("unknown filter modifier: %s=%s", name, value)
+	return f.loadValueModifier(name, value)
+}
+
+// loadValueModifier loads the modifier modifying the requests or the responses.
+// See [valueModifiers].
+func (f *NetworkRule) loadValueModifier(name, value string) (err error) {
+	switch name {
+	// $redirect and its uBlock Origin version $redirect-rule.
+	case "redirect":
+		if value == "" && !f.Whitelist {
+			return errors.Error("empty $redirect value")
+		} else if value != "" {
+			f.mutableValues().redirect = value
+		}
+
+		return f.setOptionEnabled(OptionRedirect, true)
+
+	// $removeparam, the removal of the query parameters.
+	case "removeparam":
+		var rp *removeParam
+		rp, err = newRemoveParam(value)
+		if err != nil {
+			return err
+		}
+
+		f.mutableValues().removeParam = rp
+
+		return f.setOptionEnabled(OptionRemoveParam, true)
+
+	// $removeheader, the removal of the request and response headers.
+	case "removeheader":
+		var rh *removeHeader
+		rh, err = newValueModifier("removeheader", value, f.Whitelist, newRemoveHeader)
+		if err != nil {
+			return err
+		}
+
+		f.mutableValues().removeHeader = rh
+
+		return f.setOptionEnabled(OptionRemoveHeader, true)
+
+	// $permissions, the Permissions-Policy directives of the documents.
+	case "permissions":
+		var p *permissions
+		p, err = newValueModifier("permissions", value, f.Whitelist, newPermissions)
+		if err != nil {
+			return err
+		}
+
+		f.mutableValues().permissions = p
+
+		return f.setOptionEnabled(OptionPermissions, true)
+
+	// $urltransform, the modification of the request URL.
+	case urlTransformOption:
+		var ut *urlTransform
+		ut, err = newValueModifier(urlTransformOption, value, f.Whitelist, newURLTransform)
+		if err != nil {
+			return err
+		}
+
+		f.mutableValues().urlTransform = ut
+
+		return f.setOptionEnabled(OptionURLTransform, true)
+
+	// $referrerpolicy, the Referrer-Policy of the documents.
+	case "referrerpolicy":
+		var rp *referrerPolicy
+		rp, err = newValueModifier("referrerpolicy", value, f.Whitelist, newReferrerPolicy)
+		if err != nil {
+			return err
+		}
+
+		f.mutableValues().referrerPolicy = rp
+
+		return f.setOptionEnabled(OptionReferrerPolicy, true)
+
+	// $jsonprune, the removal of the properties from the JSON responses.
+	case "jsonprune":
+		var jp *jsonPrune
+		jp, err = newValueModifier("jsonprune", value, f.Whitelist, newJSONPrune)
+		if err != nil {
+			return err
+		}
+
+		f.mutableValues().jsonPrune = jp
+
+		return f.setOptionEnabled(OptionJSONPrune, true)
+
+	// $hls, the removal of the segments from the HLS playlists.
+	case "hls":
+		var h *hls
+		h, err = newValueModifier("hls", value, f.Whitelist, newHLS)
+		if err != nil {
+			return err
+		}
+
+		f.mutableValues().hls = h
+
+		return f.setOptionEnabled(OptionHLS, true)
+	default:
+		return fmt.Errorf("unknown filter modifier: %s=%s", name, value)
+	}
 }
 
 // loadShortcut extracts a shortcut from the pattern.
@@ -969,6 +1449,112 @@ func findRegexpShortcut(pattern string) string {
 	return longest
 }
 
+// normalizePatternHostname converts the internationalized hostname in the
+// pattern to its ASCII form, so that it matches the normalized request URLs.
+// The hostname is the part of the pattern up to the first special character,
+// which follows a "||", "|", or "scheme://" prefix, like in "||пример.рф^" or
+// "|http://пример.рф/".  A pattern without a prefix, like "пример.рф^", is only
+// converted if it starts with a valid hostname followed by a "^", a "/", or
+// nothing.  Other patterns are returned as is.
+func normalizePatternHostname(pattern string) (normalized string) {
+	start, anchored := patternHostnameStart(pattern)
+	rest := pattern[start:]
+
+	end := strings.IndexAny(rest, "^/*|:?")
+	if end < 0 {
+		end = len(rest)
+	} else if !anchored && rest[end] != '^' && rest[end] != '/' {
+		return pattern
+	}
+
+	hostname := rest[:end]
+	if isASCII(hostname) {
+		return pattern
+	}
+
+	ascii := ufnet.NormalizeDomain(hostname)
+	if !anchored && (!strings.Contains(ascii, ".") || !ufnet.IsDomainName(ascii)) {
+		return pattern
+	}
+
+	return pattern[:start] + ascii + rest[end:]
+}
+
+// patternHostnameStart returns the index of the hostname in pattern.  anchored
+// is true if the hostname follows a "||", "|", or "scheme://" prefix.
+func patternHostnameStart(pattern string) (start int, anchored bool) {
+	if strings.HasPrefix(pattern, MaskStartURL) {
+		return len(MaskStartURL), true
+	}
+
+	rest, anchored := strings.CutPrefix(pattern, MaskPipe)
+	start = len(pattern) - len(rest)
+
+	scheme, _, ok := strings.Cut(rest, "://")
+	if ok && isScheme(scheme) {
+		return start + len(scheme) + len("://"), true
+	}
+
+	return start, anchored
+}
+
+// isScheme returns true if s may be a URL scheme or an empty string, as in the
+// "://hostname" patterns.
+func isScheme(s string) (ok bool) {
+	for i := range len(s) {
+		c := s[i]
+		if !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') &&
+			c != '+' && c != '-' && c != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parsePatternPrefix returns the IP network if pattern is a CIDR prefix, which
+// may be enclosed in "||" and "^", for example "192.0.2.0/24" or
+// "||[2001:db8::]/32^".  The address of an IPv6 prefix may be enclosed in
+// brackets.
+func parsePatternPrefix(pattern string) (ipNet netip.Prefix, ok bool) {
+	pattern = strings.TrimPrefix(pattern, MaskStartURL)
+	pattern = strings.TrimSuffix(pattern, MaskPipe)
+	pattern = strings.TrimSuffix(pattern, MaskSeparator)
+
+	if rest, isBracketed := strings.CutPrefix(pattern, "["); isBracketed {
+		addr, prefixLen, found := strings.Cut(rest, "]/")
+		if !found {
+			return netip.Prefix{}, false
+		}
+
+		pattern = addr + "/" + prefixLen
+	}
+
+	// Check the characters first, since most patterns containing a slash are
+	// paths and parsing errors allocate.
+	if !strings.Contains(pattern, "/") || strings.Trim(pattern, "0123456789abcdefABCDEF.:/") != "" {
+		return netip.Prefix{}, false
+	}
+
+	ipNet, err := netip.ParsePrefix(pattern)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+
+	return ipNet.Masked(), true
+}
+
+// isASCII returns true if s only contains ASCII characters.
+func isASCII(s string) (ok bool) {
+	for i := range len(s) {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
 // parseRuleText splits the rule's text into:
 //
 //	pattern, which is a basic rule pattern that can be easily converted into a regular expression;
@@ -988,30 +1574,33 @@ func parseRuleText(ruleText string) (pattern, options string, whitelist bool, er
 	// Avoid parsing options inside of a regex rule.
 	if strings.HasPrefix(ruleText, maskRegexRule) &&
 		strings.HasSuffix(ruleText, maskRegexRule) &&
-		!strings.Contains(ruleText, replaceOption+"=") {
+		!strings.Contains(ruleText, replaceOption+"=") &&
+		!strings.Contains(ruleText, urlTransformOption+"=") {
 		return ruleText, "", whitelist, nil
 	}
 
-	hasEscaped := false
-	for idx := len(ruleText) - 2; idx >= 0; idx-- {
-		c := ruleText[idx]
-		if c != optionsDelimiter {
-			continue
-		} else if !hasEscaped && idx > 0 && ruleText[idx-1] == escapeCharacter {
-			hasEscaped = true
-
-			continue
-		}
+	idx := optionsDelimiterIndex(ruleText)
+	if idx == -1 {
+		return ruleText, "", whitelist, nil
+	}
 
-		ruleText, options = ruleText[:idx], ruleText[idx+1:]
-		if hasEscaped {
-			options = reEscapedOptionsDelimiter.ReplaceAllString(options, string(optionsDelimiter))
-		}
+	ruleText, options = ruleText[:idx], ruleText[idx+1:]
+	if strings.Contains(options, `\$`) {
+		options = reEscapedOptionsDelimiter.ReplaceAllString(options, string(optionsDelimiter))
+	}
 
-		// Exit the loop since the options delimiter has been found.
-		break
+	return ruleText, options, whitelist, nil
+}
 
+// optionsDelimiterIndex returns the index of the last options delimiter in
+// ruleText, which isn't escaped, or -1 if there is none.  The last character of
+// ruleText is never considered a delimiter.
+func optionsDelimiterIndex(ruleText string) (idx int) {
+	for idx = len(ruleText) - 2; idx >= 0; idx-- {
+		if ruleText[idx] == optionsDelimiter && (idx == 0 || ruleText[idx-1] != escapeCharacter) {
+			return idx
+		}
 	}
 
-	return ruleText, options, whitelist, nil
+	return -1
 }
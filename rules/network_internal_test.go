@@ -1,7 +1,9 @@
 package rules
 
 import (
+	"regexp"
 	"testing"
+	"unsafe"
 
 	"github.com/AdguardTeam/golibs/testutil"
 	"github.com/stretchr/testify/assert"
@@ -202,7 +204,7 @@ func TestNetworkRule_clientTagRules(t *testing.T) {
 	f, err := NewNetworkRule("||example.org^$ctag=pc", 0)
 	assert.Nil(t, err)
 	assert.NotNil(t, f)
-	assert.Equal(t, []string{"pc"}, f.permittedClientTags)
+	assert.Equal(t, []string{"pc"}, f.getRestrictions().permittedClientTags)
 
 	r := NewRequestForHostname("example.org")
 	r.SortedClientTags = []string{"pc"}
@@ -212,7 +214,7 @@ func TestNetworkRule_clientTagRules(t *testing.T) {
 	assert.False(t, f.Match(r))
 
 	f, _ = NewNetworkRule("||example.org^$ctag=phone|pc", 0)
-	assert.Equal(t, []string{"pc", "phone"}, f.permittedClientTags)
+	assert.Equal(t, []string{"pc", "phone"}, f.getRestrictions().permittedClientTags)
 
 	r.SortedClientTags = []string{"phone", "other"}
 	assert.True(t, f.Match(r))
@@ -221,8 +223,8 @@ func TestNetworkRule_clientTagRules(t *testing.T) {
 	assert.False(t, f.Match(r))
 
 	f, _ = NewNetworkRule("||example.org^$ctag=~phone|pc", 0)
-	assert.Equal(t, []string{"pc"}, f.permittedClientTags)
-	assert.Equal(t, []string{"phone"}, f.restrictedClientTags)
+	assert.Equal(t, []string{"pc"}, f.getRestrictions().permittedClientTags)
+	assert.Equal(t, []string{"phone"}, f.getRestrictions().restrictedClientTags)
 
 	r.SortedClientTags = []string{"phone", "pc"}
 	assert.False(t, f.Match(r))
@@ -285,6 +287,176 @@ func TestLoadInvalidClients(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestNetworkRule_compiledRegex(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		want    *regexp.Regexp
+		name    string
+		in      string
+		wantStr string
+	}{{
+		want:    regexAny,
+		name:    "any",
+		in:      "$domain=example.org",
+		wantStr: "",
+	}, {
+		want:    regexInvalid,
+		name:    "invalid",
+		in:      "/(?!x)ads/",
+		wantStr: "",
+	}, {
+		want:    nil,
+		name:    "valid",
+		in:      "/ads[0-9]/",
+		wantStr: "(?i)ads[0-9]",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := NewNetworkRule(tc.in, 1)
+			require.NoError(t, err)
+			require.Nil(t, f.regex.Load())
+
+			re := f.compiledRegex()
+			if tc.want != nil {
+				assert.Same(t, tc.want, re)
+			} else {
+				assert.Equal(t, tc.wantStr, re.String())
+			}
+
+			assert.Same(t, re, f.compiledRegex())
+		})
+	}
+}
+
+func TestNetworkRule_sharedText(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		in   string
+	}{{
+		name: "hostname",
+		in:   "||example.org^",
+	}, {
+		name: "domains",
+		in:   "||example.org^$domain=a.example|~b.example",
+	}, {
+		name: "exception",
+		in:   "@@/ads/banner$to=example.org|~ads.example.org",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := NewNetworkRule(tc.in, 1)
+			require.NoError(t, err)
+
+			assert.True(t, isSubstring(f.pattern, f.RuleText))
+			assert.True(t, isSubstring(f.Shortcut, f.RuleText))
+
+			r := f.getRestrictions()
+			for _, domains := range [][]string{
+				r.permittedDomains,
+				r.restrictedDomains,
+				r.permittedToDomains,
+				r.restrictedToDomains,
+			} {
+				for _, d := range domains {
+					assert.True(t, isSubstring(d, f.RuleText), d)
+				}
+			}
+		})
+	}
+}
+
+// isSubstring returns true if s is stored in the memory of text.
+func isSubstring(s, text string) (ok bool) {
+	start := uintptr(unsafe.Pointer(unsafe.StringData(text)))
+	p := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+
+	return p >= start && p+uintptr(len(s)) <= start+uintptr(len(text))
+}
+
+func TestNormalizePatternHostname(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		in   string
+		want string
+	}{{
+		name: "domain_anchor",
+		in:   "||пример.рф^",
+		want: "||xn--e1afmkfd.xn--p1ai^",
+	}, {
+		name: "pipe",
+		in:   "|пример.рф^",
+		want: "|xn--e1afmkfd.xn--p1ai^",
+	}, {
+		name: "scheme",
+		in:   "|http://пример.рф/путь",
+		want: "|http://xn--e1afmkfd.xn--p1ai/путь",
+	}, {
+		name: "empty_scheme",
+		in:   "://пример.рф:8080",
+		want: "://xn--e1afmkfd.xn--p1ai:8080",
+	}, {
+		name: "plain",
+		in:   "пример.рф^",
+		want: "xn--e1afmkfd.xn--p1ai^",
+	}, {
+		name: "plain_hostname",
+		in:   "пример.рф",
+		want: "xn--e1afmkfd.xn--p1ai",
+	}, {
+		name: "plain_word",
+		in:   "реклама",
+		want: "реклама",
+	}, {
+		name: "plain_wildcard",
+		in:   "пример.рф*",
+		want: "пример.рф*",
+	}, {
+		name: "path",
+		in:   "/реклама.рф/",
+		want: "/реклама.рф/",
+	}, {
+		name: "ascii",
+		in:   "||Example.org^",
+		want: "||Example.org^",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, normalizePatternHostname(tc.in))
+		})
+	}
+}
+
+func TestNetworkRule_restrictions(t *testing.T) {
+	t.Parallel()
+
+	f, err := NewNetworkRule("||example.org^$important", 1)
+	require.NoError(t, err)
+
+	assert.Nil(t, f.restrictions)
+	assert.Same(t, noRestrictions, f.getRestrictions())
+
+	f, err = NewNetworkRule("||example.org^$domain=example.com", 1)
+	require.NoError(t, err)
+
+	require.NotNil(t, f.restrictions)
+	assert.Equal(t, []string{"example.com"}, f.restrictions.permittedDomains)
+	assert.Empty(t, noRestrictions.permittedDomains)
+}
+
 func TestNetworkRule_negatesBadfilter(t *testing.T) {
 	testCases := []struct {
 		want      assert.BoolAssertionFunc
@@ -3,6 +3,7 @@ package rules_test
 import (
 	"fmt"
 	"net/netip"
+	"runtime"
 	"testing"
 
 	"github.com/AdguardTeam/urlfilter/rules"
@@ -52,6 +53,10 @@ func TestNetworkRule_options(t *testing.T) {
 		name:        "generichide",
 		option:      rules.OptionGenerichide,
 		wantEnabled: true,
+	}, {
+		name:        "specifichide",
+		option:      rules.OptionSpecifichide,
+		wantEnabled: true,
 	}, {
 		name:        "genericblock",
 		option:      rules.OptionGenericblock,
@@ -100,6 +105,10 @@ func TestNetworkRule_options(t *testing.T) {
 		name:        "popup",
 		option:      rules.OptionPopup,
 		wantEnabled: true,
+	}, {
+		name:        "all",
+		option:      rules.OptionPopup,
+		wantEnabled: true,
 	}, {
 		name:        "empty",
 		option:      rules.OptionEmpty,
@@ -219,6 +228,12 @@ func TestNetworkRule_invalidModifiers(t *testing.T) {
 	// Blacklist-only modifier
 	_, err = rules.NewNetworkRule("@@||example.org^$popup", 0)
 	assert.NotNil(t, err)
+
+	_, err = rules.NewNetworkRule("@@||example.org^$all", 0)
+	assert.NotNil(t, err)
+
+	_, err = rules.NewNetworkRule("||example.org^$specifichide", 0)
+	assert.NotNil(t, err)
 }
 
 func TestNetworkRule_Match_case(t *testing.T) {
@@ -268,6 +283,75 @@ func TestNetworkRule_Match_thirdParty(t *testing.T) {
 	assert.False(t, f.Match(r))
 }
 
+func TestNetworkRule_Match_strictThirdParty(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sameURL  = "https://example.org/"
+		subURL   = "https://sub.example.org/"
+		otherURL = "https://example.com/"
+	)
+
+	testCases := []struct {
+		name      string
+		rule      string
+		sourceURL string
+		want      bool
+	}{{
+		name:      "third_party_same",
+		rule:      "||example.org^$strict-third-party",
+		sourceURL: sameURL,
+		want:      false,
+	}, {
+		name:      "third_party_subdomain",
+		rule:      "||example.org^$strict-third-party",
+		sourceURL: subURL,
+		want:      true,
+	}, {
+		name:      "third_party_other",
+		rule:      "||example.org^$strict-third-party",
+		sourceURL: otherURL,
+		want:      true,
+	}, {
+		name:      "third_party_no_source",
+		rule:      "||example.org^$strict-third-party",
+		sourceURL: "",
+		want:      false,
+	}, {
+		name:      "first_party_same",
+		rule:      "||example.org^$strict-first-party",
+		sourceURL: sameURL,
+		want:      true,
+	}, {
+		name:      "first_party_subdomain",
+		rule:      "||example.org^$strict-first-party",
+		sourceURL: subURL,
+		want:      false,
+	}, {
+		name:      "first_party_negated",
+		rule:      "||example.org^$~strict-third-party",
+		sourceURL: subURL,
+		want:      false,
+	}, {
+		name:      "first_party_no_source",
+		rule:      "||example.org^$strict-first-party",
+		sourceURL: "",
+		want:      true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := rules.NewNetworkRule(tc.rule, 0)
+			require.NoError(t, err)
+
+			r := rules.NewRequest(sameURL, tc.sourceURL, rules.TypeOther)
+			assert.Equal(t, tc.want, f.Match(r))
+		})
+	}
+}
+
 func TestNetworkRule_Match_contentType(t *testing.T) {
 	// $script
 	f, err := rules.NewNetworkRule("||example.org^$script", 0)
@@ -308,6 +392,34 @@ func TestNetworkRule_Match_contentType(t *testing.T) {
 	assert.True(t, f.Match(r))
 }
 
+func TestNetworkRule_Match_all(t *testing.T) {
+	t.Parallel()
+
+	f, err := rules.NewNetworkRule("||example.org^$all", 0)
+	require.NoError(t, err)
+
+	for _, rt := range []rules.RequestType{
+		rules.TypeDocument,
+		rules.TypeSubdocument,
+		rules.TypeScript,
+		rules.TypeImage,
+		rules.TypeWebsocket,
+		rules.TypeOther,
+	} {
+		r := rules.NewRequest("https://example.org/", "", rt)
+		assert.True(t, f.Match(r), rt)
+	}
+
+	f, err = rules.NewNetworkRule("||example.org^$all,~script", 0)
+	require.NoError(t, err)
+
+	r := rules.NewRequest("https://example.org/", "", rules.TypeScript)
+	assert.False(t, f.Match(r))
+
+	r = rules.NewRequest("https://example.org/", "", rules.TypeDocument)
+	assert.True(t, f.Match(r))
+}
+
 func TestNetworkRule_Match_domainRestrictions(t *testing.T) {
 	// Just one permitted domain
 	f, err := rules.NewNetworkRule("||example.org^$domain=example.org", 0)
@@ -462,6 +574,92 @@ func TestNetworkRule_Match_denyallow(t *testing.T) {
 	}
 }
 
+func TestNetworkRule_Match_to(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		ruleText   string
+		requestURL string
+		match      bool
+	}{{
+		name:       "restricted",
+		ruleText:   "/banner/$to=~example.org",
+		requestURL: "https://example.org/banner/1.png",
+		match:      false,
+	}, {
+		name:       "restricted_subdomain",
+		ruleText:   "/banner/$to=~example.org",
+		requestURL: "https://sub.example.org/banner/1.png",
+		match:      false,
+	}, {
+		name:       "restricted_other",
+		ruleText:   "/banner/$to=~example.org",
+		requestURL: "https://example.net/banner/1.png",
+		match:      true,
+	}, {
+		name:       "permitted",
+		ruleText:   "/banner/$to=example.org|example.net",
+		requestURL: "https://example.net/banner/1.png",
+		match:      true,
+	}, {
+		name:       "permitted_other",
+		ruleText:   "/banner/$to=example.org|example.net",
+		requestURL: "https://example.com/banner/1.png",
+		match:      false,
+	}, {
+		name:       "mixed",
+		ruleText:   "/banner/$to=example.org|~sub.example.org",
+		requestURL: "https://sub.example.org/banner/1.png",
+		match:      false,
+	}, {
+		name:       "wildcard_tld",
+		ruleText:   "/banner/$to=example.*",
+		requestURL: "https://example.co.uk/banner/1.png",
+		match:      true,
+	}, {
+		name:       "wildcard_tld_not_public_suffix",
+		ruleText:   "/banner/$to=example.*",
+		requestURL: "https://example.local/banner/1.png",
+		match:      false,
+	}, {
+		name:       "patternless",
+		ruleText:   "$to=example.org",
+		requestURL: "https://example.org/",
+		match:      true,
+	}, {
+		name:       "with_denyallow",
+		ruleText:   "*$to=~example.org,denyallow=example.net",
+		requestURL: "https://example.net/",
+		match:      false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := rules.NewNetworkRule(tc.ruleText, 0)
+			require.NoError(t, err)
+
+			r := rules.NewRequest(tc.requestURL, "", rules.TypeImage)
+			assert.Equal(t, tc.match, f.Match(r))
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		for _, text := range []string{
+			"/banner/$to",
+			"/banner/$to=",
+			"/banner/$to=example.org|~",
+		} {
+			_, err := rules.NewNetworkRule(text, 0)
+			assert.Error(t, err, text)
+		}
+	})
+}
+
 func TestNetworkRule_Match_wildcardTLDRestrictions(t *testing.T) {
 	f, err := rules.NewNetworkRule("||example.org^$domain=example.*", 0)
 	assert.Nil(t, err)
@@ -592,6 +790,8 @@ func TestNetworkRule_IsHigherPriority(t *testing.T) {
 	compareRulesPriority(t, "||example.org$ctag=123,client=123", "||example.org$script", true)
 	compareRulesPriority(t, "||example.org$ctag=123,client=123,dnstype=AAAA", "||example.org$client=123,dnstype=AAAA", true)
 	compareRulesPriority(t, "||example.org$denyallow=com", "||example.org", true)
+	compareRulesPriority(t, "||example.org$to=com", "||example.org", true)
+	compareRulesPriority(t, "||example.org", "||example.org$to=com", false)
 }
 
 func TestNetworkRule_Match_source(t *testing.T) {
@@ -676,6 +876,156 @@ func TestNetworkRule_IsHostLevelNetworkRule(t *testing.T) {
 	assert.False(t, r.IsHostLevelNetworkRule())
 }
 
+func TestNetworkRule_IsRestricted(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		rule string
+		want bool
+	}{{
+		rule: "||example.org^",
+		want: false,
+	}, {
+		rule: "||example.org^$important,third-party",
+		want: false,
+	}, {
+		rule: "||example.org^$client=127.0.0.1",
+		want: true,
+	}, {
+		rule: "||example.org^$ctag=device_pc",
+		want: true,
+	}, {
+		rule: "||example.org^$dnstype=AAAA",
+		want: true,
+	}, {
+		rule: "||example.org^$denyallow=example.com",
+		want: true,
+	}, {
+		rule: "||example.org^$domain=~example.com",
+		want: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.rule, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.rule, testFilterListID)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, r.IsRestricted())
+		})
+	}
+}
+
+func TestNetworkRule_HostnamePattern(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		rule           string
+		wantHost       string
+		wantSubdomains bool
+		wantOK         bool
+	}{{
+		rule:           "||example.org^",
+		wantHost:       "example.org",
+		wantSubdomains: true,
+		wantOK:         true,
+	}, {
+		rule:           "||Sub.Example.ORG^|",
+		wantHost:       "sub.example.org",
+		wantSubdomains: true,
+		wantOK:         true,
+	}, {
+		rule:           "||example.org/*$important",
+		wantHost:       "example.org",
+		wantSubdomains: true,
+		wantOK:         true,
+	}, {
+		rule:           "||example.org",
+		wantHost:       "",
+		wantSubdomains: false,
+		wantOK:         false,
+	}, {
+		rule:           "|example.org^",
+		wantHost:       "example.org",
+		wantSubdomains: false,
+		wantOK:         true,
+	}, {
+		rule:           "|http://example.org^",
+		wantHost:       "",
+		wantSubdomains: false,
+		wantOK:         false,
+	}, {
+		rule:           "||example.org^path",
+		wantHost:       "",
+		wantSubdomains: false,
+		wantOK:         false,
+	}, {
+		rule:           "||*.example.org^",
+		wantHost:       "",
+		wantSubdomains: false,
+		wantOK:         false,
+	}, {
+		rule:           "/example/",
+		wantHost:       "",
+		wantSubdomains: false,
+		wantOK:         false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.rule, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.rule, testFilterListID)
+			require.NoError(t, err)
+
+			host, subdomains, ok := r.HostnamePattern()
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantHost, host)
+			assert.Equal(t, tc.wantSubdomains, subdomains)
+		})
+	}
+}
+
+func TestNetworkRule_RegexpText(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		rule     string
+		wantText string
+		wantOK   bool
+	}{{
+		rule:     "/ads[0-9]+/",
+		wantText: "(?i)ads[0-9]+",
+		wantOK:   true,
+	}, {
+		rule:     "/ads[0-9]+/$match-case",
+		wantText: "ads[0-9]+",
+		wantOK:   true,
+	}, {
+		rule:     "|https://example.org^",
+		wantText: `(?i)^https:\/\/example\.org` + rules.RegexSeparator,
+		wantOK:   true,
+	}, {
+		rule:     "*$domain=example.org",
+		wantText: "",
+		wantOK:   false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.rule, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.rule, testFilterListID)
+			require.NoError(t, err)
+
+			text, ok := r.RegexpText()
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantText, text)
+		})
+	}
+}
+
 func TestNetworkRule_Match_ip(t *testing.T) {
 	f, err := rules.NewNetworkRule("://104.154.", -1)
 	assert.Nil(t, err)
@@ -699,6 +1049,210 @@ func TestNetworkRule_Match_ip(t *testing.T) {
 	assert.False(t, f.Match(r))
 }
 
+func TestNetworkRule_Match_idn(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		rule      string
+		url       string
+		sourceURL string
+		want      bool
+	}{{
+		name:      "pattern_unicode_request",
+		rule:      "||" + testIDNHostname + "^",
+		url:       "https://www." + testIDNHostname + "/",
+		sourceURL: "",
+		want:      true,
+	}, {
+		name:      "pattern_ascii_request",
+		rule:      "||" + testIDNHostname + "^",
+		url:       "https://" + testIDNHostnameASCII + "/",
+		sourceURL: "",
+		want:      true,
+	}, {
+		name:      "pattern_other",
+		rule:      "||" + testIDNHostname + "^",
+		url:       "https://example.org/",
+		sourceURL: "",
+		want:      false,
+	}, {
+		name:      "pattern_scheme",
+		rule:      "|https://" + testIDNHostname + "/",
+		url:       "https://" + testIDNHostname + "/page",
+		sourceURL: "",
+		want:      true,
+	}, {
+		name:      "pattern_scheme_other",
+		rule:      "|http://" + testIDNHostname + "/",
+		url:       "https://" + testIDNHostname + "/page",
+		sourceURL: "",
+		want:      false,
+	}, {
+		name:      "pattern_no_scheme",
+		rule:      "://" + testIDNHostname + "^",
+		url:       "https://" + testIDNHostnameASCII + "/",
+		sourceURL: "",
+		want:      true,
+	}, {
+		name:      "pattern_plain",
+		rule:      testIDNHostname + "^",
+		url:       "https://www." + testIDNHostname + "/",
+		sourceURL: "",
+		want:      true,
+	}, {
+		name:      "pattern_plain_path",
+		rule:      testIDNHostname + "/ads",
+		url:       "https://" + testIDNHostname + "/ads/1.js",
+		sourceURL: "",
+		want:      true,
+	}, {
+		name:      "pattern_plain_hostname",
+		rule:      testIDNHostname,
+		url:       "https://" + testIDNHostname + "/",
+		sourceURL: "",
+		want:      true,
+	}, {
+		name:      "domain",
+		rule:      "||example.org^$domain=" + testIDNHostname,
+		url:       "https://example.org/",
+		sourceURL: "https://" + testIDNHostnameASCII + "/",
+		want:      true,
+	}, {
+		name:      "domain_unicode_source",
+		rule:      "||example.org^$domain=" + testIDNHostnameASCII,
+		url:       "https://example.org/",
+		sourceURL: "https://" + testIDNHostname + "/",
+		want:      true,
+	}, {
+		name:      "denyallow",
+		rule:      "*$denyallow=" + testIDNHostname + ",domain=example.org",
+		url:       "https://" + testIDNHostnameASCII + "/",
+		sourceURL: "https://example.org/",
+		want:      false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := rules.NewNetworkRule(tc.rule, -1)
+			require.NoError(t, err)
+
+			r := rules.NewRequest(tc.url, tc.sourceURL, rules.TypeOther)
+			assert.Equal(t, tc.want, f.Match(r))
+		})
+	}
+}
+
+func TestNetworkRule_Match_ipHostname(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		rule string
+		url  string
+		want bool
+	}{{
+		name: "ipv4",
+		rule: "||192.0.2.1^",
+		url:  "http://192.0.2.1:8080/ad.js",
+		want: true,
+	}, {
+		name: "ipv6",
+		rule: "||[2001:db8::1]^",
+		url:  "http://[2001:db8::1]:8080/ad.js",
+		want: true,
+	}, {
+		name: "cidr_ipv6_userinfo",
+		rule: "||[2001:db8::]/32^",
+		url:  "http://user:pass@[2001:db8::1]/ad.js",
+		want: true,
+	}, {
+		name: "ipv6_other",
+		rule: "||[2001:db8::1]^",
+		url:  "http://[2001:db8::2]/ad.js",
+		want: false,
+	}, {
+		name: "cidr_ipv4",
+		rule: "192.0.2.0/24",
+		url:  "http://192.0.2.5/ad.js",
+		want: true,
+	}, {
+		name: "cidr_ipv4_anchored",
+		rule: "||192.0.2.0/24^",
+		url:  "https://192.0.2.5:8443/",
+		want: true,
+	}, {
+		name: "cidr_ipv4_other",
+		rule: "192.0.2.0/24",
+		url:  "http://198.51.100.1/ad.js",
+		want: false,
+	}, {
+		name: "cidr_ipv4_mapped",
+		rule: "192.0.2.0/24",
+		url:  "http://[::ffff:192.0.2.5]/ad.js",
+		want: true,
+	}, {
+		name: "cidr_ipv4_domain",
+		rule: "192.0.2.0/24",
+		url:  "http://example.org/192.0.2.0/24",
+		want: false,
+	}, {
+		name: "cidr_ipv6",
+		rule: "2001:db8::/32",
+		url:  "http://[2001:db8::1]/ad.js",
+		want: true,
+	}, {
+		name: "cidr_ipv6_brackets",
+		rule: "||[2001:db8::]/32^",
+		url:  "http://[2001:db8:1::1]:8080/ad.js",
+		want: true,
+	}, {
+		name: "cidr_ipv6_other",
+		rule: "[2001:db8::]/32",
+		url:  "http://[2001:db9::1]/ad.js",
+		want: false,
+	}, {
+		name: "cidr_ipv6_ipv4",
+		rule: "2001:db8::/32",
+		url:  "http://192.0.2.1/ad.js",
+		want: false,
+	}, {
+		name: "path",
+		rule: "/ads/1/",
+		url:  "http://example.org/ads/1/",
+		want: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := rules.NewNetworkRule(tc.rule, -1)
+			require.NoError(t, err)
+
+			r := rules.NewRequest(tc.url, "", rules.TypeOther)
+			assert.Equal(t, tc.want, f.Match(r))
+		})
+	}
+
+	t.Run("hostname_request", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := rules.NewNetworkRule("||[2001:db8::]/32^", -1)
+		require.NoError(t, err)
+
+		assert.True(t, f.Match(rules.NewRequestForHostname("2001:db8::1")))
+		assert.False(t, f.Match(rules.NewRequestForHostname("example.org")))
+
+		f, err = rules.NewNetworkRule("||[2001:db8::1]^", -1)
+		require.NoError(t, err)
+
+		assert.True(t, f.Match(rules.NewRequestForHostname("2001:db8::1")))
+	})
+}
+
 // compareRulesPriority is a helper function to compare the priority of the two
 // given rules.
 func compareRulesPriority(tb testing.TB, left, right string, expected bool) {
@@ -767,3 +1321,64 @@ func FuzzNetworkRule_Match(f *testing.F) {
 		})
 	})
 }
+
+// BenchmarkNetworkRule_heapAlloc measures the heap memory retained by parsed
+// network rules.  It reports rule_bytes, the size of allocated heap objects per
+// rule after parsing, not including the rule text.
+func BenchmarkNetworkRule_heapAlloc(b *testing.B) {
+	const n = 1_000_000
+
+	texts := make([]string, 0, n)
+	for i := range n {
+		var text string
+		switch i % 20 {
+		case 0, 1:
+			text = fmt.Sprintf("||ads%d.example^$domain=a%d.example|~b.example", i, i)
+		case 2:
+			text = fmt.Sprintf("/banner%d[0-9]+/", i)
+		case 3:
+			text = fmt.Sprintf("||x%d.example^$client=1.2.3.4,ctag=device_pc", i)
+		default:
+			text = fmt.Sprintf("||d%d.example^", i)
+		}
+
+		texts = append(texts, text)
+	}
+
+	parsed := make([]*rules.NetworkRule, n)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		clear(parsed)
+		runtime.GC()
+		initial := heapAlloc(b)
+
+		for i, text := range texts {
+			var err error
+			parsed[i], err = rules.NewNetworkRule(text, testFilterListID)
+			require.NoError(b, err)
+		}
+
+		b.ReportMetric((heapAlloc(b)-initial)/n, "rule_bytes")
+	}
+
+	// Most recent results:
+	//	goos: linux
+	//	goarch: amd64
+	//	pkg: github.com/AdguardTeam/urlfilter/rules
+	//	cpu: Intel(R) Xeon(R) Processor
+	//	BenchmarkNetworkRule_heapAlloc    	       1	1253468151 ns/op	       175.6 rule_bytes	241234960 B/op	 3349965 allocs/op
+}
+
+// heapAlloc is a helper that returns the current heap-allocated bytes as
+// counted by the runtime after a garbage collection.
+func heapAlloc(tb testing.TB) (heap float64) {
+	tb.Helper()
+
+	runtime.GC()
+
+	m := &runtime.MemStats{}
+	runtime.ReadMemStats(m)
+
+	return float64(m.HeapAlloc)
+}
@@ -0,0 +1,96 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// permissions is the parsed value of the $permissions modifier.  It is the list
+// of the Permissions-Policy directives, which must be added to the response.
+//
+// See https://adguard.com/kb/general/ad-filtering/create-own-filters/#permissions-modifier.
+type permissions struct {
+	// modifierValue is the normalized value of the modifier, which is the
+	// directives joined with ", ", as in the Permissions-Policy header.
+	modifierValue
+
+	// directives are the directives in the "feature=allowlist" form with the
+	// lowercased feature names.
+	directives []string
+}
+
+// newPermissions parses the non-empty value of the $permissions modifier.  The
+// directives are separated by "|" or by the escaped ",".
+func newPermissions(value string) (p *permissions, err error) {
+	p = &permissions{}
+	for _, d := range strings.FieldsFunc(value, isPermissionsSeparator) {
+		d, err = normalizePermissionsDirective(d)
+		if err != nil {
+			return nil, fmt.Errorf("$permissions: %w", err)
+		}
+
+		p.directives = append(p.directives, d)
+	}
+
+	if len(p.directives) == 0 {
+		return nil, fmt.Errorf("invalid $permissions value: %q", value)
+	}
+
+	p.value = strings.Join(p.directives, ", ")
+
+	return p, nil
+}
+
+// isPermissionsSeparator returns true if c separates the directives in the
+// value of the $permissions modifier.
+func isPermissionsSeparator(c rune) (ok bool) {
+	return c == '|' || c == ','
+}
+
+// normalizePermissionsDirective validates the Permissions-Policy directive d
+// and returns it with the lowercased feature name and without the surrounding
+// spaces.
+func normalizePermissionsDirective(d string) (normalized string, err error) {
+	feature, allowlist, ok := strings.Cut(strings.TrimSpace(d), "=")
+	if !ok || allowlist == "" {
+		return "", fmt.Errorf("invalid directive %q", d)
+	}
+
+	feature = strings.ToLower(feature)
+	if feature == "" || strings.Trim(feature, "abcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+		return "", fmt.Errorf("invalid feature in directive %q", d)
+	}
+
+	if allowlist != "*" && (allowlist[0] != '(' || allowlist[len(allowlist)-1] != ')') {
+		return "", fmt.Errorf("invalid allowlist in directive %q", d)
+	}
+
+	return feature + "=" + allowlist, nil
+}
+
+// permissionsPolicy returns the Permissions-Policy header value with the
+// directives of the $permissions rules.  If several directives are for the same
+// feature, the one of the $important rule or the first one wins.
+func permissionsPolicy(permissionsRules []*NetworkRule) (policy string) {
+	var directives []string
+	seen := map[string]struct{}{}
+	for _, important := range []bool{true, false} {
+		for _, r := range permissionsRules {
+			if r.IsOptionEnabled(OptionImportant) != important {
+				continue
+			}
+
+			for _, d := range r.getValues().permissions.directives {
+				feature, _, _ := strings.Cut(d, "=")
+				if _, ok := seen[feature]; ok {
+					continue
+				}
+
+				seen[feature] = struct{}{}
+				directives = append(directives, d)
+			}
+		}
+	}
+
+	return strings.Join(directives, ", ")
+}
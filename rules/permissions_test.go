@@ -0,0 +1,155 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchingResult_PermissionsPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		want  string
+		rules []string
+	}{{
+		name:  "none",
+		want:  "",
+		rules: nil,
+	}, {
+		name:  "single",
+		want:  "interest-cohort=()",
+		rules: []string{"||example.org^$permissions=Interest-Cohort=()"},
+	}, {
+		name: "several",
+		want: "interest-cohort=(), browsing-topics=(), geolocation=(self)",
+		rules: []string{
+			"||example.org^$permissions=interest-cohort=()",
+			`||example.org^$permissions=browsing-topics=()\,geolocation=(self)`,
+		},
+	}, {
+		name: "same_feature",
+		want: "geolocation=()",
+		rules: []string{
+			"||example.org^$permissions=geolocation=(self)",
+			"||example.org^$permissions=geolocation=(),important",
+		},
+	}, {
+		name: "exception",
+		want: "interest-cohort=()",
+		rules: []string{
+			"||example.org^$permissions=interest-cohort=()",
+			"||example.org^$permissions=browsing-topics=()|geolocation=()",
+			"@@||example.org^$permissions=browsing-topics=()|geolocation=()",
+		},
+	}, {
+		name: "exception_all",
+		want: "",
+		rules: []string{
+			"||example.org^$permissions=interest-cohort=()",
+			"@@||example.org^$permissions",
+		},
+	}, {
+		name: "exception_important",
+		want: "interest-cohort=()",
+		rules: []string{
+			"||example.org^$permissions=interest-cohort=(),important",
+			"@@||example.org^$permissions",
+		},
+	}, {
+		name: "badfilter",
+		want: "",
+		rules: []string{
+			"||example.org^$permissions=interest-cohort=()",
+			"||example.org^$permissions=interest-cohort=(),badfilter",
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := rules.NewMatchingResult(newNetworkRules(t, tc.rules), nil)
+			assert.Equal(t, tc.want, res.PermissionsPolicy())
+			assert.Nil(t, res.GetBasicResult())
+		})
+	}
+}
+
+func TestNetworkRule_permissions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		in         string
+		wantErrMsg string
+	}{{
+		name:       "single",
+		in:         "||example.org^$permissions=interest-cohort=()",
+		wantErrMsg: "",
+	}, {
+		name:       "several",
+		in:         `||example.org^$permissions=browsing-topics=()\,geolocation=(self)`,
+		wantErrMsg: "",
+	}, {
+		name:       "exception_all",
+		in:         "@@||example.org^$permissions",
+		wantErrMsg: "",
+	}, {
+		name:       "empty",
+		in:         "||example.org^$permissions",
+		wantErrMsg: "empty $permissions value",
+	}, {
+		name:       "separator_only",
+		in:         "||example.org^$permissions=|",
+		wantErrMsg: `invalid $permissions value: "|"`,
+	}, {
+		name:       "no_allowlist",
+		in:         "||example.org^$permissions=interest-cohort",
+		wantErrMsg: `$permissions: invalid directive "interest-cohort"`,
+	}, {
+		name:       "empty_allowlist",
+		in:         "||example.org^$permissions=interest-cohort=",
+		wantErrMsg: `$permissions: invalid directive "interest-cohort="`,
+	}, {
+		name:       "bad_feature",
+		in:         "||example.org^$permissions=interest cohort=()",
+		wantErrMsg: `$permissions: invalid feature in directive "interest cohort=()"`,
+	}, {
+		name:       "bad_allowlist",
+		in:         "||example.org^$permissions=geolocation=self",
+		wantErrMsg: `$permissions: invalid allowlist in directive "geolocation=self"`,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.in, -1)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+			if err != nil {
+				return
+			}
+
+			assert.True(t, r.IsOptionEnabled(rules.OptionPermissions))
+			assert.Nil(t, rules.GetDNSBasicRule([]*rules.NetworkRule{r}))
+		})
+	}
+
+	t.Run("match", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := rules.NewNetworkRule("||example.org^$permissions=interest-cohort=()", -1)
+		require.NoError(t, err)
+
+		doc := rules.NewRequest("https://example.org/", "", rules.TypeDocument)
+		assert.True(t, r.Match(doc))
+
+		script := rules.NewRequest("https://example.org/ad.js", "", rules.TypeScript)
+		assert.False(t, r.Match(script))
+	})
+}
@@ -0,0 +1,53 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// referrerPolicies are the valid values of the $referrerpolicy modifier.
+//
+// See https://www.w3.org/TR/referrer-policy/#referrer-policies.
+var referrerPolicies = map[string]struct{}{
+	"no-referrer":                     {},
+	"no-referrer-when-downgrade":      {},
+	"origin":                          {},
+	"origin-when-cross-origin":        {},
+	"same-origin":                     {},
+	"strict-origin":                   {},
+	"strict-origin-when-cross-origin": {},
+	"unsafe-url":                      {},
+}
+
+// referrerPolicy is the parsed value of the $referrerpolicy modifier.  It is
+// the Referrer-Policy of the documents.
+//
+// See https://adguard.com/kb/general/ad-filtering/create-own-filters/#referrerpolicy-modifier.
+type referrerPolicy struct {
+	// modifierValue is the lowercased policy.
+	modifierValue
+}
+
+// newReferrerPolicy parses the non-empty value of the $referrerpolicy modifier.
+func newReferrerPolicy(value string) (rp *referrerPolicy, err error) {
+	policy := strings.ToLower(value)
+	if _, ok := referrerPolicies[policy]; !ok {
+		return nil, fmt.Errorf("invalid $referrerpolicy value: %q", value)
+	}
+
+	return &referrerPolicy{
+		modifierValue: modifierValue{value: policy},
+	}, nil
+}
+
+// referrerPolicyRule returns the $referrerpolicy rule with the highest
+// priority, or nil if there are none.
+func referrerPolicyRule(referrerPolicyRules []*NetworkRule) (rule *NetworkRule) {
+	for _, r := range referrerPolicyRules {
+		if rule == nil || r.IsHigherPriority(rule) {
+			rule = r
+		}
+	}
+
+	return rule
+}
@@ -0,0 +1,143 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchingResult_ReferrerPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		want  string
+		rules []string
+	}{{
+		name:  "none",
+		want:  "",
+		rules: nil,
+	}, {
+		name:  "single",
+		want:  "no-referrer",
+		rules: []string{"||example.org^$referrerpolicy=No-Referrer"},
+	}, {
+		name: "important",
+		want: "origin",
+		rules: []string{
+			"||example.org^$referrerpolicy=no-referrer",
+			"||example.org^$referrerpolicy=origin,important",
+		},
+	}, {
+		name: "specific",
+		want: "same-origin",
+		rules: []string{
+			"||example.org^$referrerpolicy=no-referrer",
+			"||example.org^$referrerpolicy=same-origin,domain=example.com",
+		},
+	}, {
+		name: "exception",
+		want: "origin",
+		rules: []string{
+			"||example.org^$referrerpolicy=no-referrer,domain=example.com",
+			"||example.org^$referrerpolicy=origin",
+			"@@||example.org^$referrerpolicy=no-referrer",
+		},
+	}, {
+		name: "exception_all",
+		want: "",
+		rules: []string{
+			"||example.org^$referrerpolicy=no-referrer",
+			"@@||example.org^$referrerpolicy",
+		},
+	}, {
+		name: "exception_important",
+		want: "no-referrer",
+		rules: []string{
+			"||example.org^$referrerpolicy=no-referrer,important",
+			"@@||example.org^$referrerpolicy",
+		},
+	}, {
+		name: "badfilter",
+		want: "",
+		rules: []string{
+			"||example.org^$referrerpolicy=no-referrer",
+			"||example.org^$referrerpolicy=no-referrer,badfilter",
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := rules.NewMatchingResult(newNetworkRules(t, tc.rules), nil)
+			assert.Equal(t, tc.want, res.ReferrerPolicy())
+			assert.Nil(t, res.GetBasicResult())
+		})
+	}
+}
+
+func TestNetworkRule_referrerPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		in         string
+		wantErrMsg string
+	}{{
+		name:       "valid",
+		in:         "||example.org^$referrerpolicy=origin",
+		wantErrMsg: "",
+	}, {
+		name:       "uppercase",
+		in:         "||example.org^$referrerpolicy=No-Referrer",
+		wantErrMsg: "",
+	}, {
+		name:       "exception_all",
+		in:         "@@||example.org^$referrerpolicy",
+		wantErrMsg: "",
+	}, {
+		name:       "empty",
+		in:         "||example.org^$referrerpolicy",
+		wantErrMsg: "empty $referrerpolicy value",
+	}, {
+		name:       "invalid",
+		in:         "||example.org^$referrerpolicy=none",
+		wantErrMsg: `invalid $referrerpolicy value: "none"`,
+	}, {
+		name:       "invalid_exception",
+		in:         "@@||example.org^$referrerpolicy=none",
+		wantErrMsg: `invalid $referrerpolicy value: "none"`,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.in, -1)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+			if err != nil {
+				return
+			}
+
+			assert.True(t, r.IsOptionEnabled(rules.OptionReferrerPolicy))
+			assert.Nil(t, rules.GetDNSBasicRule([]*rules.NetworkRule{r}))
+		})
+	}
+
+	t.Run("match", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := rules.NewNetworkRule("||example.org^$referrerpolicy=origin", -1)
+		require.NoError(t, err)
+
+		doc := rules.NewRequest("https://example.org/", "", rules.TypeDocument)
+		assert.True(t, r.Match(doc))
+
+		img := rules.NewRequest("https://example.org/ad.png", "", rules.TypeImage)
+		assert.False(t, r.Match(img))
+	})
+}
@@ -1,6 +1,8 @@
 package rules
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -110,3 +112,30 @@ func patternToRegexp(pattern string) string {
 
 	return regex
 }
+
+// parseValueRegexp parses the modifier value of the "/regex/" or "/regex/i"
+// form, where the "i" flag makes the expression case-insensitive.  ok is false
+// if v is not of that form.
+func parseValueRegexp(v string) (re *regexp.Regexp, ok bool, err error) {
+	end := strings.LastIndexByte(v, '/')
+	if len(v) < 2 || v[0] != '/' || end == 0 {
+		return nil, false, nil
+	}
+
+	expr := v[1:end]
+	switch flags := v[end+1:]; flags {
+	case "":
+		// Go on.
+	case "i":
+		expr = "(?i)" + expr
+	default:
+		return nil, false, nil
+	}
+
+	re, err = regexp.Compile(expr)
+	if err != nil {
+		return nil, true, fmt.Errorf("compiling regexp %q: %w", v, err)
+	}
+
+	return re, true, nil
+}
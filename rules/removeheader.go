@@ -0,0 +1,113 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// removeHeaderRequestPrefix is the prefix of the $removeheader modifier value
+// which means that the header is removed from the request.
+const removeHeaderRequestPrefix = "request:"
+
+// unremovableHeaders are the lowercased names of the headers, which the
+// $removeheader rules must never remove, since that breaks the security of the
+// websites or the HTTP protocol itself.
+var unremovableHeaders = map[string]struct{}{
+	"accept":                              {},
+	"accept-encoding":                     {},
+	"access-control-allow-credentials":    {},
+	"access-control-allow-headers":        {},
+	"access-control-allow-methods":        {},
+	"access-control-allow-origin":         {},
+	"access-control-expose-headers":       {},
+	"access-control-max-age":              {},
+	"access-control-request-headers":      {},
+	"access-control-request-method":       {},
+	"allow":                               {},
+	"connection":                          {},
+	"content-length":                      {},
+	"content-security-policy":             {},
+	"content-security-policy-report-only": {},
+	"content-type":                        {},
+	"cross-origin-embedder-policy":        {},
+	"cross-origin-opener-policy":          {},
+	"cross-origin-resource-policy":        {},
+	"expect-ct":                           {},
+	"feature-policy":                      {},
+	"host":                                {},
+	"origin":                              {},
+	"origin-isolation":                    {},
+	"p3p":                                 {},
+	"permissions-policy":                  {},
+	"public-key-pins":                     {},
+	"public-key-pins-report-only":         {},
+	"referrer-policy":                     {},
+	"sec-fetch-dest":                      {},
+	"sec-fetch-mode":                      {},
+	"sec-fetch-site":                      {},
+	"sec-fetch-user":                      {},
+	"sec-websocket-accept":                {},
+	"sec-websocket-extensions":            {},
+	"sec-websocket-key":                   {},
+	"sec-websocket-protocol":              {},
+	"sec-websocket-version":               {},
+	"strict-transport-security":           {},
+	"timing-allow-origin":                 {},
+	"transfer-encoding":                   {},
+	"upgrade":                             {},
+	"upgrade-insecure-requests":           {},
+	"x-content-type-options":              {},
+	"x-download-options":                  {},
+	"x-frame-options":                     {},
+	"x-permitted-cross-domain-policies":   {},
+	"x-powered-by":                        {},
+	"x-xss-protection":                    {},
+}
+
+// removeHeader is the parsed value of the $removeheader modifier.  It is the
+// header, which must be removed from the request or the response.
+//
+// See https://adguard.com/kb/general/ad-filtering/create-own-filters/#removeheader-modifier.
+type removeHeader struct {
+	// modifierValue is the lowercased value of the modifier.
+	modifierValue
+
+	// name is the lowercased name of the header.  It is only empty for the
+	// exception rules, which disable all $removeheader rules.
+	name string
+
+	// request is true if the header is removed from the request instead of
+	// the response.
+	request bool
+}
+
+// newRemoveHeader parses the non-empty value of the $removeheader modifier.
+func newRemoveHeader(value string) (rh *removeHeader, err error) {
+	value = strings.ToLower(value)
+	name, request := strings.CutPrefix(value, removeHeaderRequestPrefix)
+	if name == "" || strings.ContainsAny(name, " \t:") {
+		return nil, fmt.Errorf("invalid $removeheader value: %q", value)
+	}
+
+	if _, ok := unremovableHeaders[name]; ok {
+		return nil, fmt.Errorf("$removeheader: header %q cannot be removed", name)
+	}
+
+	return &removeHeader{
+		modifierValue: modifierValue{value: value},
+		name:          name,
+		request:       request,
+	}, nil
+}
+
+// removedHeaders returns the names of the headers removed by the $removeheader
+// rules from the request, if request is true, or from the response.
+func removedHeaders(removeHeaderRules []*NetworkRule, request bool) (names []string) {
+	for _, r := range removeHeaderRules {
+		if rh := r.getValues().removeHeader; rh.request == request {
+			names = append(names, rh.name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,142 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchingResult_RemovedHeaders(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		rules        []string
+		wantRequest  []string
+		wantResponse []string
+	}{{
+		name:         "none",
+		rules:        nil,
+		wantRequest:  nil,
+		wantResponse: nil,
+	}, {
+		name:         "response",
+		rules:        []string{"||example.org^$removeheader=X-Ad-Id"},
+		wantRequest:  nil,
+		wantResponse: []string{"x-ad-id"},
+	}, {
+		name:         "request",
+		rules:        []string{"||example.org^$removeheader=request:X-Client-Data"},
+		wantRequest:  []string{"x-client-data"},
+		wantResponse: nil,
+	}, {
+		name: "both",
+		rules: []string{
+			"$removeheader=refresh",
+			"$removeheader=request:x-client-data",
+			"||example.org^$removeheader=location",
+		},
+		wantRequest:  []string{"x-client-data"},
+		wantResponse: []string{"refresh", "location"},
+	}, {
+		name: "exception",
+		rules: []string{
+			"$removeheader=refresh",
+			"$removeheader=request:refresh",
+			"@@||example.org^$removeheader=Refresh",
+		},
+		wantRequest:  []string{"refresh"},
+		wantResponse: nil,
+	}, {
+		name: "exception_all",
+		rules: []string{
+			"$removeheader=refresh",
+			"$removeheader=request:x-client-data",
+			"@@||example.org^$removeheader",
+		},
+		wantRequest:  nil,
+		wantResponse: nil,
+	}, {
+		name: "exception_important",
+		rules: []string{
+			"$removeheader=refresh,important",
+			"@@||example.org^$removeheader",
+		},
+		wantRequest:  nil,
+		wantResponse: []string{"refresh"},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := rules.NewMatchingResult(newNetworkRules(t, tc.rules), nil)
+			assert.Equal(t, tc.wantRequest, res.RemovedHeaders(true))
+			assert.Equal(t, tc.wantResponse, res.RemovedHeaders(false))
+			assert.Nil(t, res.GetBasicResult())
+		})
+	}
+}
+
+func TestNetworkRule_removeHeader(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		in         string
+		wantErrMsg string
+	}{{
+		name:       "response",
+		in:         "$removeheader=x-ad-id",
+		wantErrMsg: "",
+	}, {
+		name:       "request",
+		in:         "$removeheader=request:X-Client-Data",
+		wantErrMsg: "",
+	}, {
+		name:       "exception_all",
+		in:         "@@||example.org^$removeheader",
+		wantErrMsg: "",
+	}, {
+		name:       "empty",
+		in:         "||example.org^$removeheader",
+		wantErrMsg: "empty $removeheader value",
+	}, {
+		name:       "empty_request",
+		in:         "||example.org^$removeheader=request:",
+		wantErrMsg: `invalid $removeheader value: "request:"`,
+	}, {
+		name:       "space",
+		in:         "||example.org^$removeheader=x ad",
+		wantErrMsg: `invalid $removeheader value: "x ad"`,
+	}, {
+		name:       "unremovable",
+		in:         "||example.org^$removeheader=Content-Security-Policy",
+		wantErrMsg: `$removeheader: header "content-security-policy" cannot be removed`,
+	}, {
+		name:       "unremovable_request",
+		in:         "||example.org^$removeheader=request:host",
+		wantErrMsg: `$removeheader: header "host" cannot be removed`,
+	}, {
+		name:       "unremovable_exception",
+		in:         "@@||example.org^$removeheader=access-control-allow-origin",
+		wantErrMsg: `$removeheader: header "access-control-allow-origin" cannot be removed`,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.in, -1)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+			if err != nil {
+				return
+			}
+
+			assert.True(t, r.IsOptionEnabled(rules.OptionRemoveHeader))
+			assert.Nil(t, rules.GetDNSBasicRule([]*rules.NetworkRule{r}))
+		})
+	}
+}
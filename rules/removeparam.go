@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// removeParam is the parsed value of the $removeparam modifier.  It matches the
+// query parameters, which must be removed from the request URL.
+//
+// See https://adguard.com/kb/general/ad-filtering/create-own-filters/#removeparam-modifier.
+type removeParam struct {
+	// re matches the "name=value" pairs of the parameters to remove.  It is
+	// nil if the parameters are matched by name.
+	re *regexp.Regexp
+
+	// modifierValue is the original value of the modifier.
+	modifierValue
+
+	// name is the name of the parameters to remove.  If both name and re are
+	// empty, all parameters are removed.
+	name string
+
+	// inverted is true if all parameters except the matching ones are
+	// removed.
+	inverted bool
+}
+
+// newRemoveParam parses the value of the $removeparam modifier.  An empty value
+// means that all parameters must be removed.
+func newRemoveParam(value string) (rp *removeParam, err error) {
+	rp = &removeParam{
+		modifierValue: modifierValue{value: value},
+	}
+
+	v, inverted := cutNegation(value)
+	if inverted && v == "" {
+		return nil, fmt.Errorf("invalid $removeparam value: %q", value)
+	}
+
+	rp.inverted = inverted
+
+	re, ok, err := parseValueRegexp(v)
+	if err != nil {
+		return nil, fmt.Errorf("$removeparam: %w", err)
+	} else if ok {
+		rp.re = re
+	} else {
+		rp.name = v
+	}
+
+	return rp, nil
+}
+
+// removes returns true if the query parameter, which is a "name=value" pair,
+// must be removed.
+func (rp *removeParam) removes(param string) (ok bool) {
+	switch {
+	case rp.re != nil:
+		ok = rp.re.MatchString(param)
+	case rp.name == "":
+		return true
+	default:
+		name, _, _ := strings.Cut(param, "=")
+		ok = name == rp.name
+	}
+
+	return ok != rp.inverted
+}
+
+// removeQueryParams returns u with the query parameters removed by any of the
+// $removeparam rules.  The order and the encoding of the other parameters are
+// preserved.  u is returned as is if no parameters are removed.
+func removeQueryParams(u string, removeParamRules []*NetworkRule) (res string) {
+	if len(removeParamRules) == 0 {
+		return u
+	}
+
+	rest, fragment, hasFragment := strings.Cut(u, "#")
+	base, query, hasQuery := strings.Cut(rest, "?")
+	if !hasQuery {
+		return u
+	}
+
+	params := strings.Split(query, "&")
+	kept := params[:0:0]
+	for _, p := range params {
+		if !removesParam(removeParamRules, p) {
+			kept = append(kept, p)
+		}
+	}
+
+	if len(kept) == len(params) {
+		return u
+	}
+
+	res = base
+	if len(kept) > 0 {
+		res += "?" + strings.Join(kept, "&")
+	}
+
+	if hasFragment {
+		res += "#" + fragment
+	}
+
+	return res
+}
+
+// removesParam returns true if any of the $removeparam rules removes the query
+// parameter.
+func removesParam(removeParamRules []*NetworkRule, param string) (ok bool) {
+	for _, r := range removeParamRules {
+		if r.getValues().removeParam.removes(param) {
+			return true
+		}
+	}
+
+	return false
+}
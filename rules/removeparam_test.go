@@ -0,0 +1,196 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchingResult_RemoveParams(t *testing.T) {
+	t.Parallel()
+
+	const testURL = "https://example.org/path?utm_source=ad&id=1&utm_medium=cpc#frag"
+
+	testCases := []struct {
+		name        string
+		rules       []string
+		sourceRules []string
+		url         string
+		want        string
+	}{{
+		name:        "none",
+		rules:       nil,
+		sourceRules: nil,
+		url:         testURL,
+		want:        testURL,
+	}, {
+		name:        "name",
+		rules:       []string{"$removeparam=utm_source"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        "https://example.org/path?id=1&utm_medium=cpc#frag",
+	}, {
+		name:        "regexp",
+		rules:       []string{"||example.org^$removeparam=/^utm_/"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        "https://example.org/path?id=1#frag",
+	}, {
+		name:        "regexp_value",
+		rules:       []string{`$removeparam=/=CPC\$/i`},
+		sourceRules: nil,
+		url:         testURL,
+		want:        "https://example.org/path?utm_source=ad&id=1#frag",
+	}, {
+		name:        "inverted",
+		rules:       []string{"||example.org^$removeparam=~id"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        "https://example.org/path?id=1#frag",
+	}, {
+		name:        "all",
+		rules:       []string{"||example.org^$removeparam"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        "https://example.org/path#frag",
+	}, {
+		name:        "no_query",
+		rules:       []string{"||example.org^$removeparam"},
+		sourceRules: nil,
+		url:         "https://example.org/path#a?b",
+		want:        "https://example.org/path#a?b",
+	}, {
+		name:        "several",
+		rules:       []string{"$removeparam=utm_source", "$removeparam=utm_medium"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        "https://example.org/path?id=1#frag",
+	}, {
+		name:        "exception",
+		rules:       []string{"$removeparam=utm_source", "@@||example.org^$removeparam=utm_source"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        testURL,
+	}, {
+		name:        "exception_other",
+		rules:       []string{"$removeparam=utm_source", "@@||example.org^$removeparam=utm_medium"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        "https://example.org/path?id=1&utm_medium=cpc#frag",
+	}, {
+		name:        "exception_all",
+		rules:       []string{"$removeparam=utm_source", "$removeparam=id", "@@||example.org^$removeparam"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        testURL,
+	}, {
+		name:        "exception_important",
+		rules:       []string{"$removeparam=utm_source,important", "@@||example.org^$removeparam"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        "https://example.org/path?id=1&utm_medium=cpc#frag",
+	}, {
+		name:        "exception_basic",
+		rules:       []string{"$removeparam=utm_source", "@@||example.org^"},
+		sourceRules: nil,
+		url:         testURL,
+		want:        "https://example.org/path?id=1&utm_medium=cpc#frag",
+	}, {
+		name:        "urlblock",
+		rules:       []string{"$removeparam=utm_source"},
+		sourceRules: []string{"@@||example.com^$urlblock"},
+		url:         testURL,
+		want:        testURL,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := rules.NewMatchingResult(newNetworkRules(t, tc.rules), newNetworkRules(t, tc.sourceRules))
+			assert.Equal(t, tc.want, res.RemoveParams(tc.url))
+		})
+	}
+}
+
+func TestNetworkRule_removeParam(t *testing.T) {
+	t.Parallel()
+
+	t.Run("match", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := rules.NewNetworkRule("$removeparam=utm_source", -1)
+		require.NoError(t, err)
+
+		assert.True(t, r.IsOptionEnabled(rules.OptionRemoveParam))
+		assert.True(t, r.Match(rules.NewRequest("https://example.org/?utm_source=ad", "", rules.TypeDocument)))
+		assert.Nil(t, rules.GetDNSBasicRule([]*rules.NetworkRule{r}))
+		assert.Nil(t, rules.NewMatchingResult([]*rules.NetworkRule{r}, nil).GetBasicResult())
+	})
+
+	t.Run("badfilter", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := rules.NewNetworkRule("$removeparam=utm_source,badfilter", -1)
+		require.NoError(t, err)
+
+		idx := rules.NewBadfilterIndex()
+		require.True(t, idx.Add(b))
+
+		same, err := rules.NewNetworkRule("$removeparam=utm_source", -1)
+		require.NoError(t, err)
+
+		res, _ := idx.Apply(same)
+		assert.Nil(t, res)
+
+		other, err := rules.NewNetworkRule("$removeparam=utm_medium", -1)
+		require.NoError(t, err)
+
+		res, _ = idx.Apply(other)
+		assert.Same(t, other, res)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			name       string
+			in         string
+			wantErrMsg string
+		}{{
+			name:       "negation_only",
+			in:         "$removeparam=~",
+			wantErrMsg: `invalid $removeparam value: "~"`,
+		}, {
+			name:       "bad_regexp",
+			in:         "$removeparam=/(/",
+			wantErrMsg: "$removeparam: compiling regexp \"/(/\": error parsing regexp: missing closing ): `(`",
+		}}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := rules.NewNetworkRule(tc.in, -1)
+				testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+			})
+		}
+	})
+}
+
+// newNetworkRules is a helper that parses the network rules from texts.
+func newNetworkRules(tb testing.TB, texts []string) (rs []*rules.NetworkRule) {
+	tb.Helper()
+
+	for _, text := range texts {
+		r, err := rules.NewNetworkRule(text, -1)
+		require.NoError(tb, err)
+
+		rs = append(rs, r)
+	}
+
+	return rs
+}
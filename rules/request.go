@@ -2,6 +2,7 @@ package rules
 
 import (
 	"math/bits"
+	"net/http"
 	"net/netip"
 	"strings"
 
@@ -42,6 +43,9 @@ const (
 	TypePing
 	// TypeOther - any other request type
 	TypeOther
+
+	// typesAll are all the request types, see the $all modifier.
+	typesAll = TypeOther<<1 - 1
 )
 
 // Count returns the count of the enabled flags.
@@ -86,9 +90,18 @@ type Request struct {
 	// SortedClientTags is the list of tags to match against $ctag modifiers.
 	SortedClientTags []string
 
+	// ResponseHeaders are the headers of the response to match against $header
+	// modifiers.  It is nil until the response is received, and the rules
+	// with $header modifiers don't match the request until then.
+	ResponseHeaders http.Header
+
 	// RequestType is the type of the filtering request.
 	RequestType RequestType
 
+	// Method is the HTTP method of the request to match against $method
+	// modifiers.  The default zero value means that the method is unknown.
+	Method RequestMethod
+
 	// DNSType is the type of the resource record (RR) of a DNS request, for
 	// example "A" or "AAAA".  See [RRValue] for all acceptable constants and
 	// their corresponding values.
@@ -98,13 +111,23 @@ type Request struct {
 	// modifier.
 	ThirdParty bool
 
+	// StrictThirdParty is true if the filtering request should consider
+	// $strict-third-party modifier, that is if the hostname of the source
+	// differs from the request one.  Unlike ThirdParty, it's true for the
+	// requests to the subdomains of the source as well.
+	StrictThirdParty bool
+
 	// IsHostnameRequest means that the request is for a given Hostname, and not
 	// for a URL, and we don't really know what protocol it is.  This can be
 	// true for DNS requests, for HTTP CONNECT, or for SNI matching.
 	IsHostnameRequest bool
 }
 
-// NewRequest creates a new instance of "Request" and populates it's fields
+// NewRequest creates a new instance of "Request" and populates it's fields.
+// The hostnames are normalized to their lowercased ASCII form, and an
+// internationalized hostname in url is replaced with its Punycode form.  If a
+// hostname has no eTLD+1 domain, for example if it's an IP address, the domain
+// is the hostname itself.
 func NewRequest(url, sourceURL string, requestType RequestType) *Request {
 	if len(url) > maxURLLength {
 		url = url[:maxURLLength]
@@ -113,15 +136,17 @@ func NewRequest(url, sourceURL string, requestType RequestType) *Request {
 		sourceURL = sourceURL[:maxURLLength]
 	}
 
+	url, hostname := normalizeURLHostname(url)
+
 	r := Request{
 		RequestType: requestType,
 
 		URL:          url,
 		URLLowerCase: strings.ToLower(url),
-		Hostname:     ufnet.ExtractHostname(url),
+		Hostname:     hostname,
 
 		SourceURL:      sourceURL,
-		SourceHostname: ufnet.ExtractHostname(sourceURL),
+		SourceHostname: ufnet.NormalizeDomain(ufnet.ExtractHostname(sourceURL)),
 	}
 
 	domain := effectiveTLDPlusOne(r.Hostname)
@@ -142,9 +167,27 @@ func NewRequest(url, sourceURL string, requestType RequestType) *Request {
 		r.ThirdParty = true
 	}
 
+	if r.SourceHostname != "" && r.SourceHostname != r.Hostname {
+		r.StrictThirdParty = true
+	}
+
 	return &r
 }
 
+// normalizeURLHostname returns the normalized hostname of url.  If the hostname
+// is an internationalized one, it's also replaced with the normalized one in
+// normalized.
+func normalizeURLHostname(url string) (normalized, hostname string) {
+	start, end := ufnet.HostnameBounds(url)
+	orig := url[start:end]
+	hostname = ufnet.NormalizeDomain(orig)
+	if hostname == orig || isASCII(orig) {
+		return url, hostname
+	}
+
+	return url[:start] + hostname + url[end:], hostname
+}
+
 // NewRequestForHostname creates a new instance of [Request] for matching the
 // hostname.  It uses "http://" as a protocol and [TypeDocument] as a request
 // type.
@@ -157,11 +200,24 @@ func NewRequestForHostname(hostname string) (r *Request) {
 
 // FillRequestForHostname fills the given instance of request r for matching the
 // hostname.  It uses "http://" as a protocol for request URL and [TypeDocument]
-// as request type.
+// as request type.  The hostname is normalized to its lowercased ASCII form, and
+// an IPv6 one is enclosed in brackets in the URL.
 func FillRequestForHostname(r *Request, hostname string) {
+	FillRequestForNormalizedHostname(r, ufnet.NormalizeDomain(hostname))
+}
+
+// FillRequestForNormalizedHostname is like [FillRequestForHostname] but doesn't
+// normalize hostname, which must already be a lowercased ASCII one.  It is
+// useful for the callers which have normalized it before.
+func FillRequestForNormalizedHostname(r *Request, hostname string) {
 	// Do not use fmt.Sprintf or url.URL to achieve better performance.
 	// Hostname validation should be performed by the function caller.
 	urlStr := "http://" + hostname
+	if strings.Contains(hostname, ":") {
+		// Enclose the IPv6 address in brackets so that the rules like
+		// "||[2001:db8::1]^" match it.
+		urlStr = "http://[" + hostname + "]"
+	}
 
 	// TODO(d.kolyshev): Make r.URL to be [url.URL], then [url.AppendBinary]
 	// on usages.
@@ -171,6 +227,7 @@ func FillRequestForHostname(r *Request, hostname string) {
 
 	r.RequestType = TypeDocument
 	r.ThirdParty = false
+	r.StrictThirdParty = false
 	r.IsHostnameRequest = true
 
 	if domain := effectiveTLDPlusOne(r.Hostname); domain != "" {
@@ -181,13 +238,20 @@ func FillRequestForHostname(r *Request, hostname string) {
 }
 
 // effectiveTLDPlusOne is a faster version of publicsuffix.EffectiveTLDPlusOne
-// that avoids using fmt.Errorf when the domain is less or equal the suffix.
+// that avoids using fmt.Errorf when the domain is less or equal the suffix.  It
+// returns "" if there is no such domain, including for the IP addresses, in
+// which case the callers use the hostname itself as the domain.
 func effectiveTLDPlusOne(hostname string) (domain string) {
 	hostnameLen := len(hostname)
 	if hostnameLen < 1 {
 		return ""
 	}
 
+	// The IP addresses have no public suffixes.
+	if _, ok := hostnameAddr(hostname); ok {
+		return ""
+	}
+
 	if hostname[0] == '.' || hostname[hostnameLen-1] == '.' {
 		return ""
 	}
@@ -201,3 +265,16 @@ func effectiveTLDPlusOne(hostname string) (domain string) {
 
 	return hostname[1+strings.LastIndex(hostname[:i], "."):]
 }
+
+// hostnameAddr returns the IP address if hostname is an IP address literal.  It
+// only tries to parse the hostnames which may be one, since parsing errors
+// allocate.
+func hostnameAddr(hostname string) (ip netip.Addr, ok bool) {
+	if !strings.Contains(hostname, ":") && strings.Trim(hostname, "0123456789.") != "" {
+		return netip.Addr{}, false
+	}
+
+	ip, err := netip.ParseAddr(hostname)
+
+	return ip, err == nil
+}
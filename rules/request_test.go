@@ -19,6 +19,9 @@ const (
 
 	testLongTLDHostname = "example.org.uk"
 	testLongTLDURLStr   = "http://" + testLongTLDHostname
+
+	testIDNHostname      = "пример.рф"
+	testIDNHostnameASCII = "xn--e1afmkfd.xn--p1ai"
 )
 
 func TestNewRequest(t *testing.T) {
@@ -44,6 +47,7 @@ func TestNewRequest(t *testing.T) {
 			RequestType:       rules.TypeOther,
 			DNSType:           0,
 			ThirdParty:        false,
+			StrictThirdParty:  false,
 			IsHostnameRequest: false,
 		},
 		name:      "no_source",
@@ -64,6 +68,7 @@ func TestNewRequest(t *testing.T) {
 			RequestType:       rules.TypeOther,
 			DNSType:           0,
 			ThirdParty:        false,
+			StrictThirdParty:  true,
 			IsHostnameRequest: false,
 		},
 		name:      "source",
@@ -84,6 +89,7 @@ func TestNewRequest(t *testing.T) {
 			RequestType:       rules.TypeOther,
 			DNSType:           0,
 			ThirdParty:        false,
+			StrictThirdParty:  false,
 			IsHostnameRequest: false,
 		},
 		name:      "long_tld",
@@ -104,11 +110,75 @@ func TestNewRequest(t *testing.T) {
 			RequestType:       rules.TypeOther,
 			DNSType:           0,
 			ThirdParty:        true,
+			StrictThirdParty:  true,
 			IsHostnameRequest: false,
 		},
 		name:      "third_party",
 		sourceURL: testLongTLDURLStr,
 		url:       testURLStr,
+	}, {
+		want: &rules.Request{
+			ClientIP:          netip.Addr{},
+			ClientName:        "",
+			URL:               "https://" + testIDNHostnameASCII + "/Path",
+			URLLowerCase:      "https://" + testIDNHostnameASCII + "/path",
+			Hostname:          testIDNHostnameASCII,
+			Domain:            testIDNHostnameASCII,
+			SourceURL:         "https://ПРИМЕР.рф/",
+			SourceHostname:    testIDNHostnameASCII,
+			SourceDomain:      testIDNHostnameASCII,
+			SortedClientTags:  nil,
+			RequestType:       rules.TypeOther,
+			DNSType:           0,
+			ThirdParty:        false,
+			StrictThirdParty:  false,
+			IsHostnameRequest: false,
+		},
+		name:      "idn",
+		sourceURL: "https://ПРИМЕР.рф/",
+		url:       "https://" + testIDNHostname + "/Path",
+	}, {
+		want: &rules.Request{
+			ClientIP:          netip.Addr{},
+			ClientName:        "",
+			URL:               "https://" + testIDNHostname + "@" + testIDNHostnameASCII + "/",
+			URLLowerCase:      "https://" + testIDNHostname + "@" + testIDNHostnameASCII + "/",
+			Hostname:          testIDNHostnameASCII,
+			Domain:            testIDNHostnameASCII,
+			SourceURL:         "",
+			SourceHostname:    "",
+			SourceDomain:      "",
+			SortedClientTags:  nil,
+			RequestType:       rules.TypeOther,
+			DNSType:           0,
+			ThirdParty:        false,
+			StrictThirdParty:  false,
+			IsHostnameRequest: false,
+		},
+		name:      "idn_userinfo",
+		sourceURL: "",
+		url:       "https://" + testIDNHostname + "@" + testIDNHostname + "/",
+	}, {
+		want: &rules.Request{
+			ClientIP:          netip.Addr{},
+			ClientName:        "",
+			URL:               "http://user@[2001:DB8::1]:8080/ad.js",
+			URLLowerCase:      "http://user@[2001:db8::1]:8080/ad.js",
+			Hostname:          "2001:db8::1",
+			Domain:            "2001:db8::1",
+			SourceURL:         "http://192.0.2.1:8080/",
+			SourceHostname:    "192.0.2.1",
+			SourceDomain:      "192.0.2.1",
+			SortedClientTags:  nil,
+			RequestType:       rules.TypeOther,
+			DNSType:           0,
+			ThirdParty:        true,
+			StrictThirdParty:  true,
+			IsHostnameRequest: false,
+		},
+		name:      "ip",
+		sourceURL: "http://192.0.2.1:8080/",
+		url:       "http://user@[2001:DB8::1]:8080/ad.js",
 	}}
 
 	for _, tc := range testCases {
@@ -170,8 +240,27 @@ func TestFillRequestForHostname(t *testing.T) {
 		RequestType:       rules.TypeDocument,
 		DNSType:           0,
 		ThirdParty:        false,
+		StrictThirdParty:  false,
 		IsHostnameRequest: true,
 	}, req)
+
+	rules.FillRequestForHostname(req, testIDNHostname)
+	assert.Equal(t, "http://"+testIDNHostnameASCII, req.URL)
+	assert.Equal(t, testIDNHostnameASCII, req.Hostname)
+	assert.Equal(t, testIDNHostnameASCII, req.Domain)
+
+	rules.FillRequestForHostname(req, "TEST.example")
+	assert.Equal(t, testURLStr, req.URL)
+	assert.Equal(t, testHostname, req.Hostname)
+
+	rules.FillRequestForHostname(req, "2001:db8::1")
+	assert.Equal(t, "http://[2001:db8::1]", req.URL)
+	assert.Equal(t, "2001:db8::1", req.Hostname)
+	assert.Equal(t, "2001:db8::1", req.Domain)
+
+	rules.FillRequestForHostname(req, "192.0.2.1")
+	assert.Equal(t, "http://192.0.2.1", req.URL)
+	assert.Equal(t, "192.0.2.1", req.Domain)
 }
 
 func BenchmarkFillRequestForHostname(b *testing.B) {
@@ -196,6 +285,7 @@ func BenchmarkFillRequestForHostname(b *testing.B) {
 		RequestType:       rules.TypeDocument,
 		DNSType:           0,
 		ThirdParty:        false,
+		StrictThirdParty:  false,
 		IsHostnameRequest: true,
 	}, req)
 
@@ -83,6 +83,8 @@ func isComment(line string) bool {
 // loadDomains loads $domain modifier or cosmetic rules domains
 // domains is the list of domains
 // sep is the separator character. for network rules it is '|', for cosmetic it is ','.
+// The domains aren't copied unless normalized, so they share the memory of the
+// rule text.
 func loadDomains(domains, sep string) (permittedDomains, restrictedDomains []string, err error) {
 	if domains == "" {
 		err = errors.Error("no domains specified")
@@ -98,6 +100,7 @@ func loadDomains(domains, sep string) (permittedDomains, restrictedDomains []str
 			d = d[1:]
 		}
 
+		d = normalizeDomain(d)
 		if !ufnet.IsDomainName(d) && !strings.HasSuffix(d, ".*") {
 			err = fmt.Errorf("invalid domain specified: %s", domains)
 			return
@@ -113,6 +116,17 @@ func loadDomains(domains, sep string) (permittedDomains, restrictedDomains []str
 	return
 }
 
+// normalizeDomain returns the normalized form of the domain from a domain list
+// of a rule, which may also be a wildcard one, such as "example.*".  See
+// [ufnet.NormalizeDomain].
+func normalizeDomain(d string) (normalized string) {
+	if base, ok := strings.CutSuffix(d, ".*"); ok {
+		return ufnet.NormalizeDomain(base) + ".*"
+	}
+
+	return ufnet.NormalizeDomain(d)
+}
+
 // strToRRType converts s to a DNS resource record (RR) type.  s may be in any
 // letter case.
 func strToRRType(s string) (rr RRType, err error) {
@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// uboOptionAliases maps the uBlock Origin specific names of network rule
+// modifiers to their AdGuard equivalents.
+//
+// See https://github.com/gorhill/uBlock/wiki/Static-filter-syntax.
+var uboOptionAliases = map[string]string{
+	"1p":            "first-party",
+	"~1p":           "~first-party",
+	"3p":            "third-party",
+	"~3p":           "~third-party",
+	"css":           "stylesheet",
+	"~css":          "~stylesheet",
+	"doc":           "document",
+	"ehide":         "elemhide",
+	"frame":         "subdocument",
+	"~frame":        "~subdocument",
+	"from":          "domain",
+	"ghide":         "generichide",
+	"redirect-rule": "redirect",
+	"shide":         "specifichide",
+	"xhr":           "xmlhttprequest",
+	"~xhr":          "~xmlhttprequest",
+}
+
+// canonicalOptionName returns the AdGuard name of the network rule modifier
+// name, which may be a uBlock Origin specific one.
+func canonicalOptionName(name string) (canonical string) {
+	if alias, ok := uboOptionAliases[name]; ok {
+		return alias
+	}
+
+	return name
+}
+
+// uBlock Origin specific syntax of cosmetic rules.
+//
+// See https://github.com/gorhill/uBlock/wiki/Static-filter-syntax.
+const (
+	uboScriptletPrefix = "+js("
+	uboStylePseudo     = ":style("
+	uboRemovePseudo    = ":remove()"
+)
+
+// loadUBOSyntax converts the uBlock Origin specific syntax of the element
+// hiding rule content into the equivalent AdGuard rule type and content:
+//
+//	##+js(name, arg)      #%#//scriptlet('ubo-name', 'arg')
+//	##sel:style(color: red)   #$#sel { color: red }
+//	##sel:remove()        #$?#sel { remove: true; }
+//
+// It does nothing if the content has no such syntax.
+func (f *CosmeticRule) loadUBOSyntax() (err error) {
+	content := f.Content
+	switch {
+	case strings.HasPrefix(content, uboScriptletPrefix):
+		f.Content, err = uboScriptletToAdGuard(content)
+		if err != nil {
+			return fmt.Errorf("bad scriptlet: %w", err)
+		}
+
+		f.Type = CosmeticJS
+	case strings.HasSuffix(content, uboRemovePseudo):
+		sel := strings.TrimSpace(strings.TrimSuffix(content, uboRemovePseudo))
+		if sel == "" {
+			return errors.Error("empty selector")
+		}
+
+		f.Content = sel + " { remove: true; }"
+		f.Type = CosmeticCSS
+		f.ExtendedCSS = true
+	case strings.HasSuffix(content, ")") && strings.Contains(content, uboStylePseudo):
+		i := strings.LastIndex(content, uboStylePseudo)
+		sel := strings.TrimSpace(content[:i])
+		style := strings.TrimSpace(content[i+len(uboStylePseudo) : len(content)-1])
+		if sel == "" || style == "" {
+			return errors.Error("empty selector or style")
+		}
+
+		f.Content = sel + " { " + style + " }"
+		f.Type = CosmeticCSS
+	default:
+		// Go on.
+	}
+
+	return nil
+}
+
+// uboScriptletToAdGuard converts the uBlock Origin scriptlet injection content,
+// for example "+js(set-constant, foo, true)", into the AdGuard one.
+func uboScriptletToAdGuard(content string) (adg string, err error) {
+	if !strings.HasSuffix(content, ")") {
+		return "", errors.Error("no closing parenthesis")
+	}
+
+	args := splitWithEscapeCharacter(content[len(uboScriptletPrefix):len(content)-1], ',', '\\', true)
+	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
+		return "", errors.Error("no scriptlet name")
+	}
+
+	b := &strings.Builder{}
+	b.WriteString(scriptletPrefix)
+	for i, arg := range args {
+		arg = strings.TrimSpace(arg)
+		if i == 0 {
+			arg = "ubo-" + arg
+		} else {
+			b.WriteString(", ")
+		}
+
+		b.WriteByte('\'')
+		b.WriteString(strings.ReplaceAll(arg, "'", `\'`))
+		b.WriteByte('\'')
+	}
+	b.WriteByte(')')
+
+	return b.String(), nil
+}
@@ -0,0 +1,180 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkRule_uboOptions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		ubo string
+		adg string
+	}{{
+		ubo: "||example.org^$1p",
+		adg: "||example.org^$first-party",
+	}, {
+		ubo: "||example.org^$~1p",
+		adg: "||example.org^$~first-party",
+	}, {
+		ubo: "||example.org^$3p",
+		adg: "||example.org^$third-party",
+	}, {
+		ubo: "||example.org^$~3p",
+		adg: "||example.org^$~third-party",
+	}, {
+		ubo: "||example.org^$xhr,css,~frame",
+		adg: "||example.org^$xmlhttprequest,stylesheet,~subdocument",
+	}, {
+		ubo: "@@||example.org^$doc",
+		adg: "@@||example.org^$document",
+	}, {
+		ubo: "@@||example.org^$ehide,ghide",
+		adg: "@@||example.org^$elemhide,generichide",
+	}, {
+		ubo: "||example.org^$from=example.com|~sub.example.com",
+		adg: "||example.org^$domain=example.com|~sub.example.com",
+	}, {
+		ubo: "||example.org/ads.js$script,redirect-rule=noopjs",
+		adg: "||example.org/ads.js$script,redirect=noopjs",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.ubo, func(t *testing.T) {
+			t.Parallel()
+
+			ubo, err := rules.NewRule(tc.ubo, testFilterListID)
+			require.NoError(t, err)
+
+			adg, err := rules.NewRule(tc.adg, testFilterListID)
+			require.NoError(t, err)
+
+			require.IsType(t, (*rules.NetworkRule)(nil), ubo)
+
+			// Compare everything except the original text.
+			uboRule := ubo.(*rules.NetworkRule)
+			assert.Equal(t, tc.ubo, uboRule.RuleText)
+
+			uboRule.RuleText = tc.adg
+			assert.Equal(t, adg, uboRule)
+		})
+	}
+}
+
+func TestNetworkRule_redirect(t *testing.T) {
+	t.Parallel()
+
+	r, err := rules.NewNetworkRule("||example.org/ads.js$redirect=noopjs", testFilterListID)
+	require.NoError(t, err)
+
+	assert.True(t, r.IsOptionEnabled(rules.OptionRedirect))
+
+	r, err = rules.NewNetworkRule("@@||example.org/ads.js$redirect", testFilterListID)
+	require.NoError(t, err)
+
+	assert.True(t, r.IsOptionEnabled(rules.OptionRedirect))
+
+	_, err = rules.NewNetworkRule("||example.org/ads.js$redirect", testFilterListID)
+	assert.Error(t, err)
+}
+
+func TestNewCosmeticRule_ubo(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		in          string
+		wantContent string
+		wantType    rules.CosmeticRuleType
+		wantExtCSS  bool
+		wantAllow   bool
+	}{{
+		name:        "scriptlet",
+		in:          "example.org##+js(set-constant, foo\\, bar, it's)",
+		wantContent: `//scriptlet('ubo-set-constant', 'foo, bar', 'it\'s')`,
+		wantType:    rules.CosmeticJS,
+		wantExtCSS:  false,
+		wantAllow:   false,
+	}, {
+		name:        "scriptlet_no_args",
+		in:          "example.org#@#+js(nobab.js)",
+		wantContent: `//scriptlet('ubo-nobab.js')`,
+		wantType:    rules.CosmeticJS,
+		wantExtCSS:  false,
+		wantAllow:   true,
+	}, {
+		name:        "style",
+		in:          "example.org##.banner:style(color: red !important)",
+		wantContent: ".banner { color: red !important }",
+		wantType:    rules.CosmeticCSS,
+		wantExtCSS:  false,
+		wantAllow:   false,
+	}, {
+		name:        "remove",
+		in:          "##.banner:remove()",
+		wantContent: ".banner { remove: true; }",
+		wantType:    rules.CosmeticCSS,
+		wantExtCSS:  true,
+		wantAllow:   false,
+	}, {
+		name:        "adguard_css",
+		in:          "example.org#$#.banner { display: none; }",
+		wantContent: ".banner { display: none; }",
+		wantType:    rules.CosmeticCSS,
+		wantExtCSS:  false,
+		wantAllow:   false,
+	}, {
+		name:        "adguard_extcss_exception",
+		in:          "example.org#@?#.banner:has(> a)",
+		wantContent: ".banner:has(> a)",
+		wantType:    rules.CosmeticElementHiding,
+		wantExtCSS:  true,
+		wantAllow:   true,
+	}, {
+		name:        "adguard_js",
+		in:          "example.org#%#window.foo = 1;",
+		wantContent: "window.foo = 1;",
+		wantType:    rules.CosmeticJS,
+		wantExtCSS:  false,
+		wantAllow:   false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewRule(tc.in, testFilterListID)
+			require.NoError(t, err)
+			require.IsType(t, (*rules.CosmeticRule)(nil), r)
+
+			cr := r.(*rules.CosmeticRule)
+			assert.Equal(t, tc.in, cr.RuleText)
+			assert.Equal(t, tc.wantContent, cr.Content)
+			assert.Equal(t, tc.wantType, cr.Type)
+			assert.Equal(t, tc.wantExtCSS, cr.ExtendedCSS)
+			assert.Equal(t, tc.wantAllow, cr.Whitelist)
+		})
+	}
+}
+
+func TestNewCosmeticRule_uboErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, in := range []string{
+		"example.org##+js()",
+		"example.org##+js(set-constant",
+		"example.org##:remove()",
+		"example.org##.banner:style()",
+	} {
+		t.Run(in, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := rules.NewCosmeticRule(in, testFilterListID)
+			assert.Error(t, err)
+		})
+	}
+}
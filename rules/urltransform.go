@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// urlTransform is the parsed value of the $urltransform modifier.  It replaces
+// the first match of the regular expression in the request URL.
+//
+// See https://adguard.com/kb/general/ad-filtering/create-own-filters/#urltransform-modifier.
+type urlTransform struct {
+	// re matches the part of the URL to replace.  It is nil for the exception
+	// rules, which disable all $urltransform rules.
+	re *regexp.Regexp
+
+	// modifierValue is the original value of the modifier.
+	modifierValue
+
+	// replacement is the replacement of the matched part, which may refer to
+	// the submatches of re, like "$1".
+	replacement string
+}
+
+// newURLTransform parses the non-empty value of the $urltransform modifier,
+// which has the "/regex/replacement/" or "/regex/replacement/i" form.  The "/"
+// characters within regex and replacement must be escaped.
+func newURLTransform(value string) (ut *urlTransform, err error) {
+	parts := splitURLTransform(value)
+	if len(parts) != 3 || parts[0] == "" {
+		return nil, fmt.Errorf("invalid $%s value: %q", urlTransformOption, value)
+	}
+
+	expr := parts[0]
+	switch flags := parts[2]; flags {
+	case "":
+		// Go on.
+	case "i":
+		expr = "(?i)" + expr
+	default:
+		return nil, fmt.Errorf("$%s: invalid flags %q", urlTransformOption, flags)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("$%s: compiling regexp %q: %w", urlTransformOption, parts[0], err)
+	}
+
+	return &urlTransform{
+		re:            re,
+		modifierValue: modifierValue{value: value},
+		replacement:   strings.ReplaceAll(parts[1], `\/`, "/"),
+	}, nil
+}
+
+// splitURLTransform splits the value of the $urltransform modifier into the
+// regular expression, the replacement, and the flags.  It returns nil if the
+// value doesn't start with "/".
+func splitURLTransform(value string) (parts []string) {
+	if value[0] != '/' {
+		return nil
+	}
+
+	start := 1
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			// Skip the escaped character.
+			i++
+		case '/':
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, value[start:])
+}
+
+// transform returns u with the first match of the regular expression replaced.
+func (ut *urlTransform) transform(u string) (res string) {
+	loc := ut.re.FindStringSubmatchIndex(u)
+	if loc == nil {
+		return u
+	}
+
+	repl := ut.re.ExpandString(nil, ut.replacement, u, loc)
+
+	return u[:loc[0]] + string(repl) + u[loc[1]:]
+}
+
+// transformURL returns u transformed by the $urltransform rules one after
+// another, the $important ones first.
+func transformURL(u string, urlTransformRules []*NetworkRule) (res string) {
+	res = u
+	for _, important := range []bool{true, false} {
+		for _, r := range urlTransformRules {
+			if r.IsOptionEnabled(OptionImportant) == important {
+				res = r.getValues().urlTransform.transform(res)
+			}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,173 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchingResult_TransformURL(t *testing.T) {
+	t.Parallel()
+
+	const clickURL = "https://click.example/go?url=https://example.org/page&id=1"
+
+	testCases := []struct {
+		name  string
+		url   string
+		want  string
+		rules []string
+	}{{
+		name:  "none",
+		url:   clickURL,
+		want:  clickURL,
+		rules: nil,
+	}, {
+		name:  "extract",
+		url:   clickURL,
+		want:  "https://example.org/page",
+		rules: []string{`||click.example^$urltransform=/^.*url=([^&]*).*\$/\$1/`},
+	}, {
+		name:  "first_match",
+		url:   "https://example.org/a/a/a",
+		want:  "https://example.org/b/a/a",
+		rules: []string{`||example.org^$urltransform=/\/a/\/b/`},
+	}, {
+		name:  "no_match",
+		url:   "https://example.org/page",
+		want:  "https://example.org/page",
+		rules: []string{`||example.org^$urltransform=/ads/banners/`},
+	}, {
+		name:  "case_insensitive",
+		url:   "https://example.org/AD.html",
+		want:  "https://example.org/page.html",
+		rules: []string{`||example.org^$urltransform=/ad/page/i`},
+	}, {
+		name: "several",
+		url:  "https://example.org/ad.html",
+		want: "https://example.org/page.htm",
+		rules: []string{
+			`||example.org^$urltransform=/ad/page/`,
+			`||example.org^$urltransform=/html/htm/`,
+		},
+	}, {
+		name: "important_first",
+		url:  "https://example.org/ad.html",
+		want: "https://example.org/banner.html",
+		rules: []string{
+			`||example.org^$urltransform=/ad/page/`,
+			`||example.org^$urltransform=/ad/banner/,important`,
+		},
+	}, {
+		name: "exception",
+		url:  "https://example.org/ad.html",
+		want: "https://example.org/ad.htm",
+		rules: []string{
+			`||example.org^$urltransform=/ad/page/`,
+			`||example.org^$urltransform=/html/htm/`,
+			`@@||example.org^$urltransform=/ad/page/`,
+		},
+	}, {
+		name: "exception_all",
+		url:  "https://example.org/ad.html",
+		want: "https://example.org/ad.html",
+		rules: []string{
+			`||example.org^$urltransform=/ad/page/`,
+			`@@||example.org^$urltransform`,
+		},
+	}, {
+		name: "badfilter",
+		url:  "https://example.org/ad.html",
+		want: "https://example.org/ad.html",
+		rules: []string{
+			`||example.org^$urltransform=/ad/page/`,
+			`||example.org^$urltransform=/ad/page/,badfilter`,
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := rules.NewMatchingResult(newNetworkRules(t, tc.rules), nil)
+			assert.Equal(t, tc.want, res.TransformURL(tc.url))
+			assert.Nil(t, res.GetBasicResult())
+		})
+	}
+}
+
+func TestNetworkRule_urlTransform(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		in         string
+		wantErrMsg string
+	}{{
+		name:       "valid",
+		in:         "/banner/$urltransform=/banner/ad/",
+		wantErrMsg: "",
+	}, {
+		name:       "case_insensitive",
+		in:         "||example.org^$urltransform=/ad/page/i",
+		wantErrMsg: "",
+	}, {
+		name:       "exception_all",
+		in:         "@@||example.org^$urltransform",
+		wantErrMsg: "",
+	}, {
+		name:       "empty",
+		in:         "||example.org^$urltransform",
+		wantErrMsg: "empty $urltransform value",
+	}, {
+		name:       "no_slashes",
+		in:         "||example.org^$urltransform=ad",
+		wantErrMsg: `invalid $urltransform value: "ad"`,
+	}, {
+		name:       "no_replacement",
+		in:         "||example.org^$urltransform=/ad/",
+		wantErrMsg: `invalid $urltransform value: "/ad/"`,
+	}, {
+		name:       "empty_regexp",
+		in:         "||example.org^$urltransform=//page/",
+		wantErrMsg: `invalid $urltransform value: "//page/"`,
+	}, {
+		name:       "bad_flags",
+		in:         "||example.org^$urltransform=/ad/page/g",
+		wantErrMsg: `$urltransform: invalid flags "g"`,
+	}, {
+		name:       "extra_part",
+		in:         "||example.org^$urltransform=/ad/page/x/",
+		wantErrMsg: `invalid $urltransform value: "/ad/page/x/"`,
+	}, {
+		name:       "bad_regexp",
+		in:         "||example.org^$urltransform=/(/page/",
+		wantErrMsg: "$urltransform: compiling regexp \"(\": error parsing regexp: missing closing ): `(`",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rules.NewNetworkRule(tc.in, -1)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+			if err != nil {
+				return
+			}
+
+			assert.True(t, r.IsOptionEnabled(rules.OptionURLTransform))
+			assert.Nil(t, rules.GetDNSBasicRule([]*rules.NetworkRule{r}))
+		})
+	}
+
+	t.Run("pattern", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := rules.NewNetworkRule("/banner/$urltransform=/banner/ad/", -1)
+		require.NoError(t, err)
+
+		assert.Equal(t, "/banner/", r.Node().Pattern)
+	})
+}